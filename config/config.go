@@ -2,9 +2,16 @@
 package config
 
 import (
-	"encoding/base64" 
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+
+	"Sistem-Manajemen-Karyawan/pkg/notifier"
+	"Sistem-Manajemen-Karyawan/pkg/oidc"
+	"Sistem-Manajemen-Karyawan/pkg/password"
+	"Sistem-Manajemen-Karyawan/pkg/paseto"
 
 	"github.com/joho/godotenv"
 )
@@ -13,7 +20,31 @@ import (
 type AppConfig struct {
 	Port          string 
 	MONGOSTRING   string
-	PASETO_SECRET string
+	PasetoKeyring *paseto.Keyring
+	LeaveEscalation LeaveEscalationConfig
+	Notifier        notifier.Config
+	PasswordResetURL string // URL halaman reset password di frontend; token ditambahkan sebagai query ?token=
+	TwoFactorIssuer        string   // nama aplikasi yang tampil di authenticator
+	TwoFactorRequiredRoles []string // role yang wajib memakai 2FA, misal: admin
+	OIDC                   *oidc.Config // nil jika login SSO tidak diaktifkan (OIDC_ISSUER_URL kosong)
+	// PasswordLoginDisabledDomains adalah domain email yang wajib login lewat SSO, misal: perusahaan.co.id
+	PasswordLoginDisabledDomains []string
+	PasswordPolicy               *password.Policy
+}
+
+// Tindakan otomatis untuk pengajuan cuti yang terlalu lama menunggu persetujuan.
+const (
+	LeaveEscalationActionNone        = "none"         // hanya pengingat
+	LeaveEscalationActionEscalate    = "escalate"     // langkah aktif dialihkan ke admin
+	LeaveEscalationActionAutoApprove = "auto_approve" // langkah aktif disetujui oleh sistem
+)
+
+// LeaveEscalationConfig mengatur cron job pengingat/eskalasi pengajuan cuti pending.
+// Nilai hari 0 menonaktifkan bagian yang bersangkutan.
+type LeaveEscalationConfig struct {
+	ReminderAfterDays int    // LEAVE_REMINDER_AFTER_DAYS, default 2
+	Action            string // LEAVE_ESCALATION_ACTION: none, escalate, auto_approve (default none)
+	ActionAfterDays   int    // LEAVE_ESCALATION_AFTER_DAYS, default 5
 }
 
 func LoadConfig() *AppConfig {
@@ -22,22 +53,145 @@ func LoadConfig() *AppConfig {
 		log.Printf("Warning: Error loading .env file (might not exist in production): %v", err)
 	}
 
-	secretBase64 := getEnv("PASETO_SECRET", "default_paseto_secret_base64_mustbe32bytes_") 
+	pasetoKeyring, err := loadPasetoKeyring()
+	if err != nil {
+		log.Fatalf("Konfigurasi kunci PASETO tidak valid: %v", err)
+	}
+
+	oidcConfig, err := loadOIDCConfig()
+	if err != nil {
+		log.Fatalf("Konfigurasi OIDC tidak valid: %v", err)
+	}
+
+	// PASSWORD_LOGIN_DISABLED_DOMAINS: domain email (dipisahkan koma) yang hanya boleh login lewat SSO.
+	passwordLoginDisabledDomains := getEnvList("PASSWORD_LOGIN_DISABLED_DOMAINS", nil)
+	if len(passwordLoginDisabledDomains) > 0 && oidcConfig == nil {
+		log.Fatalf("PASSWORD_LOGIN_DISABLED_DOMAINS membutuhkan login SSO (OIDC_ISSUER_URL)")
+	}
 
-	secretBytes, err := base64.URLEncoding.DecodeString(secretBase64)
+	passwordPolicy, err := loadPasswordPolicy()
 	if err != nil {
-		log.Fatalf("PASETO_SECRET in .env is not a valid Base64 URL-encoded string: %v", err)
+		log.Fatalf("Kebijakan password tidak valid: %v", err)
 	}
 
-	if len(secretBytes) != 32 {
-		log.Fatalf("PASETO_SECRET (decoded) must be exactly 32 bytes long. Current length: %d", len(secretBytes))
+	escalationAction := getEnv("LEAVE_ESCALATION_ACTION", LeaveEscalationActionNone)
+	switch escalationAction {
+	case LeaveEscalationActionNone, LeaveEscalationActionEscalate, LeaveEscalationActionAutoApprove:
+	default:
+		log.Fatalf("LEAVE_ESCALATION_ACTION tidak valid: %q (gunakan none, escalate, atau auto_approve)", escalationAction)
 	}
 
 	return &AppConfig{
 		Port:          getEnv("PORT", "3000"),
 		MONGOSTRING:   getEnv("MONGOSTRING", ""), 
-		PASETO_SECRET: secretBase64, 
+		PasetoKeyring: pasetoKeyring,
+		LeaveEscalation: LeaveEscalationConfig{
+			ReminderAfterDays: getEnvInt("LEAVE_REMINDER_AFTER_DAYS", 2),
+			Action:            escalationAction,
+			ActionAfterDays:   getEnvInt("LEAVE_ESCALATION_AFTER_DAYS", 5),
+		},
+		Notifier: notifier.Config{
+			Driver:       getEnv("NOTIFIER_DRIVER", ""), // wajib: log, file, atau smtp
+			FilePath:     getEnv("NOTIFIER_FILE_PATH", ""),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			SMTPFrom:     getEnv("SMTP_FROM", ""),
+		},
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", ""),
+		TwoFactorIssuer:        getEnv("TWO_FACTOR_ISSUER", "Sistem Manajemen Karyawan"),
+		TwoFactorRequiredRoles: getEnvList("TWO_FACTOR_REQUIRED_ROLES", nil),
+		OIDC:                   oidcConfig,
+		PasswordLoginDisabledDomains: passwordLoginDisabledDomains,
+		PasswordPolicy:               passwordPolicy,
+	}
+}
+
+// loadPasswordPolicy membaca kebijakan password dari environment. Nilai yang tidak diisi memakai
+// password.DefaultPolicy:
+//   - PASSWORD_MIN_LENGTH, PASSWORD_MAX_LENGTH (default 8 dan 50, maksimum 72)
+//   - PASSWORD_REQUIRE_UPPERCASE (default true), PASSWORD_REQUIRE_LOWERCASE, PASSWORD_REQUIRE_DIGIT,
+//     PASSWORD_REQUIRE_SYMBOL (default false)
+//   - PASSWORD_HISTORY_SIZE: jumlah password terakhir yang tidak boleh dipakai ulang (default 5, 0 = nonaktif)
+//   - PASSWORD_MAX_AGE_DAYS: umur maksimum password sebelum wajib diganti (default 0 = tidak kedaluwarsa)
+//   - PASSWORD_BANNED_LIST_FILE: file daftar password terlarang tambahan, satu per baris
+func loadPasswordPolicy() (*password.Policy, error) {
+	policy := password.DefaultPolicy()
+	policy.MinLength = getEnvInt("PASSWORD_MIN_LENGTH", policy.MinLength)
+	policy.MaxLength = getEnvInt("PASSWORD_MAX_LENGTH", policy.MaxLength)
+	policy.RequireUppercase = getEnvBool("PASSWORD_REQUIRE_UPPERCASE", policy.RequireUppercase)
+	policy.RequireLowercase = getEnvBool("PASSWORD_REQUIRE_LOWERCASE", policy.RequireLowercase)
+	policy.RequireDigit = getEnvBool("PASSWORD_REQUIRE_DIGIT", policy.RequireDigit)
+	policy.RequireSymbol = getEnvBool("PASSWORD_REQUIRE_SYMBOL", policy.RequireSymbol)
+	policy.HistorySize = getEnvInt("PASSWORD_HISTORY_SIZE", policy.HistorySize)
+	policy.MaxAgeDays = getEnvInt("PASSWORD_MAX_AGE_DAYS", policy.MaxAgeDays)
+
+	if path := getEnv("PASSWORD_BANNED_LIST_FILE", ""); path != "" {
+		banned, err := password.LoadBannedPasswords(path)
+		if err != nil {
+			return nil, err
+		}
+		policy.AddBannedPasswords(banned)
+	}
+
+	if err := policy.Check(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// loadOIDCConfig membaca pengaturan login SSO OpenID Connect. Login SSO aktif jika OIDC_ISSUER_URL
+// diisi; OIDC_CLIENT_ID dan OIDC_REDIRECT_URL (halaman frontend yang menerima code dari IdP) wajib
+// diisi. OIDC_SCOPES opsional, default "openid email profile".
+func loadOIDCConfig() (*oidc.Config, error) {
+	issuerURL := getEnv("OIDC_ISSUER_URL", "")
+	if issuerURL == "" {
+		return nil, nil
+	}
+
+	cfg := &oidc.Config{
+		IssuerURL:    issuerURL,
+		ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		Scopes:       getEnvList("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+	}
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID dan OIDC_REDIRECT_URL wajib diisi jika OIDC_ISSUER_URL dipakai")
 	}
+	return cfg, nil
+}
+
+// legacyPasetoKeyID adalah key ID untuk PASETO_SECRET saat PASETO_KEYS belum dipakai.
+const legacyPasetoKeyID = "default"
+
+// loadPasetoKeyring membaca kunci PASETO dari environment:
+//   - PASETO_KEYS: daftar kunci "kid:base64" dipisahkan koma, semuanya diterima saat validasi token.
+//   - PASETO_PRIMARY_KEY_ID: key ID yang dipakai untuk membuat token baru.
+//
+// Jika PASETO_KEYS kosong, PASETO_SECRET dipakai sebagai satu-satunya kunci (key ID "default").
+//
+// Rotasi kunci tanpa memaksa semua user login ulang:
+//  1. Tambahkan kunci baru ke PASETO_KEYS di semua instance, primary tetap kunci lama.
+//  2. Setelah semua instance memuat kunci baru, ubah PASETO_PRIMARY_KEY_ID ke kunci baru.
+//  3. Hapus kunci lama setelah access token terakhir yang dibuat dengannya kedaluwarsa
+//     (paseto.AccessTokenDuration). Refresh token disimpan di database sehingga tidak terpengaruh.
+func loadPasetoKeyring() (*paseto.Keyring, error) {
+	keys := getEnv("PASETO_KEYS", "")
+	if keys == "" {
+		secret := getEnv("PASETO_SECRET", "")
+		if secret == "" {
+			return nil, fmt.Errorf("PASETO_KEYS atau PASETO_SECRET wajib diisi")
+		}
+		return paseto.ParseKeyring(legacyPasetoKeyID, legacyPasetoKeyID+":"+secret)
+	}
+
+	primaryKeyID := getEnv("PASETO_PRIMARY_KEY_ID", "")
+	if primaryKeyID == "" {
+		return nil, fmt.Errorf("PASETO_PRIMARY_KEY_ID wajib diisi jika PASETO_KEYS dipakai")
+	}
+	return paseto.ParseKeyring(primaryKeyID, keys)
 }
 
 func getEnv(key, defaultValue string) string {
@@ -45,4 +199,43 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		log.Fatalf("%s harus berupa bilangan bulat >= 0, didapat: %q", key, value)
+	}
+	return parsed
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("%s harus berupa true atau false, didapat: %q", key, value)
+	}
+	return parsed
+}
+
+// getEnvList membaca daftar nilai yang dipisahkan koma, misal: "admin,manager".
+func getEnvList(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
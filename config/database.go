@@ -25,6 +25,19 @@ var SalaryCollection string = "salaries"
 var LeaveRequestCollection string = "leave_requests"
 var QRCodeCollection string = "qr_codes"
 var WorkScheduleCollection string = "work_schedule"
+var ApprovalChainCollection string = "approval_chains"
+var ApprovalDelegationCollection string = "approval_delegations"
+var RefreshTokenCollection string = "refresh_tokens"
+var RevokedTokenCollection string = "revoked_tokens"
+var SessionCollection string = "sessions"
+var PasswordResetCollection string = "password_resets"
+var SecurityEventCollection string = "security_events"
+var TwoFactorChallengeCollection string = "two_factor_challenges"
+var RoleCollection string = "roles"
+var APIKeyCollection string = "api_keys"
+var OIDCStateCollection string = "oidc_login_states"
+var AuditLogCollection string = "audit_logs"
+var ActivityCollection string = "activities"
 
 func MongoConnect() {
 	mongoURI := os.Getenv("MONGOSTRING")
@@ -51,6 +64,10 @@ func MongoConnect() {
 		log.Fatalf("Failed to ping MongoDB: %v", err)
 	}
 
+	if err := checkTransactionSupport(ctx, client); err != nil {
+		log.Fatalf("MongoDB tidak mendukung transaksi: %v. Jalankan MongoDB sebagai replica set (mis. mongod --replSet rs0 lalu rs.initiate()).", err)
+	}
+
 	log.Println("Connected to MongoDB!")
 	MongoConn = client
 }
@@ -78,6 +95,123 @@ func InitDatabase() {
 		log.Println("Indeks unik untuk email berhasil dibuat di koleksi users.")
 	}
 
+	// Refresh token dicari berdasarkan hash; token dan daftar pencabutan dihapus otomatis setelah kedaluwarsa.
+	refreshTokenCollection := MongoConn.Database(DBName).Collection(RefreshTokenCollection)
+	_, err = refreshTokenCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "session_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi refresh_tokens: %v\n", err)
+	}
+
+	sessionCollection := MongoConn.Database(DBName).Collection(SessionCollection)
+	_, err = sessionCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "session_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi sessions: %v\n", err)
+	}
+
+	revokedTokenCollection := MongoConn.Database(DBName).Collection(RevokedTokenCollection)
+	_, err = revokedTokenCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "revoked_before", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi revoked_tokens: %v\n", err)
+	}
+
+	passwordResetCollection := MongoConn.Database(DBName).Collection(PasswordResetCollection)
+	_, err = passwordResetCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi password_resets: %v\n", err)
+	}
+
+	securityEventCollection := MongoConn.Database(DBName).Collection(SecurityEventCollection)
+	_, err = securityEventCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "ip_address", Value: 1}, {Key: "type", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi security_events: %v\n", err)
+	}
+
+	twoFactorChallengeCollection := MongoConn.Database(DBName).Collection(TwoFactorChallengeCollection)
+	_, err = twoFactorChallengeCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi two_factor_challenges: %v\n", err)
+	}
+
+	roleCollection := MongoConn.Database(DBName).Collection(RoleCollection)
+	_, err = roleCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi roles: %v\n", err)
+	}
+
+	apiKeyCollection := MongoConn.Database(DBName).Collection(APIKeyCollection)
+	_, err = apiKeyCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi api_keys: %v\n", err)
+	}
+
+	oidcStateCollection := MongoConn.Database(DBName).Collection(OIDCStateCollection)
+	_, err = oidcStateCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "state_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi oidc_login_states: %v\n", err)
+	}
+
+	auditLogCollection := MongoConn.Database(DBName).Collection(AuditLogCollection)
+	_, err = auditLogCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi audit_logs: %v\n", err)
+	}
+
+	activityCollection := MongoConn.Database(DBName).Collection(ActivityCollection)
+	_, err = activityCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi activities: %v\n", err)
+	}
+
+	// Analitik absensi memfilter berdasarkan rentang tanggal.
+	attendanceCollection := MongoConn.Database(DBName).Collection(AttendanceCollection)
+	_, err = attendanceCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "date", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("Peringatan: Gagal membuat indeks untuk koleksi attendances: %v\n", err)
+	}
 }
 
 func GetCollection(collectionName string) *mongo.Collection {
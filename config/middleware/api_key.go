@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"log"
+	"net"
+	"strings"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/paseto"
+	"Sistem-Manajemen-Karyawan/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyHeader adalah header alternatif untuk mengirim API key selain "Authorization: Bearer smk_...".
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyFromRequest mengambil API key dari header X-API-Key atau Authorization Bearer.
+func apiKeyFromRequest(c *fiber.Ctx) string {
+	if key := strings.TrimSpace(c.Get(APIKeyHeader)); key != "" {
+		return key
+	}
+	if token, found := strings.CutPrefix(c.Get("Authorization"), "Bearer "); found && strings.HasPrefix(token, models.APIKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey memvalidasi API key dan menyimpan klaimnya di c.Locals("api_key").
+// Klaim API key sengaja tidak disimpan di c.Locals("user"): RequirePermission yang memindahkannya
+// setelah izin key terbukti. Sampai saat itu API key ditolak FirstLoginMiddleware; hanya grup yang
+// memakai FirstLoginAPIKeyMiddleware yang meneruskannya, dan handler di grup tersebut yang tidak
+// dilindungi RequirePermission menolak API key karena c.Locals("user") kosong.
+func authenticateAPIKey(c *fiber.Ctx, apiKeyRepo repository.APIKeyRepository, rawKey string) error {
+	key, err := apiKeyRepo.FindActiveByHash(c.Context(), paseto.HashToken(rawKey))
+	if err != nil {
+		log.Printf("ERROR: Gagal memeriksa API key: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Server error: tidak bisa memproses API key"})
+	}
+	if key == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "API key tidak valid, telah dicabut, atau kedaluwarsa"})
+	}
+	if !apiKeyAllowsIP(key, c.IP()) {
+		log.Printf("WARNING: API key %s (%s) dipakai dari IP yang tidak diizinkan: %s", key.Prefix, key.Name, c.IP())
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API key tidak diizinkan dari alamat IP ini"})
+	}
+
+	if err := apiKeyRepo.TouchLastUsed(c.Context(), key.ID, c.IP()); err != nil {
+		log.Printf("ERROR: %v", err)
+	}
+
+	c.Locals("api_key", &models.Claims{
+		UserID:      key.CreatedBy,
+		Email:       "api-key:" + key.Name,
+		APIKeyID:    &key.ID,
+		Permissions: key.Permissions,
+	})
+	return c.Next()
+}
+
+func apiKeyAllowsIP(key *models.APIKey, ip string) bool {
+	if len(key.AllowedCIDRs) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, cidr := range key.AllowedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err == nil && network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditMiddleware mencatat setiap request yang mengubah data (POST, PUT, PATCH, DELETE) dan berhasil
+// (status 2xx) ke audit log. Handler melengkapi entri di c.Locals("audit") dengan aksi, target, dan
+// perubahan field; tanpa itu aksi diisi dari metode dan pola rute. Request tanpa login (login,
+// refresh token, reset password) hanya dicatat jika handler mengisi aksinya.
+//
+// Dipasang di grup /api/v1 sebelum AuthMiddleware, sehingga klaim user sudah tersedia saat entri
+// disimpan setelah handler selesai.
+func AuditMiddleware(auditRepo repository.AuditLogRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		default:
+			return c.Next()
+		}
+
+		entry := &models.AuditLog{}
+		c.Locals("audit", entry)
+
+		err := c.Next()
+		status := c.Response().StatusCode()
+		if err != nil || status < fiber.StatusOK || status >= fiber.StatusMultipleChoices {
+			return err
+		}
+
+		claims, ok := c.Locals("user").(*models.Claims)
+		if !ok {
+			claims, _ = c.Locals("api_key").(*models.Claims)
+		}
+		if claims == nil && entry.Action == "" {
+			return nil
+		}
+		if claims != nil {
+			entry.ActorID = &claims.UserID
+			entry.ActorEmail = claims.Email
+			entry.ActorRole = claims.Role
+			entry.APIKeyID = claims.APIKeyID
+		}
+
+		entry.Method = c.Method()
+		entry.Path = c.Route().Path
+		if entry.Action == "" {
+			entry.Action = entry.Method + " " + entry.Path
+		}
+		if entry.TargetID == "" {
+			entry.TargetID = c.Params("id")
+		}
+		entry.StatusCode = status
+		entry.IPAddress = c.IP()
+		entry.UserAgent = c.Get(fiber.HeaderUserAgent)
+
+		// Konteks request bisa sudah dibatalkan handler; penyimpanan audit memakai konteks sendiri.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := auditRepo.Create(ctx, entry); err != nil {
+			log.Printf("ERROR: Gagal mencatat audit log %s: %v", entry.Action, err)
+		}
+		return nil
+	}
+}
@@ -2,13 +2,32 @@ package middleware
 
 import (
 	"Sistem-Manajemen-Karyawan/pkg/paseto" 
+	"Sistem-Manajemen-Karyawan/repository"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func AuthMiddleware() fiber.Handler {
+// twoFactorSetupAllowedPaths adalah rute yang masih boleh diakses dengan token yang
+// menunggu pendaftaran 2FA (role wajib 2FA tetapi user belum mendaftar).
+var twoFactorSetupAllowedPaths = map[string]bool{
+	"/api/v1/auth/2fa/setup":        true,
+	"/api/v1/auth/2fa/enable":       true,
+	"/api/v1/auth/logout":           true,
+	"/api/v1/users/change-password": true, // password awal diganti lebih dulu, lalu 2FA didaftarkan
+}
+
+// AuthMiddleware memvalidasi access token, memeriksanya terhadap daftar pencabutan
+// (logout, logout semua sesi, atau user dihapus), dan memastikan sesi login-nya masih aktif. API key integrasi juga diterima, tetapi
+// hanya berlaku pada rute yang dilindungi RequirePermission.
+func AuthMiddleware(tokenRepo repository.TokenRepository, pasetoMaker *paseto.PasetoMaker, apiKeyRepo repository.APIKeyRepository) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		if rawKey := apiKeyFromRequest(c); rawKey != "" {
+			return authenticateAPIKey(c, apiKeyRepo, rawKey)
+		}
+
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authorization header is required"})
@@ -21,18 +40,48 @@ func AuthMiddleware() fiber.Handler {
 
 		tokenString := parts[1]
 
+		claims, err := pasetoMaker.ValidateToken(tokenString)
+		if err != nil {
 		
-		pasetoMaker, err := paseto.NewPasetoMaker()
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token tidak valid atau telah kadaluarsa",
+			})
+		}
+
+		revoked, err := tokenRepo.IsAccessTokenRevoked(c.Context(), claims)
 		if err != nil {
-	
+			log.Printf("ERROR: Gagal memeriksa pencabutan token user %s: %v", claims.UserID.Hex(), err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Server error: tidak bisa memproses token"})
 		}
+		if revoked {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Token telah dicabut, silakan login kembali",
+			})
+		}
 
-		claims, err := pasetoMaker.ValidateToken(tokenString)
+		// Token selalu terikat ke sesi login; sesi yang diakhiri dari perangkat lain atau oleh admin
+		// langsung membuat access token-nya tidak berlaku.
+		if claims.SessionID == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token tidak valid atau telah kadaluarsa"})
+		}
+		session, err := tokenRepo.FindSession(c.Context(), claims.SessionID)
 		if err != nil {
-		
+			log.Printf("ERROR: Gagal memeriksa sesi user %s: %v", claims.UserID.Hex(), err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Server error: tidak bisa memproses token"})
+		}
+		if session == nil || session.UserID != claims.UserID || !session.IsActive(time.Now()) {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Token tidak valid atau telah kadaluarsa",
+				"error": "Sesi telah berakhir, silakan login kembali",
+			})
+		}
+		if err := tokenRepo.TouchSession(c.Context(), session, c.IP()); err != nil {
+			log.Printf("ERROR: Gagal memperbarui waktu akses sesi user %s: %v", claims.UserID.Hex(), err)
+		}
+
+		if claims.TwoFactorSetupRequired && !twoFactorSetupAllowedPaths[strings.TrimRight(c.Path(), "/")] {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":                     "Role Anda wajib memakai autentikasi dua faktor. Daftarkan 2FA terlebih dahulu.",
+				"two_factor_setup_required": true,
 			})
 		}
 
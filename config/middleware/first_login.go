@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"strings"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// firstLoginAllowedPaths adalah rute yang masih boleh diakses sebelum user mengganti password awal.
+var firstLoginAllowedPaths = map[string]bool{
+	"/api/v1/users/change-password": true,
+	"/api/v1/auth/logout":           true,
+	"/api/v1/auth/logout-all":       true,
+}
+
+// FirstLoginMiddleware memaksa user yang masih memakai password awal dari admin, atau yang
+// password-nya sudah kedaluwarsa, untuk menggantinya sebelum memakai API lain. Dipasang setelah
+// AuthMiddleware. API key yang izinnya belum diperiksa RequirePermission ditolak, sehingga rute
+// tanpa pemeriksaan izin tidak bisa diakses dengan API key.
+func FirstLoginMiddleware() fiber.Handler {
+	return firstLoginMiddleware(false)
+}
+
+// FirstLoginAPIKeyMiddleware sama dengan FirstLoginMiddleware, tetapi meneruskan API key ke handler
+// berikutnya agar izinnya diperiksa RequirePermission pada rute. Hanya untuk grup yang setiap rutenya
+// memasang RequirePermission atau membaca user dari c.Locals("user"), yang untuk API key baru diisi
+// oleh RequirePermission.
+func FirstLoginAPIKeyMiddleware() fiber.Handler {
+	return firstLoginMiddleware(true)
+}
+
+func firstLoginMiddleware(allowAPIKey bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*models.Claims)
+		if !ok {
+			if _, isAPIKey := c.Locals("api_key").(*models.Claims); isAPIKey {
+				if allowAPIKey {
+					return c.Next()
+				}
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "API key tidak dapat dipakai untuk rute ini"})
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+		}
+
+		if claims.IsFirstLogin && !firstLoginAllowedPaths[strings.TrimRight(c.Path(), "/")] {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":                    "Anda wajib mengganti password awal sebelum menggunakan fitur lain.",
+				"password_change_required": true,
+			})
+		}
+
+		if claims.PasswordExpired && !firstLoginAllowedPaths[strings.TrimRight(c.Path(), "/")] {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":                    "Password Anda sudah kedaluwarsa. Ganti password untuk melanjutkan.",
+				"password_change_required": true,
+				"password_expired":         true,
+			})
+		}
+
+		return c.Next()
+	}
+}
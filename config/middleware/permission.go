@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"log"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/repository"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequirePermission mengizinkan request hanya jika role user memiliki semua izin yang diminta.
+// Izin role dibaca dari koleksi roles, sehingga role kustom (misal: hr_viewer) bisa diatur tanpa
+// mengubah kode. Untuk API key, yang diperiksa adalah izin milik key itu sendiri. Dipasang setelah AuthMiddleware.
+func RequirePermission(roleRepo repository.RoleRepository, permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*models.Claims)
+		isAPIKey := false
+		if !ok {
+			claims, isAPIKey = c.Locals("api_key").(*models.Claims)
+			if !isAPIKey {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+			}
+		}
+
+		for _, permission := range permissions {
+			var allowed bool
+			if isAPIKey {
+				allowed = claims.HasAPIKeyPermission(permission)
+			} else {
+				var err error
+				allowed, err = roleRepo.HasPermission(c.Context(), claims.Role, permission)
+				if err != nil {
+					log.Printf("ERROR: Gagal memeriksa izin %s untuk role %s: %v", permission, claims.Role, err)
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Server error: tidak bisa memeriksa hak akses"})
+				}
+			}
+			if !allowed {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error":      "Akses ditolak. Anda tidak memiliki izin untuk aksi ini",
+					"permission": permission,
+				})
+			}
+		}
+
+		if isAPIKey {
+			c.Locals("user", claims)
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireAPIKeyPermission sama dengan RequirePermission, tetapi hanya memeriksa API key; user yang
+// login diteruskan tanpa pemeriksaan. Untuk rute yang dipakai semua karyawan untuk dirinya sendiri
+// dan juga oleh integrasi atas nama karyawan lain (mis. scan absensi oleh badge reader); handler
+// yang membatasi aksi user tanpa izin ke dirinya sendiri.
+func RequireAPIKeyPermission(roleRepo repository.RoleRepository, permissions ...string) fiber.Handler {
+	requirePermission := RequirePermission(roleRepo, permissions...)
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("user").(*models.Claims); ok {
+			return c.Next()
+		}
+		return requirePermission(c)
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// checkTransactionSupport memastikan server MongoDB mendukung transaksi multi-dokumen, yaitu
+// anggota replica set atau mongos (sharded cluster). Server standalone menolak transaksi.
+func checkTransactionSupport(ctx context.Context, client *mongo.Client) error {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		// Server lama (< 4.4.2) belum mengenal hello.
+		err = client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	}
+	if err != nil {
+		return fmt.Errorf("gagal memeriksa topologi MongoDB: %w", err)
+	}
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return fmt.Errorf("MongoDB berjalan sebagai server standalone; transaksi (dipakai saat keputusan cuti dan penarikan) membutuhkan replica set atau Atlas")
+	}
+	return nil
+}
+
+// WithTransaction menjalankan fn di dalam transaksi multi-dokumen MongoDB.
+// Semua operasi repository di dalam fn harus memakai sessCtx sebagai context agar ikut
+// dalam transaksi; jika fn mengembalikan error, seluruh perubahan dibatalkan.
+// Transaksi membutuhkan MongoDB replica set / Atlas.
+func WithTransaction(ctx context.Context, fn func(sessCtx mongo.SessionContext) error) error {
+	if MongoConn == nil {
+		return fmt.Errorf("MongoDB belum terkoneksi")
+	}
+
+	session, err := MongoConn.StartSession()
+	if err != nil {
+		return fmt.Errorf("gagal memulai sesi MongoDB: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
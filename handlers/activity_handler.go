@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+// dashboardActivityLimit adalah jumlah aktivitas terbaru yang disertakan di statistik dashboard.
+const dashboardActivityLimit = 10
+
+// recordActivity menyimpan aktivitas ke feed aktivitas. Seperti recordSecurityEvent, kegagalan
+// hanya dicatat di log agar alur utama tidak ikut gagal.
+func recordActivity(ctx context.Context, activityRepo repository.ActivityRepository, activity models.Activity) {
+	if _, err := activityRepo.Create(ctx, &activity); err != nil {
+		log.Printf("ERROR: Gagal mencatat aktivitas %s: %v", activity.Type, err)
+	}
+}
+
+// activityActor mengembalikan ID user yang melakukan request untuk Activity.ActorID. Request dengan
+// API key tidak dilakukan oleh user (UserID pada klaimnya adalah admin pembuat key), sehingga
+// ActorID dikosongkan; key yang dipakai tercatat di audit log.
+func activityActor(c *fiber.Ctx) *primitive.ObjectID {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok || claims.APIKeyID != nil {
+		return nil
+	}
+	return &claims.UserID
+}
+
+type ActivityHandler struct {
+	activityRepo repository.ActivityRepository
+}
+
+func NewActivityHandler(activityRepo repository.ActivityRepository) *ActivityHandler {
+	return &ActivityHandler{
+		activityRepo: activityRepo,
+	}
+}
+
+// GetActivities godoc
+// @Summary Get Recent Activities
+// @Description Mengambil feed aktivitas karyawan (check-in, pengajuan dan keputusan cuti, karyawan baru, perubahan profil), terbaru lebih dulu
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "Filter jenis aktivitas" Enums(check_in, leave_submitted, leave_approved, leave_rejected, employee_joined, profile_updated)
+// @Param page query int false "Nomor halaman (default: 1)"
+// @Param limit query int false "Jumlah item per halaman (default: 20, maks: 100)"
+// @Success 200 {object} object{data=[]models.Activity,total=int,page=int,limit=int} "Feed aktivitas"
+// @Failure 500 {object} object{error=string} "Gagal mengambil aktivitas"
+// @Router /admin/activities [get]
+func (h *ActivityHandler) GetActivities(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	activities, total, err := h.activityRepo.Find(ctx, c.Query("type"), int64(page), int64(limit))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mengambil aktivitas: %v", err)})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":  activities,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
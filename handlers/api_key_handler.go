@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/paseto"
+	util "Sistem-Manajemen-Karyawan/pkg/utils"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+type APIKeyHandler struct {
+	apiKeyRepo repository.APIKeyRepository
+	roleRepo   repository.RoleRepository
+}
+
+func NewAPIKeyHandler(apiKeyRepo repository.APIKeyRepository, roleRepo repository.RoleRepository) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyRepo: apiKeyRepo,
+		roleRepo:   roleRepo,
+	}
+}
+
+// normalizeCIDRs memvalidasi daftar rentang IP; alamat IP tunggal diubah menjadi /32 (IPv4) atau /128 (IPv6).
+func normalizeCIDRs(entries []string) ([]string, error) {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("rentang IP '%s' tidak valid", entry)
+		}
+		result = append(result, network.String())
+	}
+	return result, nil
+}
+
+// GetAllAPIKeys godoc
+// @Summary Get All API Keys
+// @Description Mengambil daftar API key integrasi beserta waktu dan IP pemakaian terakhir. Key asli tidak pernah ditampilkan lagi setelah dibuat.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param include_revoked query bool false "Sertakan API key yang sudah dicabut"
+// @Success 200 {array} models.APIKey "Daftar API key"
+// @Failure 500 {object} object{error=string} "Gagal mengambil API key"
+// @Router /admin/api-keys [get]
+func (h *APIKeyHandler) GetAllAPIKeys(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	keys, err := h.apiKeyRepo.FindAll(ctx, c.QueryBool("include_revoked", false))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mengambil API key: %v", err)})
+	}
+	return c.Status(fiber.StatusOK).JSON(keys)
+}
+
+// CreateAPIKey godoc
+// @Summary Create API Key
+// @Description Membuat API key untuk integrasi (misal: mesin absensi atau tool BI). Izin key dibatasi pada daftar permissions dan tidak boleh melebihi izin pembuatnya; team.read, leave.approve, api_keys.write, roles.write, users.write, dan users.security tidak bisa diberikan ke API key. Key dikirim lewat header X-API-Key atau Authorization Bearer dan hanya berlaku pada endpoint yang dilindungi izin. Key asli hanya ditampilkan sekali.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body models.CreateAPIKeyPayload true "Data API key"
+// @Success 201 {object} object{message=string,key=string,data=models.APIKey} "API key berhasil dibuat"
+// @Failure 400 {object} object{error=string} "Payload tidak valid"
+// @Failure 403 {object} object{error=string} "Izin melebihi izin pembuat"
+// @Failure 500 {object} object{error=string} "Gagal membuat API key"
+// @Router /admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+
+	var payload models.CreateAPIKeyPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body", "details": err.Error()})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "expires_at harus di masa depan"})
+	}
+
+	permissions, err := validatePermissions(payload.Permissions)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	cidrs, err := normalizeCIDRs(payload.AllowedCIDRs)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	for _, p := range permissions {
+		if models.APIKeyExcludedPermissions[p] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("izin '%s' tidak dapat diberikan ke API key", p)})
+		}
+		if !hasPermission(ctx, h.roleRepo, claims, p) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": fmt.Sprintf("Anda tidak dapat memberikan izin '%s' yang tidak Anda miliki", p)})
+		}
+	}
+
+	secret, err := paseto.NewRandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat API key"})
+	}
+	rawKey := models.APIKeyPrefix + secret
+
+	key := &models.APIKey{
+		Name:         payload.Name,
+		Prefix:       rawKey[:len(models.APIKeyPrefix)+6],
+		KeyHash:      paseto.HashToken(rawKey),
+		Permissions:  permissions,
+		AllowedCIDRs: cidrs,
+		CreatedBy:    claims.UserID,
+		ExpiresAt:    payload.ExpiresAt,
+	}
+	if _, err := h.apiKeyRepo.Create(ctx, key); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal membuat API key: %v", err)})
+	}
+	recordAudit(c, models.AuditActionAPIKeyCreate, models.AuditTargetAPIKey, key.ID.Hex(), nil, key)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "API key berhasil dibuat. Simpan key ini sekarang; key tidak akan ditampilkan lagi.",
+		"key":     rawKey,
+		"data":    key,
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API Key
+// @Description Mencabut API key; request berikutnya yang memakai key ini langsung ditolak
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API Key ID"
+// @Success 200 {object} object{message=string} "API key berhasil dicabut"
+// @Failure 400 {object} object{error=string} "Format ID tidak valid"
+// @Failure 404 {object} object{error=string} "API key tidak ditemukan atau sudah dicabut"
+// @Failure 500 {object} object{error=string} "Gagal mencabut API key"
+// @Router /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format ID API key tidak valid"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	result, err := h.apiKeyRepo.Revoke(ctx, objID, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mencabut API key: %v", err)})
+	}
+	if result.ModifiedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API key tidak ditemukan atau sudah dicabut"})
+	}
+	recordAudit(c, models.AuditActionAPIKeyRevoke, models.AuditTargetAPIKey, objID.Hex(), nil, nil)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "API key berhasil dicabut"})
+}
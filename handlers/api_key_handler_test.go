@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/config/middleware"
+	"Sistem-Manajemen-Karyawan/handlers"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+// apiKeyForbiddenPermissions adalah izin yang memungkinkan API key bocor mengambil alih akun atau
+// bertindak sebagai approver.
+var apiKeyForbiddenPermissions = []string{
+	models.PermissionUsersWrite,
+	models.PermissionUsersSecurity,
+	models.PermissionLeaveApprove,
+}
+
+func TestCreateAPIKeyRejectsPrivilegedPermissions(t *testing.T) {
+	roleRepo := &memoryRoleRepo{permissions: map[string][]string{
+		models.RoleAdmin: {models.PermissionUsersWrite, models.PermissionUsersSecurity, models.PermissionLeaveApprove},
+	}}
+	h := handlers.NewAPIKeyHandler(nil, roleRepo)
+	app := fiber.New()
+	app.Post("/admin/api-keys", func(c *fiber.Ctx) error {
+		c.Locals("user", &models.Claims{UserID: primitive.NewObjectID(), Role: models.RoleAdmin})
+		return c.Next()
+	}, h.CreateAPIKey)
+
+	for _, permission := range apiKeyForbiddenPermissions {
+		t.Run(permission, func(t *testing.T) {
+			raw, err := json.Marshal(models.CreateAPIKeyPayload{Name: "integrasi", Permissions: []string{permission}})
+			if err != nil {
+				t.Fatalf("json.Marshal: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/admin/api-keys", bytes.NewReader(raw))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+			resp, err := app.Test(req, 10000)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != fiber.StatusBadRequest {
+				t.Errorf("status = %d, want 400", resp.StatusCode)
+			}
+		})
+	}
+}
+
+// Key yang dibuat sebelum izin tersebut dikecualikan tetap ditolak saat dipakai.
+func TestRequirePermissionRejectsPrivilegedPermissionsOnExistingAPIKey(t *testing.T) {
+	keyID := primitive.NewObjectID()
+	key := &models.Claims{UserID: primitive.NewObjectID(), APIKeyID: &keyID, Permissions: apiKeyForbiddenPermissions}
+
+	for _, permission := range apiKeyForbiddenPermissions {
+		t.Run(permission, func(t *testing.T) {
+			app := fiber.New()
+			app.Post("/", func(c *fiber.Ctx) error {
+				c.Locals("api_key", key)
+				return c.Next()
+			}, middleware.RequirePermission(&memoryRoleRepo{}, permission), func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusNoContent)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("POST", "/", nil), 10000)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != fiber.StatusForbidden {
+				t.Errorf("status = %d, want 403", resp.StatusCode)
+			}
+		})
+	}
+}
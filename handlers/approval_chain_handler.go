@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/models"
+	util "Sistem-Manajemen-Karyawan/pkg/utils"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+type ApprovalChainHandler struct {
+	chainRepo repository.ApprovalChainRepository
+}
+
+func NewApprovalChainHandler(chainRepo repository.ApprovalChainRepository) *ApprovalChainHandler {
+	return &ApprovalChainHandler{
+		chainRepo: chainRepo,
+	}
+}
+
+// validateApprovalSteps memastikan setiap langkah punya target approver sesuai jenisnya.
+func validateApprovalSteps(steps []models.ApprovalStepDefinition) error {
+	for i, step := range steps {
+		switch step.ApproverType {
+		case models.ApproverTypeRole:
+			if step.ApproverRole == "" {
+				return fmt.Errorf("langkah %d (%s): 'approver_role' wajib diisi untuk approver_type 'role'", i+1, step.Name)
+			}
+		case models.ApproverTypeUser:
+			if step.ApproverID == nil || step.ApproverID.IsZero() {
+				return fmt.Errorf("langkah %d (%s): 'approver_id' wajib diisi untuk approver_type 'user'", i+1, step.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// CreateApprovalChain godoc
+// @Summary Create Approval Chain
+// @Description Membuat rantai persetujuan pengajuan cuti/sakit untuk jenis pengajuan dan/atau departemen tertentu (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param chain body models.ApprovalChainPayload true "Data rantai persetujuan"
+// @Success 201 {object} object{message=string,data=models.ApprovalChain} "Rantai persetujuan berhasil dibuat"
+// @Failure 400 {object} object{error=string} "Payload tidak valid"
+// @Failure 500 {object} object{error=string} "Gagal membuat rantai persetujuan"
+// @Router /admin/approval-chains [post]
+func (h *ApprovalChainHandler) CreateApprovalChain(c *fiber.Ctx) error {
+	var payload models.ApprovalChainPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body", "details": err.Error()})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+	if err := validateApprovalSteps(payload.Steps); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	chain := &models.ApprovalChain{
+		Name:        payload.Name,
+		RequestType: payload.RequestType,
+		Department:  payload.Department,
+		Steps:       payload.Steps,
+	}
+	if _, err := h.chainRepo.Create(ctx, chain); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal membuat rantai persetujuan: %v", err)})
+	}
+	recordAudit(c, models.AuditActionApprovalChainCreate, models.AuditTargetApprovalChain, chain.ID.Hex(), nil, chain)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Rantai persetujuan berhasil dibuat", "data": chain})
+}
+
+// GetAllApprovalChains godoc
+// @Summary Get All Approval Chains
+// @Description Mengambil semua rantai persetujuan (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.ApprovalChain "Daftar rantai persetujuan"
+// @Failure 500 {object} object{error=string} "Gagal mengambil rantai persetujuan"
+// @Router /admin/approval-chains [get]
+func (h *ApprovalChainHandler) GetAllApprovalChains(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	chains, err := h.chainRepo.FindAll(ctx)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mengambil rantai persetujuan: %v", err)})
+	}
+	return c.Status(fiber.StatusOK).JSON(chains)
+}
+
+// UpdateApprovalChain godoc
+// @Summary Update Approval Chain
+// @Description Memperbarui rantai persetujuan. Pengajuan yang sudah dibuat tetap memakai salinan langkah saat diajukan (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Approval Chain ID"
+// @Param chain body models.ApprovalChainPayload true "Data rantai persetujuan"
+// @Success 200 {object} object{message=string} "Rantai persetujuan berhasil diupdate"
+// @Failure 400 {object} object{error=string} "ID atau payload tidak valid"
+// @Failure 404 {object} object{error=string} "Rantai persetujuan tidak ditemukan"
+// @Failure 500 {object} object{error=string} "Gagal mengupdate rantai persetujuan"
+// @Router /admin/approval-chains/{id} [put]
+func (h *ApprovalChainHandler) UpdateApprovalChain(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format ID rantai persetujuan tidak valid"})
+	}
+
+	var payload models.ApprovalChainPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body", "details": err.Error()})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+	if err := validateApprovalSteps(payload.Steps); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	before, err := h.chainRepo.FindByID(ctx, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mencari rantai persetujuan: %v", err)})
+	}
+	if before == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Rantai persetujuan tidak ditemukan"})
+	}
+
+	result, err := h.chainRepo.Update(ctx, objID, bson.M{
+		"name":         payload.Name,
+		"request_type": payload.RequestType,
+		"department":   payload.Department,
+		"steps":        payload.Steps,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mengupdate rantai persetujuan: %v", err)})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Rantai persetujuan tidak ditemukan"})
+	}
+	after, err := h.chainRepo.FindByID(ctx, objID)
+	if err != nil {
+		log.Printf("ERROR: Gagal membaca rantai persetujuan %s setelah update untuk audit log: %v", objID.Hex(), err)
+	}
+	recordAudit(c, models.AuditActionApprovalChainUpdate, models.AuditTargetApprovalChain, objID.Hex(), before, after)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Rantai persetujuan berhasil diupdate"})
+}
+
+// DeleteApprovalChain godoc
+// @Summary Delete Approval Chain
+// @Description Menghapus rantai persetujuan (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Approval Chain ID"
+// @Success 200 {object} object{message=string} "Rantai persetujuan berhasil dihapus"
+// @Failure 400 {object} object{error=string} "Format ID tidak valid"
+// @Failure 404 {object} object{error=string} "Rantai persetujuan tidak ditemukan"
+// @Failure 500 {object} object{error=string} "Gagal menghapus rantai persetujuan"
+// @Router /admin/approval-chains/{id} [delete]
+func (h *ApprovalChainHandler) DeleteApprovalChain(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format ID rantai persetujuan tidak valid"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	before, err := h.chainRepo.FindByID(ctx, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mencari rantai persetujuan: %v", err)})
+	}
+
+	result, err := h.chainRepo.Delete(ctx, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal menghapus rantai persetujuan: %v", err)})
+	}
+	if result.DeletedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Rantai persetujuan tidak ditemukan"})
+	}
+	recordAudit(c, models.AuditActionApprovalChainDelete, models.AuditTargetApprovalChain, objID.Hex(), before, nil)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Rantai persetujuan berhasil dihapus"})
+}
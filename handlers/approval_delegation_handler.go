@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/models"
+	util "Sistem-Manajemen-Karyawan/pkg/utils"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+type ApprovalDelegationHandler struct {
+	delegationRepo repository.ApprovalDelegationRepository
+	userRepo       *repository.UserRepository
+	roleRepo       repository.RoleRepository
+}
+
+func NewApprovalDelegationHandler(delegationRepo repository.ApprovalDelegationRepository, userRepo *repository.UserRepository, roleRepo repository.RoleRepository) *ApprovalDelegationHandler {
+	return &ApprovalDelegationHandler{
+		delegationRepo: delegationRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+	}
+}
+
+// CreateDelegation godoc
+// @Summary Create Approval Delegation
+// @Description Mendelegasikan hak persetujuan user yang sedang login kepada user lain untuk rentang tanggal tertentu (misal: selama cuti). Selama delegasi berlaku, delegate dapat memutuskan langkah persetujuan yang ditujukan langsung ke delegator (atasan langsung, kepala departemen, atau user tertentu) atas nama delegator. Langkah yang ditujukan ke role tidak ikut didelegasikan. Delegate tidak dapat memutuskan pengajuannya sendiri.
+// @Tags Delegation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body models.ApprovalDelegationPayload true "Data delegasi"
+// @Success 201 {object} models.ApprovalDelegation "Delegasi berhasil dibuat"
+// @Failure 400 {object} object{error=string} "Payload atau tanggal tidak valid, atau delegate nonaktif"
+// @Failure 404 {object} object{error=string} "Delegate tidak ditemukan"
+// @Failure 409 {object} object{error=string} "Sudah ada delegasi pada rentang tanggal tersebut"
+// @Failure 500 {object} object{error=string} "Gagal membuat delegasi"
+// @Router /delegations [post]
+func (h *ApprovalDelegationHandler) CreateDelegation(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi"})
+	}
+
+	var payload models.ApprovalDelegationPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	delegateID, err := primitive.ObjectIDFromHex(payload.DelegateID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format delegate_id tidak valid"})
+	}
+	if delegateID == claims.UserID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tidak dapat mendelegasikan persetujuan kepada diri sendiri"})
+	}
+
+	startDate, errStart := time.Parse("2006-01-02", payload.StartDate)
+	endDate, errEnd := time.Parse("2006-01-02", payload.EndDate)
+	if errStart != nil || errEnd != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format tanggal tidak valid (YYYY-MM-DD)"})
+	}
+	if endDate.Before(startDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tanggal selesai tidak boleh sebelum tanggal mulai"})
+	}
+	if payload.EndDate < todayWIB() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Rentang delegasi sudah lewat"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	delegate, err := h.userRepo.FindUserByID(ctx, delegateID)
+	if err != nil {
+		log.Printf("ERROR: Gagal mengambil delegate %s: %v", delegateID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa data delegate"})
+	}
+	if delegate == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Delegate tidak ditemukan"})
+	}
+	if delegate.EmploymentStatus == models.EmploymentStatusInactive {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tidak dapat mendelegasikan persetujuan kepada karyawan nonaktif"})
+	}
+
+	existing, err := h.delegationRepo.FindOverlapping(ctx, claims.UserID, payload.StartDate, payload.EndDate)
+	if err != nil {
+		log.Printf("ERROR: Gagal memeriksa delegasi user %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa delegasi yang ada"})
+	}
+	if existing != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Sudah ada delegasi aktif yang beririsan dengan rentang tanggal tersebut (" + existing.StartDate + " s/d " + existing.EndDate + ")",
+		})
+	}
+
+	delegation := &models.ApprovalDelegation{
+		DelegatorID:   claims.UserID,
+		DelegatorRole: claims.Role,
+		DelegateID:    delegateID,
+		StartDate:     payload.StartDate,
+		EndDate:       payload.EndDate,
+		Reason:        payload.Reason,
+	}
+	if _, err := h.delegationRepo.Create(ctx, delegation); err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat delegasi"})
+	}
+
+	recordAudit(c, models.AuditActionDelegationCreate, models.AuditTargetDelegation, delegation.ID.Hex(), nil, delegation)
+
+	return c.Status(fiber.StatusCreated).JSON(delegation)
+}
+
+// GetMyDelegations godoc
+// @Summary Get My Delegations
+// @Description Mengambil delegasi persetujuan yang diberikan maupun diterima oleh user yang sedang login
+// @Tags Delegation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.ApprovalDelegation "Daftar delegasi"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 500 {object} object{error=string} "Gagal mengambil delegasi"
+// @Router /delegations [get]
+func (h *ApprovalDelegationHandler) GetMyDelegations(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi"})
+	}
+
+	delegations, err := h.delegationRepo.FindByUser(c.Context(), claims.UserID)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil delegasi"})
+	}
+	return c.Status(fiber.StatusOK).JSON(delegations)
+}
+
+// RevokeDelegation godoc
+// @Summary Revoke Approval Delegation
+// @Description Mencabut delegasi persetujuan. Hanya delegator atau admin yang dapat mencabut.
+// @Tags Delegation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Delegation ID"
+// @Success 200 {object} object{message=string} "Delegasi berhasil dicabut"
+// @Failure 400 {object} object{error=string} "ID tidak valid"
+// @Failure 403 {object} object{error=string} "Bukan pemilik delegasi"
+// @Failure 404 {object} object{error=string} "Delegasi tidak ditemukan"
+// @Failure 409 {object} object{error=string} "Delegasi sudah dicabut"
+// @Failure 500 {object} object{error=string} "Gagal mencabut delegasi"
+// @Router /delegations/{id} [delete]
+func (h *ApprovalDelegationHandler) RevokeDelegation(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi"})
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID delegasi tidak valid"})
+	}
+
+	delegation, err := h.delegationRepo.FindByID(c.Context(), id)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil delegasi"})
+	}
+	if delegation == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Delegasi tidak ditemukan"})
+	}
+	if delegation.DelegatorID != claims.UserID && !hasPermission(c.Context(), h.roleRepo, claims, models.PermissionDelegationsManageAll) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda hanya dapat mencabut delegasi milik Anda sendiri"})
+	}
+
+	result, err := h.delegationRepo.Revoke(c.Context(), id)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mencabut delegasi"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Delegasi sudah dicabut sebelumnya"})
+	}
+	after, err := h.delegationRepo.FindByID(c.Context(), id)
+	if err != nil {
+		log.Printf("ERROR: Gagal membaca delegasi %s setelah dicabut untuk audit log: %v", id.Hex(), err)
+	}
+	recordAudit(c, models.AuditActionDelegationRevoke, models.AuditTargetDelegation, id.Hex(), delegation, after)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Delegasi berhasil dicabut"})
+}
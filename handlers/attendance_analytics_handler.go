@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+// analyticsMaxRangeDays adalah rentang tanggal maksimum untuk tren harian dan peringkat keterlambatan.
+const analyticsMaxRangeDays = 366
+
+// parseAnalyticsMonth membaca query month (YYYY-MM, default bulan ini WIB) dan mengembalikan tanggal
+// awal dan akhir bulan tersebut.
+func parseAnalyticsMonth(c *fiber.Ctx) (month, startDate, endDate string, err error) {
+	wib, _ := time.LoadLocation("Asia/Jakarta")
+	month = c.Query("month", time.Now().In(wib).Format("2006-01"))
+	first, err := time.Parse("2006-01", month)
+	if err != nil {
+		return "", "", "", errors.New("Format bulan tidak valid (YYYY-MM)")
+	}
+	return month, first.Format("2006-01-02"), first.AddDate(0, 1, -1).Format("2006-01-02"), nil
+}
+
+// parseAnalyticsRange membaca query start_date dan end_date (YYYY-MM-DD). Tanggal yang kosong diisi
+// dari defaultStart dan defaultEnd.
+func parseAnalyticsRange(c *fiber.Ctx, defaultStart, defaultEnd time.Time) (start, end time.Time, err error) {
+	start, end = defaultStart, defaultEnd
+	var errStart, errEnd error
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		start, errStart = time.Parse("2006-01-02", startDateStr)
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		end, errEnd = time.Parse("2006-01-02", endDateStr)
+	}
+	if errStart != nil || errEnd != nil {
+		return start, end, errors.New("Format tanggal tidak valid (YYYY-MM-DD)")
+	}
+	if end.Before(start) {
+		return start, end, errors.New("Tanggal selesai tidak boleh sebelum tanggal mulai")
+	}
+	if end.Sub(start) >= analyticsMaxRangeDays*24*time.Hour {
+		return start, end, errors.New("Rentang tanggal maksimal 366 hari")
+	}
+	return start, end, nil
+}
+
+// todayDateWIB mengembalikan tanggal hari ini (WIB) sebagai tengah malam UTC, sama seperti hasil
+// time.Parse("2006-01-02", ...), sehingga bisa dibandingkan dengan tanggal dari query.
+func todayDateWIB() time.Time {
+	today, _ := time.Parse("2006-01-02", todayWIB())
+	return today
+}
+
+// GetEmployeeAttendanceSummary godoc
+// @Summary Get Monthly Attendance Summary per Employee
+// @Description Ringkasan absensi bulanan setiap karyawan: jumlah per status, total menit keterlambatan, dan tingkat kehadiran (Hadir + Terlambat dibanding hari wajib masuk; Cuti dan Sakit tidak dihitung). Record Terlambat yang dibuat sebelum late_minutes dicatat dihitung 0 menit.
+// @Tags Attendance
+// @Produce json
+// @Security BearerAuth
+// @Param month query string false "Bulan (YYYY-MM, default: bulan ini)"
+// @Param department query string false "Filter nama departemen"
+// @Success 200 {object} object{month=string,start_date=string,end_date=string,data=[]models.EmployeeAttendanceSummary} "Ringkasan absensi per karyawan"
+// @Failure 400 {object} object{error=string} "Format bulan tidak valid"
+// @Failure 500 {object} object{error=string} "Gagal menghitung ringkasan absensi"
+// @Router /attendance/analytics/employees [get]
+func (h *AttendanceHandler) GetEmployeeAttendanceSummary(c *fiber.Ctx) error {
+	month, startDate, endDate, err := parseAnalyticsMonth(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	summaries, err := h.repo.SummarizeByEmployee(ctx, startDate, endDate, c.Query("department"))
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung ringkasan absensi karyawan"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"month":      month,
+		"start_date": startDate,
+		"end_date":   endDate,
+		"data":       summaries,
+	})
+}
+
+// GetDepartmentAttendanceSummary godoc
+// @Summary Get Monthly Attendance Summary per Department
+// @Description Ringkasan absensi bulanan setiap departemen: jumlah karyawan, jumlah per status, total menit keterlambatan, dan tingkat kehadiran. Record Terlambat yang dibuat sebelum late_minutes dicatat dihitung 0 menit.
+// @Tags Attendance
+// @Produce json
+// @Security BearerAuth
+// @Param month query string false "Bulan (YYYY-MM, default: bulan ini)"
+// @Success 200 {object} object{month=string,start_date=string,end_date=string,data=[]models.DepartmentAttendanceSummary} "Ringkasan absensi per departemen"
+// @Failure 400 {object} object{error=string} "Format bulan tidak valid"
+// @Failure 500 {object} object{error=string} "Gagal menghitung ringkasan absensi"
+// @Router /attendance/analytics/departments [get]
+func (h *AttendanceHandler) GetDepartmentAttendanceSummary(c *fiber.Ctx) error {
+	month, startDate, endDate, err := parseAnalyticsMonth(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	summaries, err := h.repo.SummarizeByDepartment(ctx, startDate, endDate)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung ringkasan absensi departemen"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"month":      month,
+		"start_date": startDate,
+		"end_date":   endDate,
+		"data":       summaries,
+	})
+}
+
+// GetDailyAttendanceTrend godoc
+// @Summary Get Daily Attendance Trend
+// @Description Jumlah absensi per status untuk setiap tanggal dalam rentang (default: 30 hari terakhir). Tanggal tanpa record absensi tetap disertakan dengan jumlah 0.
+// @Tags Attendance
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string false "Tanggal mulai (YYYY-MM-DD)"
+// @Param end_date query string false "Tanggal selesai (YYYY-MM-DD, default: hari ini)"
+// @Param department query string false "Filter nama departemen"
+// @Success 200 {object} object{start_date=string,end_date=string,data=[]models.DailyAttendanceTrend} "Tren absensi harian"
+// @Failure 400 {object} object{error=string} "Rentang tanggal tidak valid (maks 366 hari)"
+// @Failure 500 {object} object{error=string} "Gagal menghitung tren absensi"
+// @Router /attendance/analytics/daily [get]
+func (h *AttendanceHandler) GetDailyAttendanceTrend(c *fiber.Ctx) error {
+	today := todayDateWIB()
+	start, end, err := parseAnalyticsRange(c, today.AddDate(0, 0, -29), today)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	startDate, endDate := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	trend, err := h.repo.DailyTrend(ctx, startDate, endDate, c.Query("department"))
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung tren absensi harian"})
+	}
+
+	// Lengkapi tanggal tanpa record agar deret waktu tidak berlubang.
+	byDate := make(map[string]models.DailyAttendanceTrend, len(trend))
+	for _, day := range trend {
+		byDate[day.Date] = day
+	}
+	series := make([]models.DailyAttendanceTrend, 0, int(end.Sub(start).Hours()/24)+1)
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		day, found := byDate[date]
+		if !found {
+			day = models.DailyAttendanceTrend{Date: date}
+		}
+		series = append(series, day)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"start_date": startDate,
+		"end_date":   endDate,
+		"data":       series,
+	})
+}
+
+// GetLatenessRanking godoc
+// @Summary Get Top Lateness Ranking
+// @Description Peringkat karyawan dengan total menit keterlambatan terbanyak dalam rentang tanggal (default: bulan ini). Urutan memakai jumlah hari terlambat sebagai pembanding kedua karena record Terlambat yang dibuat sebelum late_minutes dicatat dihitung 0 menit.
+// @Tags Attendance
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string false "Tanggal mulai (YYYY-MM-DD, default: awal bulan ini)"
+// @Param end_date query string false "Tanggal selesai (YYYY-MM-DD, default: hari ini)"
+// @Param department query string false "Filter nama departemen"
+// @Param limit query int false "Jumlah karyawan (default: 10, maks: 100)"
+// @Success 200 {object} object{start_date=string,end_date=string,data=[]models.LatenessRanking} "Peringkat keterlambatan"
+// @Failure 400 {object} object{error=string} "Rentang tanggal tidak valid (maks 366 hari)"
+// @Failure 500 {object} object{error=string} "Gagal menghitung peringkat keterlambatan"
+// @Router /attendance/analytics/lateness [get]
+func (h *AttendanceHandler) GetLatenessRanking(c *fiber.Ctx) error {
+	today := todayDateWIB()
+	start, end, err := parseAnalyticsRange(c, today.AddDate(0, 0, 1-today.Day()), today)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	startDate, endDate := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	rankings, err := h.repo.TopLateness(ctx, startDate, endDate, c.Query("department"), int64(limit))
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung peringkat keterlambatan"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"start_date": startDate,
+		"end_date":   endDate,
+		"data":       rankings,
+	})
+}
@@ -13,25 +13,34 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"Sistem-Manajemen-Karyawan/models"
+	util "Sistem-Manajemen-Karyawan/pkg/utils"
 	"Sistem-Manajemen-Karyawan/repository"
 )
 
 type AttendanceHandler struct {
 	repo             repository.AttendanceRepository
 	workScheduleRepo *repository.WorkScheduleRepository
+	leaveRepo        repository.LeaveRequestRepository
+	activityRepo     repository.ActivityRepository
+	userRepo         *repository.UserRepository
+	roleRepo         repository.RoleRepository
 }
 
-func NewAttendanceHandler(repo repository.AttendanceRepository, workScheduleRepo *repository.WorkScheduleRepository) *AttendanceHandler {
+func NewAttendanceHandler(repo repository.AttendanceRepository, workScheduleRepo *repository.WorkScheduleRepository, leaveRepo repository.LeaveRequestRepository, activityRepo repository.ActivityRepository, userRepo *repository.UserRepository, roleRepo repository.RoleRepository) *AttendanceHandler {
 	return &AttendanceHandler{
 		repo:             repo,
 		workScheduleRepo: workScheduleRepo,
+		leaveRepo:        leaveRepo,
+		activityRepo:     activityRepo,
+		userRepo:         userRepo,
+		roleRepo:         roleRepo,
 	}
 
 }
 
 // ScanQRCode godoc
 // @Summary Scan QR Code untuk Check-in/Check-out
-// @Description Melakukan scan QR code untuk proses check-in atau check-out karyawan
+// @Description Melakukan scan QR code untuk proses check-in karyawan. Karyawan hanya bisa check-in untuk dirinya sendiri (user_id boleh dikosongkan). Pemegang izin attendance.scan, mis. API key badge reader, mengisi user_id karyawan yang di-scan.
 // @Tags Attendance
 // @Accept json
 // @Produce json
@@ -39,7 +48,9 @@ func NewAttendanceHandler(repo repository.AttendanceRepository, workScheduleRepo
 // @Param payload body models.QRCodeScanPayload true "Data QR Code scan"
 // @Success 200 {object} object{message=string} "Berhasil check-in/check-out"
 // @Success 201 {object} object{message=string} "Berhasil check-in"
-// @Failure 400 {object} object{error=string} "Payload tidak valid atau QR Code bermasalah"
+// @Failure 400 {object} object{error=string} "Payload tidak valid, user_id tidak valid, atau QR Code bermasalah"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 403 {object} object{error=string} "Check-in untuk karyawan lain tanpa izin attendance.scan"
 // @Failure 404 {object} object{error=string} "QR Code tidak ditemukan"
 // @Failure 409 {object} object{error=string} "Sudah melakukan check-in dan check-out"
 // @Failure 500 {object} object{error=string} "Gagal melakukan check-in/check-out"
@@ -47,10 +58,23 @@ func NewAttendanceHandler(repo repository.AttendanceRepository, workScheduleRepo
 // handlers/attendance_handler.go
 // file: handlers/attendance_handler.go
 func (h *AttendanceHandler) ScanQRCode(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Klaim token tidak valid atau sesi rusak"})
+	}
+
 	var payload models.QRCodeScanPayload
 	if err := c.BodyParser(&payload); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload tidak valid: " + err.Error()})
 	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	userID, ferr := h.scanTargetUserID(c.Context(), claims, payload.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+	}
 
 	wib, _ := time.LoadLocation("Asia/Jakarta")
 	now := time.Now().In(wib)
@@ -62,11 +86,6 @@ func (h *AttendanceHandler) ScanQRCode(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "QR Code tidak valid atau sudah kadaluarsa."})
 	}
 
-	userID, err := primitive.ObjectIDFromHex(payload.UserID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format User ID tidak valid."})
-	}
-
 	// 2. Cek duplikasi absensi
 	existingAttendance, err := h.repo.FindAttendanceByUserAndDate(c.Context(), userID, today)
 	if err == nil && existingAttendance != nil {
@@ -81,6 +100,20 @@ func (h *AttendanceHandler) ScanQRCode(c *fiber.Ctx) error {
 
 	// -- BARIS TYPE ASSERTION YANG KEMARIN KITA TAMBAHKAN, SEKARANG DIHAPUS --
 
+	// 3b. Geser jam masuk jika ada cuti setengah hari / per jam yang sudah disetujui hari ini,
+	// supaya karyawan yang cuti pagi tidak dianggap Terlambat.
+	approvedLeaves, err := h.leaveRepo.FindApprovedRequestsByUserAndDate(c.Context(), userID, today)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa pengajuan cuti hari ini: " + err.Error()})
+	}
+	todaysSchedule, coveredByLeave, err := util.AdjustScheduleForLeaves(todaysSchedule, approvedLeaves)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyesuaikan jadwal dengan cuti: " + err.Error()})
+	}
+	if coveredByLeave {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": fmt.Sprintf("Anda memiliki pengajuan '%s' yang sudah disetujui untuk seluruh jam kerja hari ini.", approvedLeaves[0].RequestType)})
+	}
+
 	// 4. Logika perbandingan waktu
 	scheduledStartTime, _ := time.ParseInLocation("15:04", todaysSchedule.StartTime, wib)
 	scheduleCheckInTime := time.Date(
@@ -92,33 +125,80 @@ func (h *AttendanceHandler) ScanQRCode(c *fiber.Ctx) error {
 	latestCheckInTime := scheduleCheckInTime.Add(gracePeriod)
 
 	var attendanceStatus string
+	var lateMinutes int
 	if now.After(latestCheckInTime) {
 		attendanceStatus = "Terlambat"
+		lateMinutes = int(now.Sub(scheduleCheckInTime).Minutes())
 	} else {
 		attendanceStatus = "Hadir"
 	}
 
 	// 5. Membuat record absensi baru
 	newAttendance := models.Attendance{
-		ID:        primitive.NewObjectID(),
-		UserID:    userID,
-		Date:      today,
-		CheckIn:   now.Format("15:04"),
-		Status:    attendanceStatus,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Date:        today,
+		CheckIn:     now.Format("15:04"),
+		Status:      attendanceStatus,
+		LateMinutes: lateMinutes,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 
 	_, err = h.repo.CreateAttendance(c.Context(), &newAttendance)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan data check-in: " + err.Error()})
 	}
+	recordAudit(c, models.AuditActionAttendanceScan, models.AuditTargetAttendance, newAttendance.ID.Hex(), nil, newAttendance)
+	recordActivity(c.Context(), h.activityRepo, models.Activity{
+		Type:        models.ActivityCheckIn,
+		UserID:      userID,
+		ActorID:     activityActor(c),
+		ReferenceID: &newAttendance.ID,
+		Message:     fmt.Sprintf("Check-in pukul %s (%s)", newAttendance.CheckIn, newAttendance.Status),
+	})
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": fmt.Sprintf("Berhasil check-in pukul %s. Status Anda: %s", newAttendance.CheckIn, newAttendance.Status),
 	})
 }
 
+// scanTargetUserID menentukan karyawan yang di-check-in oleh ScanQRCode. User tanpa izin
+// attendance.scan hanya bisa check-in untuk dirinya sendiri. Pemegang izin tersebut boleh mengisi
+// user_id karyawan aktif mana pun; API key wajib mengisinya karena UserID pada klaim API key
+// adalah admin pembuat key, bukan karyawan yang di-scan.
+func (h *AttendanceHandler) scanTargetUserID(ctx context.Context, claims *models.Claims, requested string) (primitive.ObjectID, *fiber.Error) {
+	if requested == "" {
+		if claims.APIKeyID != nil {
+			return primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "user_id wajib diisi untuk scan dengan API key")
+		}
+		return claims.UserID, nil
+	}
+
+	userID, err := primitive.ObjectIDFromHex(requested)
+	if err != nil {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "Format User ID tidak valid.")
+	}
+	if userID == claims.UserID && claims.APIKeyID == nil {
+		return userID, nil
+	}
+	if !hasPermission(ctx, h.roleRepo, claims, models.PermissionAttendanceScan) {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusForbidden, "Anda hanya dapat melakukan absensi untuk diri sendiri.")
+	}
+
+	user, err := h.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa user")
+	}
+	if user == nil {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "user_id tidak ditemukan")
+	}
+	if user.EmploymentStatus == models.EmploymentStatusInactive {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "user_id merujuk ke karyawan yang tidak aktif")
+	}
+	return userID, nil
+}
+
 // GenerateQRCode godoc
 // @Summary Generate QR Code untuk Attendance
 // @Description Membuat QR code baru untuk attendance atau mengembalikan QR code yang masih aktif
@@ -188,8 +268,6 @@ func (h *AttendanceHandler) GenerateQRCode(c *fiber.Ctx) error {
 	})
 }
 
-
-
 // GetAttendanceHistoryForAdmin godoc
 // @Summary Get Attendance History for All Employees (Admin)
 // @Description Mengambil riwayat kehadiran semua karyawan dengan filter dan pagination (admin only)
@@ -209,9 +287,9 @@ func (h *AttendanceHandler) GenerateQRCode(c *fiber.Ctx) error {
 // @Failure 500 {object} object{error=string} "Internal server error"
 // @Router /admin/attendance/history [get]
 func (h *AttendanceHandler) GetAttendanceHistoryForAdmin(c *fiber.Ctx) error {
-	claims, ok := c.Locals("user").(*models.Claims)
-	if !ok || claims.Role != "admin" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak. Hanya admin."})
+	// Hak akses (attendance.read.all) diperiksa RequirePermission di router.
+	if _, ok := c.Locals("user").(*models.Claims); !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi"})
 	}
 
 	page := c.QueryInt("page", 1)
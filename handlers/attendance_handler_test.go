@@ -0,0 +1,220 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/config/middleware"
+	"Sistem-Manajemen-Karyawan/handlers"
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+const scanTestQRCode = "qr-hari-ini"
+
+// memoryAttendanceRepo mengenali satu QR code aktif dan menganggap setiap karyawan sudah check-in,
+// sehingga ScanQRCode berhenti setelah menentukan karyawan yang di-scan (dicatat di checkedUsers).
+type memoryAttendanceRepo struct {
+	repository.AttendanceRepository
+	checkedUsers []primitive.ObjectID
+}
+
+func (r *memoryAttendanceRepo) FindQRCodeByValue(ctx context.Context, code string) (*models.QRCode, error) {
+	if code != scanTestQRCode {
+		return nil, nil
+	}
+	wib, _ := time.LoadLocation("Asia/Jakarta")
+	now := time.Now().In(wib)
+	return &models.QRCode{Code: code, Date: now.Format("2006-01-02"), ExpiresAt: now.Add(time.Minute)}, nil
+}
+
+func (r *memoryAttendanceRepo) FindAttendanceByUserAndDate(ctx context.Context, userID primitive.ObjectID, date string) (*models.Attendance, error) {
+	r.checkedUsers = append(r.checkedUsers, userID)
+	return &models.Attendance{UserID: userID, Date: date, Status: "Hadir"}, nil
+}
+
+// memoryRoleRepo memetakan nama role ke izinnya.
+type memoryRoleRepo struct {
+	repository.RoleRepository
+	permissions map[string][]string
+}
+
+func (r *memoryRoleRepo) HasPermission(ctx context.Context, roleName, permission string) (bool, error) {
+	for _, p := range r.permissions[roleName] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type scanTestEnv struct {
+	app         *fiber.App
+	attendances *memoryAttendanceRepo
+}
+
+// newScanTestEnv memasang ScanQRCode dengan middleware yang sama seperti di router. Klaim request
+// diambil dari caller: klaim dengan APIKeyID disimpan sebagai API key, selain itu sebagai user login.
+func newScanTestEnv(mt *mtest.T, caller *models.Claims) *scanTestEnv {
+	mt.Helper()
+
+	previousConn := config.MongoConn
+	config.MongoConn = mt.Client
+	mt.Cleanup(func() { config.MongoConn = previousConn })
+
+	env := &scanTestEnv{attendances: &memoryAttendanceRepo{}}
+	roleRepo := &memoryRoleRepo{permissions: map[string][]string{
+		models.RoleKaryawan: {},
+		"resepsionis":       {models.PermissionAttendanceScan},
+	}}
+	h := handlers.NewAttendanceHandler(env.attendances, nil, nil, nil, repository.NewUserRepository(), roleRepo)
+
+	env.app = fiber.New()
+	env.app.Post("/attendance/scan",
+		func(c *fiber.Ctx) error {
+			if caller.APIKeyID != nil {
+				c.Locals("api_key", caller)
+			} else {
+				c.Locals("user", caller)
+			}
+			return c.Next()
+		},
+		middleware.RequireAPIKeyPermission(roleRepo, models.PermissionAttendanceScan),
+		h.ScanQRCode,
+	)
+	return env
+}
+
+func (env *scanTestEnv) scan(t testing.TB, userID string) (int, map[string]interface{}) {
+	t.Helper()
+	raw, err := json.Marshal(models.QRCodeScanPayload{QRCodeValue: scanTestQRCode, UserID: userID})
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/attendance/scan", bytes.NewReader(raw))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	resp, err := env.app.Test(req, 10000)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("respons bukan JSON: %v", err)
+	}
+	return resp.StatusCode, body
+}
+
+// wantScannedUser memastikan ScanQRCode memproses check-in untuk user tersebut.
+func (env *scanTestEnv) wantScannedUser(t testing.TB, status int, body map[string]interface{}, userID primitive.ObjectID) {
+	t.Helper()
+	if status != fiber.StatusConflict {
+		t.Fatalf("status = %d, body = %v, want 409 dari record absensi yang sudah ada", status, body)
+	}
+	if len(env.attendances.checkedUsers) != 1 || env.attendances.checkedUsers[0] != userID {
+		t.Errorf("check-in untuk %v, want %s", env.attendances.checkedUsers, userID.Hex())
+	}
+}
+
+func (env *scanTestEnv) wantNoScan(t testing.TB) {
+	t.Helper()
+	if len(env.attendances.checkedUsers) != 0 {
+		t.Errorf("check-in diproses untuk %v", env.attendances.checkedUsers)
+	}
+}
+
+func TestScanQRCode(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	employee := models.User{ID: primitive.NewObjectID(), Name: "Budi", Email: "budi@example.com", Role: models.RoleKaryawan, EmploymentStatus: models.EmploymentStatusActive}
+	keyID := primitive.NewObjectID()
+	badgeReader := func(permissions ...string) *models.Claims {
+		return &models.Claims{UserID: primitive.NewObjectID(), APIKeyID: &keyID, Permissions: permissions}
+	}
+
+	mt.Run("karyawan check-in untuk diri sendiri tanpa user_id", func(mt *mtest.T) {
+		env := newScanTestEnv(mt, &models.Claims{UserID: employee.ID, Role: models.RoleKaryawan})
+		status, body := env.scan(mt, "")
+		env.wantScannedUser(mt, status, body, employee.ID)
+	})
+
+	mt.Run("karyawan boleh mengisi user_id miliknya sendiri", func(mt *mtest.T) {
+		env := newScanTestEnv(mt, &models.Claims{UserID: employee.ID, Role: models.RoleKaryawan})
+		status, body := env.scan(mt, employee.ID.Hex())
+		env.wantScannedUser(mt, status, body, employee.ID)
+	})
+
+	mt.Run("karyawan tidak bisa check-in untuk karyawan lain", func(mt *mtest.T) {
+		env := newScanTestEnv(mt, &models.Claims{UserID: primitive.NewObjectID(), Role: models.RoleKaryawan})
+		status, body := env.scan(mt, employee.ID.Hex())
+		if status != fiber.StatusForbidden {
+			mt.Fatalf("status = %d, body = %v, want 403", status, body)
+		}
+		env.wantNoScan(mt)
+	})
+
+	mt.Run("user dengan izin attendance.scan bisa check-in untuk karyawan lain", func(mt *mtest.T) {
+		env := newScanTestEnv(mt, &models.Claims{UserID: primitive.NewObjectID(), Role: "resepsionis"})
+		mt.AddMockResponses(userFound(mt, employee))
+		status, body := env.scan(mt, employee.ID.Hex())
+		env.wantScannedUser(mt, status, body, employee.ID)
+	})
+
+	mt.Run("API key badge reader check-in untuk user_id pada payload", func(mt *mtest.T) {
+		env := newScanTestEnv(mt, badgeReader(models.PermissionAttendanceScan))
+		mt.AddMockResponses(userFound(mt, employee))
+		status, body := env.scan(mt, employee.ID.Hex())
+		env.wantScannedUser(mt, status, body, employee.ID)
+	})
+
+	mt.Run("API key tanpa izin attendance.scan ditolak", func(mt *mtest.T) {
+		env := newScanTestEnv(mt, badgeReader(models.PermissionAttendanceReadAll))
+		status, body := env.scan(mt, employee.ID.Hex())
+		if status != fiber.StatusForbidden || body["permission"] != models.PermissionAttendanceScan {
+			mt.Fatalf("status = %d, body = %v, want 403 untuk izin %s", status, body, models.PermissionAttendanceScan)
+		}
+		env.wantNoScan(mt)
+	})
+
+	mt.Run("API key wajib mengisi user_id", func(mt *mtest.T) {
+		env := newScanTestEnv(mt, badgeReader(models.PermissionAttendanceScan))
+		status, body := env.scan(mt, "")
+		if status != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, body = %v, want 400", status, body)
+		}
+		env.wantNoScan(mt)
+	})
+
+	mt.Run("API key untuk user_id yang tidak terdaftar ditolak", func(mt *mtest.T) {
+		env := newScanTestEnv(mt, badgeReader(models.PermissionAttendanceScan))
+		mt.AddMockResponses(userNotFound())
+		status, body := env.scan(mt, primitive.NewObjectID().Hex())
+		if status != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, body = %v, want 400", status, body)
+		}
+		env.wantNoScan(mt)
+	})
+
+	mt.Run("API key untuk karyawan tidak aktif ditolak", func(mt *mtest.T) {
+		env := newScanTestEnv(mt, badgeReader(models.PermissionAttendanceScan))
+		inactive := employee
+		inactive.EmploymentStatus = models.EmploymentStatusInactive
+		mt.AddMockResponses(userFound(mt, inactive))
+		status, body := env.scan(mt, inactive.ID.Hex())
+		if status != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, body = %v, want 400", status, body)
+		}
+		env.wantNoScan(mt)
+	})
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+// auditMaskedFields adalah field yang perubahannya dicatat tanpa nilai.
+var auditMaskedFields = map[string]bool{
+	"password": true,
+}
+
+// auditIgnoredFields tidak dicatat sebagai perubahan: updated_at berubah pada setiap update, dan
+// revisions adalah riwayat perubahan pengajuan cuti yang sudah tersimpan di pengajuan itu sendiri.
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+	"revisions":  true,
+}
+
+// recordAudit melengkapi entri audit log request ini yang disiapkan middleware.AuditMiddleware.
+// before dan after adalah keadaan objek sebelum dan sesudah perubahan (nil untuk data baru atau
+// data yang dihapus); hanya field yang berbeda yang disimpan. Entri baru disimpan jika request
+// berhasil.
+func recordAudit(c *fiber.Ctx, action, targetType, targetID string, before, after interface{}) {
+	entry, ok := c.Locals("audit").(*models.AuditLog)
+	if !ok {
+		return
+	}
+	entry.Action = action
+	entry.TargetType = targetType
+	entry.TargetID = targetID
+	entry.Changes = auditChanges(before, after)
+}
+
+// auditChanges membandingkan dua objek berdasarkan representasi JSON-nya, sehingga field yang tidak
+// pernah dikirim ke client (json:"-") juga tidak masuk audit log.
+func auditChanges(before, after interface{}) []models.AuditChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	fieldSet := make(map[string]bool, len(beforeFields)+len(afterFields))
+	for field := range beforeFields {
+		fieldSet[field] = true
+	}
+	for field := range afterFields {
+		fieldSet[field] = true
+	}
+	fields := make([]string, 0, len(fieldSet))
+	for field := range fieldSet {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var changes []models.AuditChange
+	for _, field := range fields {
+		if auditIgnoredFields[field] {
+			continue
+		}
+		oldValue, newValue := beforeFields[field], afterFields[field]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		// Untuk data baru atau data yang dihapus, field kosong tidak perlu dicatat.
+		if (beforeFields == nil || afterFields == nil) && isEmptyAuditValue(oldValue) && isEmptyAuditValue(newValue) {
+			continue
+		}
+		if auditMaskedFields[field] {
+			changes = append(changes, models.AuditChange{Field: field})
+			continue
+		}
+		changes = append(changes, models.AuditChange{Field: field, Before: oldValue, After: newValue})
+	}
+	return changes
+}
+
+// auditFields mengubah objek menjadi map field JSON.
+func auditFields(v interface{}) map[string]interface{} {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// isEmptyAuditValue mengembalikan true untuk nilai kosong hasil auditFields (string kosong, 0,
+// false, ObjectID atau waktu nol).
+func isEmptyAuditValue(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == "" || value == primitive.NilObjectID.Hex() || value == (time.Time{}).Format(time.RFC3339)
+	case float64:
+		return value == 0
+	case bool:
+		return !value
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+	return false
+}
+
+type AuditLogHandler struct {
+	auditRepo repository.AuditLogRepository
+}
+
+func NewAuditLogHandler(auditRepo repository.AuditLogRepository) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditRepo: auditRepo,
+	}
+}
+
+// GetAuditLogs godoc
+// @Summary Get Audit Logs
+// @Description Mengambil audit log perubahan data (siapa mengubah apa, kapan, dari IP mana, beserta nilai sebelum dan sesudahnya), terbaru lebih dulu
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query string false "Filter user yang melakukan perubahan"
+// @Param action query string false "Filter aksi, contoh: user.update"
+// @Param target_type query string false "Filter jenis objek" Enums(user, department, leave_request, approval_chain, approval_delegation, work_schedule, role, api_key, attendance, session)
+// @Param target_id query string false "Filter ID objek"
+// @Param from query string false "Tanggal mulai (YYYY-MM-DD)"
+// @Param to query string false "Tanggal akhir, inklusif (YYYY-MM-DD)"
+// @Param page query int false "Nomor halaman (default: 1)"
+// @Param limit query int false "Jumlah item per halaman (default: 20, maks: 100)"
+// @Success 200 {object} object{data=[]models.AuditLog,total=int,page=int,limit=int} "Audit log"
+// @Failure 400 {object} object{error=string} "Format filter tidak valid"
+// @Failure 500 {object} object{error=string} "Gagal mengambil audit log"
+// @Router /admin/audit-logs [get]
+func (h *AuditLogHandler) GetAuditLogs(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := models.AuditLogFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+	if actorParam := c.Query("actor_id"); actorParam != "" {
+		actorID, err := primitive.ObjectIDFromHex(actorParam)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format actor_id tidak valid"})
+		}
+		filter.ActorID = &actorID
+	}
+	if fromParam := c.Query("from"); fromParam != "" {
+		from, err := time.ParseInLocation("2006-01-02", fromParam, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format tanggal 'from' tidak valid, gunakan YYYY-MM-DD"})
+		}
+		filter.From = &from
+	}
+	if toParam := c.Query("to"); toParam != "" {
+		to, err := time.ParseInLocation("2006-01-02", toParam, time.Local)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format tanggal 'to' tidak valid, gunakan YYYY-MM-DD"})
+		}
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	entries, total, err := h.auditRepo.Find(ctx, filter, int64(page), int64(limit))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mengambil audit log: %v", err)})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":  entries,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
@@ -3,12 +3,14 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/oidc"
 	"Sistem-Manajemen-Karyawan/pkg/paseto"
 	"Sistem-Manajemen-Karyawan/pkg/password"
 	util "Sistem-Manajemen-Karyawan/pkg/utils"
@@ -16,18 +18,90 @@ import (
 )
 
 type AuthHandler struct {
-	userRepo *repository.UserRepository
+	userRepo      *repository.UserRepository
+	tokenRepo     repository.TokenRepository
+	securityRepo  repository.SecurityEventRepository
+	twoFactorRepo repository.TwoFactorRepository
+	roleRepo      repository.RoleRepository
+	pasetoMaker   *paseto.PasetoMaker
+
+	twoFactorIssuer        string
+	twoFactorRequiredRoles []string
+
+	oidcProvider                 *oidc.Provider // nil jika login SSO tidak diaktifkan
+	oidcStateRepo                repository.OIDCStateRepository
+	passwordLoginDisabledDomains []string
+	passwordPolicy               *password.Policy
+	activityRepo                 repository.ActivityRepository
 }
 
-func NewAuthHandler(userRepo *repository.UserRepository) *AuthHandler {
+func NewAuthHandler(
+	userRepo *repository.UserRepository,
+	tokenRepo repository.TokenRepository,
+	securityRepo repository.SecurityEventRepository,
+	twoFactorRepo repository.TwoFactorRepository,
+	roleRepo repository.RoleRepository,
+	pasetoMaker *paseto.PasetoMaker,
+	twoFactorIssuer string,
+	twoFactorRequiredRoles []string,
+	oidcProvider *oidc.Provider,
+	oidcStateRepo repository.OIDCStateRepository,
+	passwordLoginDisabledDomains []string,
+	passwordPolicy *password.Policy,
+	activityRepo repository.ActivityRepository,
+) *AuthHandler {
 	return &AuthHandler{
-		userRepo: userRepo,
+		userRepo:               userRepo,
+		tokenRepo:              tokenRepo,
+		securityRepo:           securityRepo,
+		twoFactorRepo:          twoFactorRepo,
+		roleRepo:               roleRepo,
+		pasetoMaker:            pasetoMaker,
+		twoFactorIssuer:        twoFactorIssuer,
+		twoFactorRequiredRoles: twoFactorRequiredRoles,
+
+		oidcProvider:                 oidcProvider,
+		oidcStateRepo:                oidcStateRepo,
+		passwordLoginDisabledDomains: passwordLoginDisabledDomains,
+		passwordPolicy:               passwordPolicy,
+		activityRepo:                 activityRepo,
 	}
 }
 
+// Aturan proteksi brute-force login.
+const (
+	loginDelayAfterFailures = 3                // mulai jeda progresif setelah sekian kali gagal berturut-turut
+	loginMaxDelay           = 30 * time.Second // jeda maksimum antar percobaan
+	loginLockAfterFailures  = 5                // akun dikunci setelah sekian kali gagal berturut-turut
+	loginLockDuration       = 15 * time.Minute
+	loginIPMaxFailures      = 20 // login gagal per IP dalam loginIPWindow
+	loginIPWindow           = 15 * time.Minute
+)
+
+// errAccountInactive adalah pesan penolakan login dan refresh token untuk user yang status
+// kepegawaiannya tidak aktif.
+const errAccountInactive = "Akun tidak aktif. Hubungi admin."
+
+// loginRetryDelay mengembalikan jeda minimum sebelum percobaan berikutnya boleh dilakukan,
+// berlipat dua untuk setiap kegagalan setelah loginDelayAfterFailures.
+func loginRetryDelay(failedCount int) time.Duration {
+	if failedCount < loginDelayAfterFailures {
+		return 0
+	}
+	shift := failedCount - loginDelayAfterFailures
+	if shift > 5 {
+		return loginMaxDelay
+	}
+	delay := time.Second << shift
+	if delay > loginMaxDelay {
+		return loginMaxDelay
+	}
+	return delay
+}
+
 // Register godoc
 // @Summary Register User
-// @Description Mendaftarkan user baru (admin only)
+// @Description Mendaftarkan user baru dengan role apa pun (membutuhkan izin users.write)
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -35,6 +109,9 @@ func NewAuthHandler(userRepo *repository.UserRepository) *AuthHandler {
 // @Param user body models.UserRegisterPayload true "Data registrasi user"
 // @Success 201 {object} object{message=string,user_id=string} "User berhasil didaftarkan"
 // @Failure 400 {object} models.ValidationErrorResponse "Invalid request body atau validation error" // <-- Perbaikan di sini
+// @Failure 400 {object} object{error=string,violations=[]string} "Password tidak memenuhi kebijakan password"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 403 {object} object{error=string} "Tidak memiliki izin users.write"
 // @Failure 500 {object} object{error=string} "Gagal hash password atau gagal mendaftarkan user"
 // @Router /auth/register [post]
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
@@ -47,10 +124,26 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
 	}
 
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	role, err := h.roleRepo.FindByName(ctx, payload.Role)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "gagal memeriksa role"})
+	}
+	if role == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("role '%s' tidak ditemukan", payload.Role)})
+	}
+
+	if violations := h.passwordPolicy.Validate(payload.Password); len(violations) > 0 {
+		return respondPasswordPolicyViolation(c, violations)
+	}
+
 	hashedPassword, err := password.HashPassword(payload.Password)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "gagal hash password"})
 	}
+	passwordChangedAt := time.Now()
 
 	newUser := &models.User{
 		Name:         payload.Name,
@@ -63,16 +156,41 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		Address:      payload.Address,
 		Photo:        payload.Photo,
 		IsFirstLogin: true,
-	}
 
-	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
-	defer cancel()
+		EmploymentStatus:  payload.EmploymentStatus,
+		PasswordChangedAt: &passwordChangedAt,
+	}
+	if newUser.EmploymentStatus == "" {
+		newUser.EmploymentStatus = models.EmploymentStatusActive
+	}
+	if payload.ManagerID != "" {
+		managerID, ferr := findManagerID(ctx, h.userRepo, payload.ManagerID, primitive.NilObjectID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+		}
+		newUser.ManagerID = &managerID
+	}
 
 	result, err := h.userRepo.CreateUser(ctx, newUser)
 	if err != nil {
 
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mendaftarkan user: %v", err)})
 	}
+	recordAudit(c, models.AuditActionUserCreate, models.AuditTargetUser, newUser.ID.Hex(), nil, newUser)
+
+	joinedMessage := "Bergabung sebagai karyawan baru"
+	if newUser.Position != "" {
+		joinedMessage = "Bergabung sebagai " + newUser.Position
+	}
+	if newUser.Department != "" {
+		joinedMessage += " di departemen " + newUser.Department
+	}
+	recordActivity(ctx, h.activityRepo, models.Activity{
+		Type:    models.ActivityEmployeeJoined,
+		UserID:  newUser.ID,
+		ActorID: activityActor(c),
+		Message: joinedMessage,
+	})
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "User berhasil didaftarkan (oleh admin)",
@@ -82,14 +200,17 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 
 // Login godoc
 // @Summary Login User
-// @Description Melakukan proses login dan mengembalikan token PASETO jika email dan password valid
+// @Description Melakukan proses login dan mengembalikan access token PASETO (15 menit) beserta refresh token (7 hari) jika email dan password valid. Jika user memakai 2FA, respons berisi challenge_token yang harus diverifikasi di /auth/2fa/verify.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param credentials body models.UserLoginPayload true "Kredensial untuk Login"
-// @Success 200 {object} object{message=string,token=string,user=models.User} "Login berhasil"
+// @Success 200 {object} object{message=string,token=string,token_expires_at=string,refresh_token=string,refresh_token_expires_at=string,user=models.User,two_factor_required=bool,challenge_token=string} "Login berhasil, atau tantangan 2FA jika user memakai 2FA"
 // @Failure 400 {object} models.ValidationErrorResponse "Payload tidak valid atau validation error" // <-- Perbaikan di sini
 // @Failure 401 {object} object{error=string} "Kombinasi email dan password salah"
+// @Failure 403 {object} object{error=string,sso_required=bool} "Domain email wajib login lewat SSO atau akun tidak aktif"
+// @Failure 423 {object} object{error=string,locked_until=string} "Akun dikunci sementara"
+// @Failure 429 {object} object{error=string} "Terlalu banyak percobaan login"
 // @Failure 500 {object} object{error=string} "Error internal server"
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -101,45 +222,341 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	if errors := util.ValidateStruct(payload); errors != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
 	}
+	if h.passwordLoginDisabled(payload.Email) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":        "Login dengan password dinonaktifkan untuk domain email ini. Gunakan login SSO.",
+			"sso_required": true,
+		})
+	}
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
 
+	now := time.Now()
+	event := models.SecurityEvent{
+		Email:     payload.Email,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+
+	ipFailures, err := h.securityRepo.CountFailedLoginsByIP(ctx, event.IPAddress, now.Add(-loginIPWindow))
+	if err != nil {
+		log.Printf("ERROR: Gagal memeriksa login gagal per IP %s: %v", event.IPAddress, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error internal server"})
+	}
+	if ipFailures >= loginIPMaxFailures {
+		c.Set(fiber.HeaderRetryAfter, fmt.Sprintf("%d", int(loginIPWindow.Seconds())))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Terlalu banyak percobaan login gagal dari alamat ini. Coba lagi nanti."})
+	}
+
 	user, err := h.userRepo.FindUserByEmail(ctx, payload.Email)
-	if err != nil || user == nil {
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error internal server"})
+	}
+	if user == nil {
+		event.Type = models.SecurityEventLoginFailed
+		event.Reason = models.LoginFailureUnknownEmail
+		recordSecurityEvent(ctx, h.securityRepo, event)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Kombinasi email dan password salah"})
 	}
+	event.UserID = &user.ID
+
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		event.Type = models.SecurityEventLoginFailed
+		event.Reason = models.LoginFailureAccountLocked
+		recordSecurityEvent(ctx, h.securityRepo, event)
+		c.Set(fiber.HeaderRetryAfter, fmt.Sprintf("%d", int(user.LockedUntil.Sub(now).Seconds())+1))
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error":        "Akun dikunci sementara karena terlalu banyak percobaan login gagal.",
+			"locked_until": user.LockedUntil,
+		})
+	}
+
+	if delay := loginRetryDelay(user.FailedLoginCount); delay > 0 && user.LastFailedLoginAt != nil {
+		if retryAt := user.LastFailedLoginAt.Add(delay); now.Before(retryAt) {
+			c.Set(fiber.HeaderRetryAfter, fmt.Sprintf("%d", int(retryAt.Sub(now).Seconds())+1))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "Terlalu cepat. Tunggu beberapa saat sebelum mencoba login lagi."})
+		}
+	}
 
 	if !password.CheckPasswordHash(payload.Password, user.Password) {
+		event.Type = models.SecurityEventLoginFailed
+		event.Reason = models.LoginFailureInvalidPassword
+		recordSecurityEvent(ctx, h.securityRepo, event)
+
+		failedCount, err := h.userRepo.RegisterFailedLogin(ctx, user.ID)
+		if err != nil {
+			log.Printf("ERROR: Gagal mencatat login gagal user %s: %v", user.ID.Hex(), err)
+		} else if failedCount >= loginLockAfterFailures {
+			lockedUntil := now.Add(loginLockDuration)
+			if err := h.userRepo.LockUser(ctx, user.ID, lockedUntil); err != nil {
+				log.Printf("ERROR: Gagal mengunci akun user %s: %v", user.ID.Hex(), err)
+			} else {
+				recordSecurityEvent(ctx, h.securityRepo, models.SecurityEvent{
+					UserID:    &user.ID,
+					Type:      models.SecurityEventAccountLocked,
+					IPAddress: event.IPAddress,
+					UserAgent: event.UserAgent,
+					Detail:    fmt.Sprintf("Dikunci hingga %s setelah %d kali login gagal", lockedUntil.Format(time.RFC3339), failedCount),
+				})
+			}
+		}
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Kombinasi email dan password salah"})
 	}
 
-	pasetoMaker, err := paseto.NewPasetoMaker()
-	if err != nil || pasetoMaker == nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menginisialisasi token generator"})
+	// Diperiksa setelah password agar status akun tidak terbuka bagi yang tidak tahu passwordnya.
+	if user.EmploymentStatus == models.EmploymentStatusInactive {
+		event.Type = models.SecurityEventLoginFailed
+		event.Reason = models.LoginFailureAccountInactive
+		recordSecurityEvent(ctx, h.securityRepo, event)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": errAccountInactive})
 	}
 
-	token, err := pasetoMaker.GenerateToken(user)
+	// Dengan 2FA, token baru diberikan setelah kode valid. Hitungan login gagal belum direset
+	// agar kode 2FA tidak bisa ditebak berulang kali dengan password yang sudah diketahui.
+	if user.TwoFactorEnabled {
+		return h.startTwoFactorChallenge(ctx, c, user)
+	}
+
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		if err := h.userRepo.ResetLoginFailures(ctx, user.ID); err != nil {
+			log.Printf("ERROR: Gagal mereset hitungan login gagal user %s: %v", user.ID.Hex(), err)
+		}
+	}
+	event.Type = models.SecurityEventLoginSuccess
+	recordSecurityEvent(ctx, h.securityRepo, event)
+
+	return h.respondLoginSuccess(ctx, c, user)
+}
+
+// startSession mencatat sesi login baru untuk perangkat yang sedang melakukan request dan
+// mengembalikan session ID yang dibawa token.
+func (h *AuthHandler) startSession(ctx context.Context, c *fiber.Ctx, user *models.User) (string, error) {
+	sessionID, err := paseto.NewRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	session := &models.Session{
+		SessionID: sessionID,
+		UserID:    user.ID,
+		Device:    util.DescribeUserAgent(userAgent),
+		UserAgent: userAgent,
+		IPAddress: c.IP(),
+		ExpiresAt: time.Now().Add(paseto.RefreshTokenDuration),
+	}
+	if _, err := h.tokenRepo.CreateSession(ctx, session); err != nil {
+		return "", err
+	}
+	return sessionID, nil
+}
+
+// respondLoginSuccess membuka sesi baru dan mengirim token ke client.
+func (h *AuthHandler) respondLoginSuccess(ctx context.Context, c *fiber.Ctx, user *models.User) error {
+	sessionID, err := h.startSession(ctx, c, user)
 	if err != nil {
+		log.Printf("ERROR: Gagal membuat sesi untuk user %s: %v", user.ID.Hex(), err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Login berhasil",
-		"token":   token,
-		"user":    user,
+	tokens, err := h.issueTokenPair(ctx, c, user, sessionID)
+	if err != nil {
+		log.Printf("ERROR: Gagal membuat token untuk user %s: %v", user.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
+	}
+
+	response := fiber.Map{
+		"message":                  "Login berhasil",
+		"token":                    tokens.AccessToken,
+		"token_expires_at":         tokens.AccessExpiresAt,
+		"refresh_token":            tokens.RefreshToken,
+		"refresh_token_expires_at": tokens.RefreshExpiresAt,
+		"user":                     user,
+	}
+	if user.IsFirstLogin {
+		response["password_change_required"] = true
+	}
+	if h.passwordExpired(user) {
+		response["password_change_required"] = true
+		response["password_expired"] = true
+	}
+	if h.twoFactorRequiredForRole(user.Role) && !user.TwoFactorEnabled {
+		response["two_factor_setup_required"] = true
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// issueTokenPair membuat access token dan refresh token baru pada sesi sessionID.
+// Refresh token hanya disimpan dalam bentuk hash.
+func (h *AuthHandler) issueTokenPair(ctx context.Context, c *fiber.Ctx, user *models.User, sessionID string) (*models.TokenPair, error) {
+	refresh, err := h.newRefreshToken(c, user.ID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := h.tokenRepo.CreateRefreshToken(ctx, refresh.record); err != nil {
+		return nil, err
+	}
+	return h.completeTokenPair(user, sessionID, refresh)
+}
+
+type pendingRefreshToken struct {
+	token  string
+	record *models.RefreshToken
+}
+
+func (h *AuthHandler) newRefreshToken(c *fiber.Ctx, userID primitive.ObjectID, sessionID string) (*pendingRefreshToken, error) {
+	token, err := paseto.NewRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	return &pendingRefreshToken{
+		token: token,
+		record: &models.RefreshToken{
+			ID:        primitive.NewObjectID(),
+			UserID:    userID,
+			SessionID: sessionID,
+			TokenHash: paseto.HashToken(token),
+			ExpiresAt: time.Now().Add(paseto.RefreshTokenDuration),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
+			IPAddress: c.IP(),
+		},
+	}, nil
+}
+
+func (h *AuthHandler) completeTokenPair(user *models.User, sessionID string, refresh *pendingRefreshToken) (*models.TokenPair, error) {
+	accessToken, claims, err := h.pasetoMaker.GenerateToken(user, sessionID, paseto.TokenOptions{
+		TwoFactorSetupRequired: h.twoFactorRequiredForRole(user.Role) && !user.TwoFactorEnabled,
+		PasswordExpired:        h.passwordExpired(user),
 	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  claims.ExpiresAt,
+		RefreshToken:     refresh.token,
+		RefreshExpiresAt: refresh.record.ExpiresAt,
+	}, nil
+}
+
+// RefreshToken godoc
+// @Summary Refresh Access Token
+// @Description Menukar refresh token dengan access token dan refresh token baru (rotasi). Refresh token lama langsung tidak berlaku; jika token lama dipakai ulang, seluruh sesinya dicabut.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body models.RefreshTokenPayload true "Refresh token"
+// @Success 200 {object} models.TokenPair "Token baru berhasil dibuat"
+// @Failure 400 {object} models.ValidationErrorResponse "Payload tidak valid"
+// @Failure 401 {object} object{error=string} "Refresh token tidak valid, kedaluwarsa, atau sudah dicabut"
+// @Failure 500 {object} object{error=string} "Gagal membuat token"
+// @Router /auth/refresh [post]
+func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
+	var payload models.RefreshTokenPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body", "details": err.Error()})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	stored, err := h.tokenRepo.FindRefreshTokenByHash(ctx, paseto.HashToken(payload.RefreshToken))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa refresh token"})
+	}
+	if stored == nil || time.Now().After(stored.ExpiresAt) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token tidak valid atau telah kedaluwarsa"})
+	}
+	if stored.RevokedAt != nil {
+		// Token yang sudah dirotasi dipakai lagi: kemungkinan bocor, cabut seluruh sesi.
+		if stored.ReplacedBy != nil {
+			if _, err := h.tokenRepo.RevokeSession(ctx, stored.SessionID); err != nil {
+				log.Printf("ERROR: Gagal mencabut sesi %s setelah refresh token dipakai ulang: %v", stored.SessionID, err)
+			}
+			log.Printf("WARNING: Refresh token yang sudah dirotasi dipakai ulang untuk user %s, sesi %s dicabut", stored.UserID.Hex(), stored.SessionID)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token telah dicabut, silakan login kembali"})
+	}
+
+	user, err := h.userRepo.FindUserByID(ctx, stored.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa user"})
+	}
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token tidak valid atau telah kedaluwarsa"})
+	}
+	if user.EmploymentStatus == models.EmploymentStatusInactive {
+		if _, err := h.tokenRepo.RevokeSession(ctx, stored.SessionID); err != nil {
+			log.Printf("ERROR: Gagal mencabut sesi %s milik user tidak aktif %s: %v", stored.SessionID, user.ID.Hex(), err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": errAccountInactive})
+	}
+
+	session, err := h.tokenRepo.FindSession(ctx, stored.SessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa sesi"})
+	}
+	if session == nil {
+		// Sesi yang dibuka sebelum sesi disimpan di database: catat dari data refresh token.
+		session = &models.Session{
+			SessionID: stored.SessionID,
+			UserID:    stored.UserID,
+			Device:    util.DescribeUserAgent(stored.UserAgent),
+			UserAgent: stored.UserAgent,
+			IPAddress: stored.IPAddress,
+			ExpiresAt: stored.ExpiresAt,
+		}
+		if _, err := h.tokenRepo.CreateSession(ctx, session); err != nil {
+			log.Printf("ERROR: Gagal mencatat sesi lama %s untuk user %s: %v", stored.SessionID, user.ID.Hex(), err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
+		}
+	}
+	if !session.IsActive(time.Now()) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Sesi telah berakhir, silakan login kembali"})
+	}
+
+	next, err := h.newRefreshToken(c, user.ID, stored.SessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
+	}
+
+	// Tandai token lama terlebih dahulu agar dua permintaan refresh bersamaan tidak sama-sama berhasil.
+	result, err := h.tokenRepo.RotateRefreshToken(ctx, stored.ID, next.record.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
+	}
+	if result.ModifiedCount == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Refresh token telah dicabut, silakan login kembali"})
+	}
+	if _, err := h.tokenRepo.CreateRefreshToken(ctx, next.record); err != nil {
+		log.Printf("ERROR: Gagal menyimpan refresh token baru untuk user %s: %v", user.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
+	}
+
+	if err := h.tokenRepo.ExtendSession(ctx, stored.SessionID, c.IP(), next.record.ExpiresAt); err != nil {
+		log.Printf("ERROR: Gagal memperpanjang sesi %s untuk user %s: %v", stored.SessionID, user.ID.Hex(), err)
+	}
+
+	tokens, err := h.completeTokenPair(user, stored.SessionID, next)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat token"})
+	}
+	return c.Status(fiber.StatusOK).JSON(tokens)
 }
 
 // ChangePassword godoc
 // @Summary Change Password
-// @Description Mengubah password user yang sedang login (required authentication)
+// @Description Mengubah password user yang sedang login (required authentication). Semua sesi lama di perangkat mana pun dicabut dan sesi saat ini diganti dengan token baru, sehingga user yang baru pertama login langsung bisa memakai API lain tanpa login ulang.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param password body models.ChangePasswordPayload true "Data untuk mengubah password"
-// @Success 200 {object} object{message=string} "Password berhasil diubah"
+// @Success 200 {object} object{message=string,token=string,token_expires_at=string,refresh_token=string,refresh_token_expires_at=string} "Password berhasil diubah beserta token baru"
 // @Failure 400 {object} models.ValidationErrorResponse "Invalid request body atau validation error" // <-- Perbaikan di sini
+// @Failure 400 {object} object{error=string,violations=[]string} "Password baru tidak memenuhi kebijakan password atau pernah dipakai"
 // @Failure 401 {object} object{error=string} "Tidak terautentikasi atau password lama tidak cocok"
 // @Failure 500 {object} object{error=string} "User tidak ditemukan atau gagal update"
 // @Router /users/change-password [post]
@@ -162,7 +579,7 @@ func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
 	defer cancel()
 
 	user, err := h.userRepo.FindUserByID(ctx, claims.UserID)
-	if err != nil {
+	if err != nil || user == nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "User tidak ditemukan"})
 	}
 
@@ -174,46 +591,132 @@ func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
 	if payload.NewPassword == payload.OldPassword {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password baru tidak boleh sama dengan password lama."})
 	}
+	if violations := passwordPolicyViolations(h.passwordPolicy, user, payload.NewPassword); len(violations) > 0 {
+		return respondPasswordPolicyViolation(c, violations)
+	}
 
 	newHashedPassword, err := password.HashPassword(payload.NewPassword)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal hash password baru"})
 	}
 
-	updateData := bson.M{
-		"password":     newHashedPassword,
-		"isFirstLogin": false,
-		"updated_at":   time.Now(),
+	history := h.passwordPolicy.NextHistory(user.Password, user.PasswordHistory)
+	if err := h.userRepo.UpdateUserPassword(ctx, claims.UserID, newHashedPassword, history); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal update password: %v", err)})
+	}
+	recordAudit(c, models.AuditActionUserPasswordChange, models.AuditTargetUser, user.ID.Hex(), nil, nil)
+
+	// Semua sesi yang dibuka dengan password lama diakhiri, termasuk di perangkat lain. Sesi ini
+	// diganti dengan sesi baru (token lama juga masih membawa is_first_login/password_expired) agar
+	// user bisa langsung memakai API tanpa login ulang.
+	if _, err := revokeAllUserTokens(ctx, h.tokenRepo, user.ID); err != nil {
+		log.Printf("ERROR: Gagal mencabut sesi lama user %s setelah ganti password: %v", user.ID.Hex(), err)
 	}
 
-	_, err = h.userRepo.UpdateUser(ctx, claims.UserID, updateData)
+	now := time.Now()
+	user.Password = newHashedPassword
+	user.PasswordHistory = history
+	user.PasswordChangedAt = &now
+	user.IsFirstLogin = false
+	sessionID, err := h.startSession(ctx, c, user)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal update password: %v", err)})
+		log.Printf("ERROR: Gagal membuat sesi baru untuk user %s: %v", user.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Password berhasil diubah, tetapi gagal membuat token baru. Silakan login kembali."})
+	}
+	tokens, err := h.issueTokenPair(ctx, c, user, sessionID)
+	if err != nil {
+		log.Printf("ERROR: Gagal membuat token baru untuk user %s: %v", user.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Password berhasil diubah, tetapi gagal membuat token baru. Silakan login kembali."})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Password berhasil diubah."})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":                  "Password berhasil diubah.",
+		"token":                    tokens.AccessToken,
+		"token_expires_at":         tokens.AccessExpiresAt,
+		"refresh_token":            tokens.RefreshToken,
+		"refresh_token_expires_at": tokens.RefreshExpiresAt,
+	})
 }
 
 // Logout godoc
 // @Summary Logout User
-// @Description Melakukan logout user dengan menginformasikan client untuk menghapus token
+// @Description Mengakhiri sesi saat ini: access token yang dipakai dicabut dan refresh token sesi ini tidak bisa dipakai lagi
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} object{message=string} "Logout berhasil"
 // @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 500 {object} object{error=string} "Gagal logout"
 // @Router /auth/logout [post]
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
-	// Validasi bahwa user terautentikasi
-	_, ok := c.Locals("user").(*models.Claims)
+	claims, ok := c.Locals("user").(*models.Claims)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi"})
 	}
 
-	// Karena token stateless, tidak bisa dihapus dari server.
-	// Logout cukup informasikan agar frontend menghapus token.
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if claims.SessionID != "" {
+		if _, err := h.tokenRepo.RevokeSession(ctx, claims.SessionID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal logout"})
+		}
+	}
+	if claims.TokenID != "" {
+		if err := h.tokenRepo.RevokeAccessToken(ctx, claims); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal logout"})
+		}
+	}
+	recordAudit(c, models.AuditActionLogout, models.AuditTargetUser, claims.UserID.Hex(), nil, nil)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Logout berhasil. Silakan hapus token dari sisi client.",
+		"message": "Logout berhasil.",
 	})
 }
+
+// LogoutAll godoc
+// @Summary Logout From All Sessions
+// @Description Mengakhiri semua sesi user yang sedang login di semua perangkat
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} object{message=string,revoked_sessions=int} "Semua sesi berhasil diakhiri"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 500 {object} object{error=string} "Gagal logout dari semua sesi"
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	revoked, err := revokeAllUserTokens(ctx, h.tokenRepo, claims.UserID)
+	if err != nil {
+		log.Printf("ERROR: Gagal mencabut semua sesi user %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal logout dari semua sesi"})
+	}
+	recordAudit(c, models.AuditActionLogoutAll, models.AuditTargetUser, claims.UserID.Hex(), nil, nil)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":          "Berhasil logout dari semua sesi.",
+		"revoked_sessions": revoked,
+	})
+}
+
+// revokeAllUserTokens mencabut semua refresh token user dan menolak semua access token
+// yang sudah terbit. Mengembalikan jumlah sesi aktif yang dicabut.
+func revokeAllUserTokens(ctx context.Context, tokenRepo repository.TokenRepository, userID primitive.ObjectID) (int64, error) {
+	result, err := tokenRepo.RevokeAllSessionsForUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if err := tokenRepo.RevokeAccessTokensForUser(ctx, userID, time.Now()); err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
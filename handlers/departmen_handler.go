@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -66,6 +68,7 @@ func (h *DepartmentHandler) CreateDepartment(c *fiber.Ctx) error {
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal membuat departemen: %v", err)})
 	}
+	recordAudit(c, models.AuditActionDepartmentCreate, models.AuditTargetDepartment, newDept.ID.Hex(), nil, newDept)
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Departemen berhasil ditambahkan",
@@ -129,7 +132,7 @@ func (h *DepartmentHandler) GetDepartmentByID(c *fiber.Ctx) error {
 
 // UpdateDepartment godoc
 // @Summary Update Department
-// @Description Memperbarui departemen berdasarkan ID (admin only)
+// @Description Memperbarui departemen berdasarkan ID (admin only). Kirim "head_id": null untuk menghapus kepala departemen.
 // @Tags Admin
 // @Accept json
 // @Produce json
@@ -172,18 +175,45 @@ func (h *DepartmentHandler) UpdateDepartment(c *fiber.Ctx) error {
 		}
 		updateData["name"] = updatePayload.Name
 	}
+	// head_id: null menghapus kepala departemen; field yang tidak dikirim tidak diubah.
+	var unsetFields []string
+	var rawPayload map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &rawPayload); err == nil && string(rawPayload["head_id"]) == "null" {
+		unsetFields = append(unsetFields, "head_id")
+	} else if updatePayload.HeadID != nil {
+		updateData["head_id"] = updatePayload.HeadID
+	}
+	if updatePayload.MinStaffing != nil {
+		updateData["min_staffing"] = *updatePayload.MinStaffing
+	}
+	if updatePayload.StaffingPolicy != "" {
+		updateData["staffing_policy"] = updatePayload.StaffingPolicy
+	}
 
-	if len(updateData) == 0 {
+	if len(updateData) == 0 && len(unsetFields) == 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tidak ada data untuk diupdate"})
 	}
 
-	result, err := h.deptRepo.UpdateDepartment(ctx, objID, updateData)
+	before, err := h.deptRepo.GetDepartmentByID(ctx, objID)
+	if err != nil {
+		if err.Error() == "departemen tidak ditemukan" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Departemen tidak ditemukan"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mengambil departemen: %v", err)})
+	}
+
+	result, err := h.deptRepo.UpdateDepartment(ctx, objID, updateData, unsetFields)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mengupdate departemen: %v", err)})
 	}
 	if result.ModifiedCount == 0 {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Departemen tidak ditemukan atau tidak ada perubahan"})
 	}
+	after, err := h.deptRepo.GetDepartmentByID(ctx, objID)
+	if err != nil {
+		log.Printf("ERROR: Gagal membaca departemen %s setelah update untuk audit log: %v", objID.Hex(), err)
+	}
+	recordAudit(c, models.AuditActionDepartmentUpdate, models.AuditTargetDepartment, objID.Hex(), before, after)
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Departemen berhasil diupdate"})
 }
@@ -211,6 +241,14 @@ func (h *DepartmentHandler) DeleteDepartment(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
 
+	before, err := h.deptRepo.GetDepartmentByID(ctx, objID)
+	if err != nil {
+		if err.Error() == "departemen tidak ditemukan" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Departemen tidak ditemukan"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mengambil departemen: %v", err)})
+	}
+
 	result, err := h.deptRepo.DeleteDepartment(ctx, objID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal menghapus departemen: %v", err)})
@@ -218,6 +256,7 @@ func (h *DepartmentHandler) DeleteDepartment(c *fiber.Ctx) error {
 	if result.DeletedCount == 0 {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Departemen tidak ditemukan"})
 	}
+	recordAudit(c, models.AuditActionDepartmentDelete, models.AuditTargetDepartment, objID.Hex(), before, nil)
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Departemen berhasil dihapus"})
 }
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/notifier"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// LeaveEscalationJob adalah cron job untuk pengajuan cuti yang terlalu lama menunggu persetujuan:
+// mengingatkan approver, lalu (sesuai kebijakan) mengalihkan langkah ke admin atau menyetujuinya
+// secara otomatis. Setiap tindakan dicatat di LeaveRequest.AutoActions.
+type LeaveEscalationJob struct {
+	leave    *LeaveRequestHandler
+	notifier notifier.Notifier
+	policy   config.LeaveEscalationConfig
+}
+
+func NewLeaveEscalationJob(leaveHandler *LeaveRequestHandler, n notifier.Notifier, policy config.LeaveEscalationConfig) *LeaveEscalationJob {
+	return &LeaveEscalationJob{
+		leave:    leaveHandler,
+		notifier: n,
+		policy:   policy,
+	}
+}
+
+// Run memproses semua pengajuan pending. Kegagalan pada satu pengajuan hanya dicatat di log
+// agar pengajuan lain tetap diproses.
+func (j *LeaveEscalationJob) Run(ctx context.Context) error {
+	minDays := j.policy.ReminderAfterDays
+	if j.policy.Action != config.LeaveEscalationActionNone && j.policy.ActionAfterDays > 0 &&
+		(minDays == 0 || j.policy.ActionAfterDays < minDays) {
+		minDays = j.policy.ActionAfterDays
+	}
+	if minDays == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	requests, err := j.leave.leaveRepo.FindPendingCreatedBefore(ctx, now.AddDate(0, 0, -minDays))
+	if err != nil {
+		return err
+	}
+
+	for i := range requests {
+		if err := j.process(ctx, &requests[i], now); err != nil {
+			log.Printf("ERROR: Gagal memproses pengajuan pending %s: %v", requests[i].ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+func (j *LeaveEscalationJob) process(ctx context.Context, request *models.LeaveRequest, now time.Time) error {
+	// Pengajuan lama belum punya rantai persetujuan: perlakukan sebagai satu langkah oleh admin.
+	approvals := request.Approvals
+	stepIndex := request.CurrentStep
+	if len(approvals) == 0 {
+		approvals = models.NewApprovalSteps([]models.ApprovalStepDefinition{models.AdminApprovalStep("Persetujuan Admin")})
+		stepIndex = 0
+	}
+	if stepIndex < 0 || stepIndex >= len(approvals) {
+		return fmt.Errorf("rantai persetujuan tidak valid (current_step %d)", stepIndex)
+	}
+	step := approvals[stepIndex]
+
+	pendingSince := request.PendingSince()
+	pendingDays := int(now.Sub(pendingSince).Hours() / 24)
+
+	if j.policy.Action != config.LeaveEscalationActionNone && j.policy.ActionAfterDays > 0 && pendingDays >= j.policy.ActionAfterDays {
+		switch j.policy.Action {
+		case config.LeaveEscalationActionEscalate:
+			// Langkah yang sudah ditangani admin tidak bisa dieskalasi lagi; cukup diingatkan.
+			if !step.IsAdminStep() {
+				return j.escalate(ctx, request, stepIndex, step, pendingDays, now)
+			}
+		case config.LeaveEscalationActionAutoApprove:
+			handled, err := j.autoApprove(ctx, request, approvals, stepIndex, pendingSince, pendingDays, now)
+			if handled || err != nil {
+				return err
+			}
+		}
+	}
+
+	if j.policy.ReminderAfterDays > 0 && pendingDays >= j.policy.ReminderAfterDays {
+		return j.remind(ctx, request, step, pendingSince, pendingDays, now)
+	}
+	return nil
+}
+
+// remind mengirim pengingat ke approver langkah aktif, paling banyak sekali per hari per langkah.
+func (j *LeaveEscalationJob) remind(ctx context.Context, request *models.LeaveRequest, step models.ApprovalStep, pendingSince time.Time, pendingDays int, now time.Time) error {
+	if last := request.LastAutoAction(models.LeaveAutoActionReminder, step.Level, pendingSince); last != nil && now.Sub(last.PerformedAt) < 23*time.Hour {
+		return nil
+	}
+
+	recipients, err := j.approverEmails(ctx, request, step)
+	if err != nil {
+		return err
+	}
+	if len(recipients) > 0 {
+		err = j.notifier.Send(ctx, notifier.Message{
+			To:      recipients,
+			Subject: "Pengingat: pengajuan menunggu persetujuan Anda",
+			Body: fmt.Sprintf("Pengajuan %s tanggal %s s/d %s sudah menunggu %d hari pada langkah '%s'.",
+				request.RequestType, request.StartDate, request.EndDate, pendingDays, step.Name),
+		})
+		if err != nil {
+			return fmt.Errorf("gagal mengirim pengingat: %w", err)
+		}
+	}
+
+	_, err = j.leave.leaveRepo.AppendAutoAction(ctx, request.ID, models.LeaveAutoAction{
+		Action:      models.LeaveAutoActionReminder,
+		StepLevel:   step.Level,
+		StepName:    step.Name,
+		PendingDays: pendingDays,
+		Recipients:  recipients,
+		PerformedAt: now,
+	})
+	return err
+}
+
+// escalate mengalihkan langkah aktif ke langkah pengganti admin dan memberi tahu semua pemegang
+// izin leave.approve.
+func (j *LeaveEscalationJob) escalate(ctx context.Context, request *models.LeaveRequest, stepIndex int, step models.ApprovalStep, pendingDays int, now time.Time) error {
+	detail := fmt.Sprintf("Langkah dialihkan dari approver %s", step.ApproverType)
+	if step.ApproverID != nil {
+		detail = fmt.Sprintf("%s (%s)", detail, step.ApproverID.Hex())
+	} else if step.ApproverRole != "" {
+		detail = fmt.Sprintf("%s (%s)", detail, step.ApproverRole)
+	}
+	detail += " ke admin"
+
+	fallback := models.AdminApprovalStep(step.Name)
+	escalated := step
+	escalated.ApproverType = fallback.ApproverType
+	escalated.ApproverRole = fallback.ApproverRole
+	escalated.ApproverID = nil
+
+	recipients, err := j.approverEmails(ctx, request, escalated)
+	if err != nil {
+		return err
+	}
+
+	action := models.LeaveAutoAction{
+		Action:      models.LeaveAutoActionEscalate,
+		StepLevel:   step.Level,
+		StepName:    step.Name,
+		PendingDays: pendingDays,
+		Recipients:  recipients,
+		Detail:      detail,
+		PerformedAt: now,
+	}
+	result, err := j.leave.leaveRepo.EscalateApprovalStep(ctx, request.ID, stepIndex, escalated, action)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		// Sudah diputuskan approver sejak data diambil.
+		return nil
+	}
+
+	if len(recipients) > 0 {
+		err = j.notifier.Send(ctx, notifier.Message{
+			To:      recipients,
+			Subject: "Eskalasi: pengajuan menunggu persetujuan admin",
+			Body: fmt.Sprintf("Pengajuan %s tanggal %s s/d %s tidak diputuskan selama %d hari pada langkah '%s' dan dialihkan ke admin.",
+				request.RequestType, request.StartDate, request.EndDate, pendingDays, step.Name),
+		})
+		if err != nil {
+			log.Printf("WARNING: Gagal mengirim notifikasi eskalasi pengajuan %s: %v", request.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// autoApprove menyetujui langkah aktif atas nama sistem, mewakili approver langkah tersebut. Langkah
+// tidak disetujui (dicatat sekali per langkah) dan handled bernilai false agar pengingat tetap dikirim jika:
+//   - tidak ada approver yang berwenang selain pemohon sendiri, karena persetujuan otomatis tidak boleh
+//     menjadi jalan bagi pemohon untuk menyetujui pengajuannya sendiri;
+//   - departemen memakai kebijakan block dan persetujuan membuat staf di bawah batas minimum.
+func (j *LeaveEscalationJob) autoApprove(ctx context.Context, request *models.LeaveRequest, approvals []models.ApprovalStep, stepIndex int, pendingSince time.Time, pendingDays int, now time.Time) (bool, error) {
+	step := approvals[stepIndex]
+
+	approvers, err := j.approvers(ctx, request, step)
+	if err != nil {
+		return false, fmt.Errorf("gagal menentukan approver langkah: %w", err)
+	}
+	if len(approvers) == 0 {
+		return false, j.recordAutoApproveBlocked(ctx, request, step, pendingSince, pendingDays, now,
+			"Persetujuan otomatis dibatalkan: tidak ada approver yang berwenang selain pemohon")
+	}
+
+	conflicts, policy, err := j.leave.checkStaffing(ctx, request)
+	if err != nil {
+		return false, fmt.Errorf("gagal memeriksa batas minimum staf: %w", err)
+	}
+	if len(conflicts) > 0 && policy == models.StaffingPolicyBlock {
+		return false, j.recordAutoApproveBlocked(ctx, request, step, pendingSince, pendingDays, now,
+			fmt.Sprintf("Persetujuan otomatis dibatalkan: %d tanggal di bawah batas minimum staf", len(conflicts)))
+	}
+
+	note := fmt.Sprintf("Disetujui otomatis oleh sistem setelah menunggu %d hari", pendingDays)
+	decidedAt := now
+	step.Decision = models.ApprovalDecisionApproved
+	step.Note = note
+	step.DecidedAt = &decidedAt
+	approvals[stepIndex] = step
+
+	newStatus := "approved"
+	if stepIndex < len(approvals)-1 {
+		newStatus = "pending"
+	}
+
+	err = config.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		updateResult, err := j.leave.leaveRepo.RecordApprovalDecision(sessCtx, request.ID, stepIndex, approvals, newStatus, note)
+		if err != nil {
+			return err
+		}
+		if updateResult.MatchedCount == 0 {
+			return errLeaveRequestChanged
+		}
+		_, err = j.leave.leaveRepo.AppendAutoAction(sessCtx, request.ID, models.LeaveAutoAction{
+			Action:      models.LeaveAutoActionAutoApprove,
+			StepLevel:   step.Level,
+			StepName:    step.Name,
+			PendingDays: pendingDays,
+			Detail:      note,
+			PerformedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+		if newStatus == "pending" {
+			return nil
+		}
+		return j.leave.attendanceRepo.ApplyLeaveDecision(sessCtx, request, newStatus, note)
+	})
+	if errors.Is(err, errLeaveRequestChanged) {
+		return true, nil
+	}
+	if err != nil {
+		return true, err
+	}
+
+	if newStatus == "approved" {
+		j.leave.recordLeaveActivity(ctx, models.ActivityLeaveApproved, request, nil, "Pengajuan "+leaveActivitySubject(request)+" disetujui otomatis oleh sistem")
+
+		requester, err := j.leave.userRepo.FindUserByID(ctx, request.UserID)
+		if err == nil && requester != nil {
+			err = j.notifier.Send(ctx, notifier.Message{
+				To:      []string{requester.Email},
+				Subject: "Pengajuan Anda disetujui otomatis",
+				Body:    fmt.Sprintf("Pengajuan %s tanggal %s s/d %s disetujui otomatis oleh sistem.", request.RequestType, request.StartDate, request.EndDate),
+			})
+		}
+		if err != nil {
+			log.Printf("WARNING: Gagal mengirim notifikasi persetujuan otomatis pengajuan %s: %v", request.ID.Hex(), err)
+		}
+	}
+	return true, nil
+}
+
+// recordAutoApproveBlocked mencatat bahwa persetujuan otomatis dibatalkan, paling banyak sekali per langkah.
+func (j *LeaveEscalationJob) recordAutoApproveBlocked(ctx context.Context, request *models.LeaveRequest, step models.ApprovalStep, pendingSince time.Time, pendingDays int, now time.Time, detail string) error {
+	if request.LastAutoAction(models.LeaveAutoActionAutoApproveBlocked, step.Level, pendingSince) != nil {
+		return nil
+	}
+	_, err := j.leave.leaveRepo.AppendAutoAction(ctx, request.ID, models.LeaveAutoAction{
+		Action:      models.LeaveAutoActionAutoApproveBlocked,
+		StepLevel:   step.Level,
+		StepName:    step.Name,
+		PendingDays: pendingDays,
+		Detail:      detail,
+		PerformedAt: now,
+	})
+	return err
+}
+
+// approvers mengembalikan user yang dituju sebuah langkah: user yang ditunjuk langsung, anggota role
+// yang dituju, atau untuk langkah pengganti admin, semua user yang role-nya memiliki izin leave.approve.
+// Pemohon sendiri dan karyawan nonaktif tidak pernah termasuk.
+func (j *LeaveEscalationJob) approvers(ctx context.Context, request *models.LeaveRequest, step models.ApprovalStep) ([]models.User, error) {
+	var candidates []models.User
+	switch {
+	case step.ApproverType == models.ApproverTypeRole:
+		roleNames := []string{step.ApproverRole}
+		if step.IsAdminStep() {
+			roles, err := j.leave.roleRepo.FindAll(ctx)
+			if err != nil {
+				return nil, err
+			}
+			roleNames = roleNames[:0]
+			for i := range roles {
+				if roles[i].HasPermission(models.PermissionLeaveApprove) {
+					roleNames = append(roleNames, roles[i].Name)
+				}
+			}
+			if len(roleNames) == 0 {
+				return []models.User{}, nil
+			}
+		}
+		users, err := j.leave.userRepo.FindUsersByRoles(ctx, roleNames)
+		if err != nil {
+			return nil, err
+		}
+		candidates = users
+	case step.ApproverID != nil:
+		approver, err := j.leave.userRepo.FindUserByID(ctx, *step.ApproverID)
+		if err != nil {
+			return nil, err
+		}
+		if approver != nil {
+			candidates = []models.User{*approver}
+		}
+	}
+
+	approvers := make([]models.User, 0, len(candidates))
+	for _, user := range candidates {
+		if user.ID == request.UserID || user.EmploymentStatus == models.EmploymentStatusInactive {
+			continue
+		}
+		approvers = append(approvers, user)
+	}
+	return approvers, nil
+}
+
+// approverEmails mengembalikan email approver sebuah langkah (lihat approvers).
+func (j *LeaveEscalationJob) approverEmails(ctx context.Context, request *models.LeaveRequest, step models.ApprovalStep) ([]string, error) {
+	approvers, err := j.approvers(ctx, request, step)
+	if err != nil {
+		return nil, err
+	}
+	emails := make([]string, 0, len(approvers))
+	for _, user := range approvers {
+		emails = append(emails, user.Email)
+	}
+	return emails, nil
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -10,28 +12,58 @@ import (
 
 	"Sistem-Manajemen-Karyawan/config"
 	"Sistem-Manajemen-Karyawan/models"
+	util "Sistem-Manajemen-Karyawan/pkg/utils"
 	"Sistem-Manajemen-Karyawan/repository"
 
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// errLeaveRequestChanged dipakai di dalam transaksi untuk membatalkan perubahan ketika
+// pengajuan sudah diubah oleh proses lain sejak dibaca.
+var errLeaveRequestChanged = errors.New("pengajuan sudah berubah")
+
 type LeaveRequestHandler struct {
 	leaveRepo      repository.LeaveRequestRepository
 	attendanceRepo repository.AttendanceRepository
+	userRepo       *repository.UserRepository
+	deptRepo       repository.DepartmentRepository
+	chainRepo      repository.ApprovalChainRepository
+	delegationRepo repository.ApprovalDelegationRepository
+	activityRepo   repository.ActivityRepository
+	roleRepo       repository.RoleRepository
+	scheduleRepo   *repository.WorkScheduleRepository
 }
 
-func NewLeaveRequestHandler(leaveRepo repository.LeaveRequestRepository, attendanceRepo repository.AttendanceRepository) *LeaveRequestHandler {
+func NewLeaveRequestHandler(
+	leaveRepo repository.LeaveRequestRepository,
+	attendanceRepo repository.AttendanceRepository,
+	userRepo *repository.UserRepository,
+	deptRepo repository.DepartmentRepository,
+	chainRepo repository.ApprovalChainRepository,
+	delegationRepo repository.ApprovalDelegationRepository,
+	activityRepo repository.ActivityRepository,
+	roleRepo repository.RoleRepository,
+	scheduleRepo *repository.WorkScheduleRepository,
+) *LeaveRequestHandler {
 	return &LeaveRequestHandler{
 		leaveRepo:      leaveRepo,
 		attendanceRepo: attendanceRepo,
+		userRepo:       userRepo,
+		deptRepo:       deptRepo,
+		chainRepo:      chainRepo,
+		delegationRepo: delegationRepo,
+		activityRepo:   activityRepo,
+		roleRepo:       roleRepo,
+		scheduleRepo:   scheduleRepo,
 	}
 }
 
 // CreateLeaveRequest godoc
 // @Summary Create Leave Request
-// @Description Membuat pengajuan cuti atau sakit baru. Untuk 'Cuti', hanya bisa satu tanggal per pengajuan dan dibatasi 12 kali setahun. Untuk 'Sakit', bisa rentang tanggal.
+// @Description Membuat pengajuan cuti atau sakit baru. Untuk 'Cuti', hanya bisa satu tanggal per pengajuan dan dibatasi saldo 12 hari setahun. Untuk 'Sakit', bisa rentang tanggal. Pengajuan bisa penuh, setengah hari (AM/PM), atau per jam; setengah hari dan per jam hanya untuk satu tanggal, membutuhkan jadwal kerja pada tanggal tersebut, dan dipotong dari saldo secara pecahan (per jam dihitung terhadap lama jam kerja di jadwal). Cuti AM dan PM boleh diajukan terpisah pada tanggal yang sama.
 // @Tags Leave Request
 // @Accept multipart/form-data
 // @Produce json
@@ -40,6 +72,10 @@ func NewLeaveRequestHandler(leaveRepo repository.LeaveRequestRepository, attenda
 // @Param start_date formData string true "Tanggal Mulai (YYYY-MM-DD)"
 // @Param end_date formData string true "Tanggal Selesai (YYYY-MM-DD)"
 // @Param reason formData string true "Alasan Pengajuan"
+// @Param duration formData string false "Durasi (default: full_day)" Enums(full_day, half_day, hourly)
+// @Param half_day_period formData string false "Periode setengah hari (wajib untuk half_day)" Enums(AM, PM)
+// @Param start_time formData string false "Jam mulai HH:MM (wajib untuk hourly)"
+// @Param end_time formData string false "Jam selesai HH:MM (wajib untuk hourly)"
 // @Param attachment formData file false "Lampiran (Wajib untuk Sakit, maks 2MB)"
 // @Success 201 {object} object{message=string, request=models.LeaveRequest} "Pengajuan berhasil dikirim"
 // @Failure 400 {object} object{error=string} "Input tidak valid"
@@ -52,163 +88,668 @@ func (h *LeaveRequestHandler) CreateLeaveRequest(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Klaim token tidak valid atau sesi rusak"})
 	}
 
-	requestType := c.FormValue("request_type")
-	startDateStr := c.FormValue("start_date")
-	endDateStr := c.FormValue("end_date")
-	reason := c.FormValue("reason")
+	input, err := h.parseLeaveForm(c, claims.UserID, nil)
+	if err != nil {
+		return leaveFormErrorResponse(c, err)
+	}
+
+	// --- Logika Lampiran (wajib untuk Sakit) ---
+	attachmentURL, err := uploadLeaveAttachment(c)
+	if err != nil {
+		return leaveFormErrorResponse(c, err)
+	}
+	if attachmentURL == "" && input.RequestType == "Sakit" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Lampiran wajib untuk pengajuan Sakit"})
+	}
+
+	// --- Tentukan Rantai Persetujuan ---
+	chainID, approvals, err := h.buildApprovals(c.Context(), claims.UserID, input.RequestType)
+	if err != nil {
+		return leaveFormErrorResponse(c, err)
+	}
+
+	// --- Buat dan Simpan Pengajuan ---
+	newRequest := &models.LeaveRequest{
+		ID:              primitive.NewObjectID(),
+		UserID:          claims.UserID,
+		StartDate:       input.StartDate,
+		EndDate:         input.EndDate, // EndDate tetap disimpan, bahkan jika sama dengan StartDate untuk Cuti
+		Reason:          input.Reason,
+		Status:          "pending",
+		RequestType:     input.RequestType,
+		AttachmentURL:   attachmentURL,
+		Duration:        input.Duration,
+		HalfDayPeriod:   input.HalfDayPeriod,
+		StartTime:       input.StartTime,
+		EndTime:         input.EndTime,
+		LeaveDays:       input.LeaveDays,
+		ApprovalChainID: chainID,
+		Approvals:       approvals,
+		CurrentStep:     0,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	_, createErr := h.leaveRepo.Create(c.Context(), newRequest)
+	if createErr != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan data pengajuan"})
+	}
+
+	recordAudit(c, models.AuditActionLeaveCreate, models.AuditTargetLeaveRequest, newRequest.ID.Hex(), nil, newRequest)
+	h.recordLeaveActivity(c.Context(), models.ActivityLeaveSubmitted, newRequest, &claims.UserID, "Mengajukan "+leaveActivitySubject(newRequest))
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Pengajuan berhasil dikirim", "request": newRequest})
+}
+
+// UpdateLeaveRequest godoc
+// @Summary Edit Pending Leave Request
+// @Description Mengubah pengajuan milik sendiri yang masih pending (tanggal, alasan, durasi, lampiran). Field yang tidak dikirim tidak berubah; jenis pengajuan tidak dapat diubah. Isi sebelumnya disimpan di riwayat revisi dan rantai persetujuan dimulai ulang dari langkah pertama.
+// @Tags Leave Request
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Leave Request ID"
+// @Param start_date formData string false "Tanggal Mulai (YYYY-MM-DD)"
+// @Param end_date formData string false "Tanggal Selesai (YYYY-MM-DD)"
+// @Param reason formData string false "Alasan Pengajuan"
+// @Param duration formData string false "Durasi" Enums(full_day, half_day, hourly)
+// @Param half_day_period formData string false "Periode setengah hari" Enums(AM, PM)
+// @Param start_time formData string false "Jam mulai HH:MM (hourly)"
+// @Param end_time formData string false "Jam selesai HH:MM (hourly)"
+// @Param attachment formData file false "Lampiran pengganti (maks 2MB)"
+// @Success 200 {object} object{message=string,request=models.LeaveRequest} "Pengajuan berhasil diubah"
+// @Failure 400 {object} object{error=string} "Input tidak valid atau tidak ada perubahan"
+// @Failure 403 {object} object{error=string} "Bukan pemilik pengajuan atau saldo tidak mencukupi"
+// @Failure 404 {object} object{error=string} "Pengajuan tidak ditemukan"
+// @Failure 409 {object} object{error=string} "Pengajuan tidak lagi pending"
+// @Failure 500 {object} object{error=string} "Gagal menyimpan perubahan"
+// @Router /leave-requests/{id} [put]
+func (h *LeaveRequestHandler) UpdateLeaveRequest(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Klaim token tidak valid atau sesi rusak"})
+	}
+
+	reqID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+
+	original, err := h.leaveRepo.FindByID(reqID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mencari pengajuan: %v", err)})
+	}
+	if original == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pengajuan tidak ditemukan"})
+	}
+	if original.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda hanya dapat mengubah pengajuan milik sendiri"})
+	}
+	if original.Status != "pending" || original.WithdrawalRequested {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("Hanya pengajuan berstatus pending yang dapat diubah (status saat ini: '%s').", original.Status),
+		})
+	}
+
+	input, err := h.parseLeaveForm(c, claims.UserID, original)
+	if err != nil {
+		return leaveFormErrorResponse(c, err)
+	}
+	attachmentURL, err := uploadLeaveAttachment(c)
+	if err != nil {
+		return leaveFormErrorResponse(c, err)
+	}
+	if attachmentURL == "" {
+		attachmentURL = original.AttachmentURL
+	}
+
+	updated := *original
+	updated.StartDate = input.StartDate
+	updated.EndDate = input.EndDate
+	updated.Reason = input.Reason
+	updated.Duration = input.Duration
+	updated.HalfDayPeriod = input.HalfDayPeriod
+	updated.StartTime = input.StartTime
+	updated.EndTime = input.EndTime
+	updated.LeaveDays = input.LeaveDays
+	updated.AttachmentURL = attachmentURL
+
+	revision := models.NewLeaveRequestRevision(models.LeaveRevisionEdit, original, &updated, claims.UserID)
+	if len(revision.ChangedFields) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tidak ada perubahan pada pengajuan"})
+	}
+
+	// Isi pengajuan berubah, jadi keputusan yang sudah ada tidak berlaku lagi: mulai ulang rantai persetujuan.
+	chainID, approvals, err := h.buildApprovals(c.Context(), claims.UserID, original.RequestType)
+	if err != nil {
+		return leaveFormErrorResponse(c, err)
+	}
+	updated.ApprovalChainID = chainID
+	updated.Approvals = approvals
+	updated.CurrentStep = 0
+	updated.Revisions = append(updated.Revisions, revision)
+
+	updateData := bson.M{
+		"start_date":        updated.StartDate,
+		"end_date":          updated.EndDate,
+		"reason":            updated.Reason,
+		"duration":          updated.Duration,
+		"half_day_period":   updated.HalfDayPeriod,
+		"start_time":        updated.StartTime,
+		"end_time":          updated.EndTime,
+		"leave_days":        updated.LeaveDays,
+		"attachment_url":    updated.AttachmentURL,
+		"approval_chain_id": updated.ApprovalChainID,
+		"approvals":         updated.Approvals,
+		"current_step":      0,
+	}
+	result, err := h.leaveRepo.UpdatePendingRequest(c.Context(), reqID, updateData, revision)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan perubahan pengajuan"})
+	}
+	if result.MatchedCount == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Pengajuan sudah diproses, silakan muat ulang data."})
+	}
+
+	recordAudit(c, models.AuditActionLeaveUpdate, models.AuditTargetLeaveRequest, reqID.Hex(), original, updated)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Pengajuan berhasil diubah", "request": updated})
+}
+
+// ResubmitLeaveRequest godoc
+// @Summary Resubmit Rejected Leave Request
+// @Description Mengajukan ulang pengajuan milik sendiri yang ditolak. Field yang tidak dikirim disalin dari pengajuan asal. Pengajuan baru ditautkan ke pengajuan asal (resubmitted_from / resubmitted_as) dan membawa riwayat revisinya.
+// @Tags Leave Request
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "ID pengajuan yang ditolak"
+// @Param start_date formData string false "Tanggal Mulai (YYYY-MM-DD)"
+// @Param end_date formData string false "Tanggal Selesai (YYYY-MM-DD)"
+// @Param reason formData string false "Alasan Pengajuan"
+// @Param duration formData string false "Durasi" Enums(full_day, half_day, hourly)
+// @Param half_day_period formData string false "Periode setengah hari" Enums(AM, PM)
+// @Param start_time formData string false "Jam mulai HH:MM (hourly)"
+// @Param end_time formData string false "Jam selesai HH:MM (hourly)"
+// @Param attachment formData file false "Lampiran pengganti (maks 2MB)"
+// @Success 201 {object} object{message=string,request=models.LeaveRequest} "Pengajuan ulang berhasil dikirim"
+// @Failure 400 {object} object{error=string} "Input tidak valid"
+// @Failure 403 {object} object{error=string} "Bukan pemilik pengajuan atau saldo tidak mencukupi"
+// @Failure 404 {object} object{error=string} "Pengajuan tidak ditemukan"
+// @Failure 409 {object} object{error=string} "Pengajuan tidak ditolak atau sudah diajukan ulang"
+// @Failure 500 {object} object{error=string} "Gagal menyimpan pengajuan ulang"
+// @Router /leave-requests/{id}/resubmit [post]
+func (h *LeaveRequestHandler) ResubmitLeaveRequest(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Klaim token tidak valid atau sesi rusak"})
+	}
+
+	reqID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
 
-	if requestType == "" || startDateStr == "" || endDateStr == "" || reason == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Jenis pengajuan, tanggal mulai, tanggal selesai, dan alasan wajib diisi."})
+	original, err := h.leaveRepo.FindByID(reqID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mencari pengajuan: %v", err)})
+	}
+	if original == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pengajuan tidak ditemukan"})
+	}
+	if original.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda hanya dapat mengajukan ulang pengajuan milik sendiri"})
+	}
+	if original.Status != "rejected" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Hanya pengajuan yang ditolak yang dapat diajukan ulang."})
+	}
+	if original.ResubmittedAs != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Pengajuan ini sudah pernah diajukan ulang."})
 	}
 
-	if !strings.EqualFold(requestType, "Cuti") && !strings.EqualFold(requestType, "Sakit") {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Jenis pengajuan tidak valid. Hanya 'Cuti' atau 'Sakit' yang diterima."})
+	input, err := h.parseLeaveForm(c, claims.UserID, original)
+	if err != nil {
+		return leaveFormErrorResponse(c, err)
+	}
+	attachmentURL, err := uploadLeaveAttachment(c)
+	if err != nil {
+		return leaveFormErrorResponse(c, err)
+	}
+	if attachmentURL == "" {
+		attachmentURL = original.AttachmentURL
+	}
+	if attachmentURL == "" && input.RequestType == "Sakit" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Lampiran wajib untuk pengajuan Sakit"})
 	}
 
-	parsedStartDate, err := time.Parse("2006-01-02", startDateStr)
+	chainID, approvals, err := h.buildApprovals(c.Context(), claims.UserID, input.RequestType)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format tanggal mulai tidak valid. Gunakan YYYY-MM-DD."})
+		return leaveFormErrorResponse(c, err)
+	}
+
+	newRequest := &models.LeaveRequest{
+		ID:              primitive.NewObjectID(),
+		UserID:          claims.UserID,
+		StartDate:       input.StartDate,
+		EndDate:         input.EndDate,
+		Reason:          input.Reason,
+		Status:          "pending",
+		RequestType:     input.RequestType,
+		AttachmentURL:   attachmentURL,
+		Duration:        input.Duration,
+		HalfDayPeriod:   input.HalfDayPeriod,
+		StartTime:       input.StartTime,
+		EndTime:         input.EndTime,
+		LeaveDays:       input.LeaveDays,
+		ApprovalChainID: chainID,
+		Approvals:       approvals,
+		CurrentStep:     0,
+		ResubmittedFrom: &original.ID,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	revision := models.NewLeaveRequestRevision(models.LeaveRevisionResubmit, original, newRequest, claims.UserID)
+	newRequest.Revisions = append(append([]models.LeaveRequestRevision{}, original.Revisions...), revision)
+
+	err = config.WithTransaction(c.Context(), func(sessCtx mongo.SessionContext) error {
+		if _, err := h.leaveRepo.Create(sessCtx, newRequest); err != nil {
+			return err
+		}
+		result, err := h.leaveRepo.MarkResubmitted(sessCtx, original.ID, newRequest.ID)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return errLeaveRequestChanged
+		}
+		return nil
+	})
+	if errors.Is(err, errLeaveRequestChanged) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Pengajuan ini sudah pernah diajukan ulang."})
+	}
+	if err != nil {
+		log.Printf("ERROR: Gagal mengajukan ulang pengajuan %s: %v", original.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan pengajuan ulang"})
+	}
+
+	recordAudit(c, models.AuditActionLeaveResubmit, models.AuditTargetLeaveRequest, newRequest.ID.Hex(), nil, newRequest)
+	h.recordLeaveActivity(c.Context(), models.ActivityLeaveSubmitted, newRequest, &claims.UserID, "Mengajukan ulang "+leaveActivitySubject(newRequest))
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Pengajuan ulang berhasil dikirim", "request": newRequest})
+}
+
+// leaveFormInput adalah hasil validasi form pengajuan (create, edit, atau resubmit).
+type leaveFormInput struct {
+	RequestType   string
+	StartDate     string
+	EndDate       string
+	Reason        string
+	Duration      string
+	HalfDayPeriod string
+	StartTime     string
+	EndTime       string
+	LeaveDays     float64
+}
+
+// leaveFormErrorResponse menulis *fiber.Error dari helper form sebagai respons JSON {"error": ...}.
+func leaveFormErrorResponse(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
 	}
-	parsedEndDate, err := time.Parse("2006-01-02", endDateStr)
+	log.Printf("ERROR: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Kesalahan server internal"})
+}
+
+// parseLeaveForm memvalidasi form pengajuan. Jika base tidak nil (edit/resubmit), field yang tidak
+// dikirim diambil dari base, jenis pengajuan tidak dapat diubah, dan base sendiri dikecualikan
+// dari cek duplikasi serta saldo cuti.
+func (h *LeaveRequestHandler) parseLeaveForm(c *fiber.Ctx, userID primitive.ObjectID, base *models.LeaveRequest) (*leaveFormInput, error) {
+	input := &leaveFormInput{Duration: models.LeaveDurationFullDay}
+	var excludeID primitive.ObjectID
+	if base != nil {
+		excludeID = base.ID
+		input.RequestType = base.RequestType
+		input.StartDate = base.StartDate
+		input.EndDate = base.EndDate
+		input.Reason = base.Reason
+		if base.Duration != "" {
+			input.Duration = base.Duration
+		}
+		input.HalfDayPeriod = base.HalfDayPeriod
+		input.StartTime = base.StartTime
+		input.EndTime = base.EndTime
+	} else {
+		input.RequestType = c.FormValue("request_type")
+	}
+	input.StartDate = c.FormValue("start_date", input.StartDate)
+	input.EndDate = c.FormValue("end_date", input.EndDate)
+	input.Reason = c.FormValue("reason", input.Reason)
+
+	if input.RequestType == "" || input.StartDate == "" || input.EndDate == "" || input.Reason == "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Jenis pengajuan, tanggal mulai, tanggal selesai, dan alasan wajib diisi.")
+	}
+
+	// Simpan jenis pengajuan dalam bentuk kanonis agar "cuti" tidak melewati validasi khusus Cuti.
+	switch {
+	case strings.EqualFold(input.RequestType, "Cuti"):
+		input.RequestType = "Cuti"
+	case strings.EqualFold(input.RequestType, "Sakit"):
+		input.RequestType = "Sakit"
+	default:
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Jenis pengajuan tidak valid. Hanya 'Cuti' atau 'Sakit' yang diterima.")
+	}
+
+	parsedStartDate, err := time.Parse("2006-01-02", input.StartDate)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format tanggal selesai tidak valid. Gunakan YYYY-MM-DD."})
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Format tanggal mulai tidak valid. Gunakan YYYY-MM-DD.")
+	}
+	parsedEndDate, err := time.Parse("2006-01-02", input.EndDate)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Format tanggal selesai tidak valid. Gunakan YYYY-MM-DD.")
 	}
 	if parsedStartDate.After(parsedEndDate) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tanggal selesai tidak boleh sebelum tanggal mulai."})
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Tanggal selesai tidak boleh sebelum tanggal mulai.")
+	}
+
+	// --- Durasi: penuh, setengah hari, atau per jam ---
+	input.Duration = c.FormValue("duration", input.Duration)
+	input.HalfDayPeriod = strings.ToUpper(c.FormValue("half_day_period", input.HalfDayPeriod))
+	input.StartTime = c.FormValue("start_time", input.StartTime)
+	input.EndTime = c.FormValue("end_time", input.EndTime)
+
+	switch input.Duration {
+	case models.LeaveDurationFullDay:
+		input.HalfDayPeriod, input.StartTime, input.EndTime = "", "", ""
+	case models.LeaveDurationHalfDay:
+		if input.HalfDayPeriod != models.HalfDayPeriodAM && input.HalfDayPeriod != models.HalfDayPeriodPM {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Untuk pengajuan setengah hari, 'half_day_period' wajib diisi 'AM' atau 'PM'.")
+		}
+		input.StartTime, input.EndTime = "", ""
+	case models.LeaveDurationHourly:
+		if input.StartTime == "" || input.EndTime == "" {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Untuk pengajuan per jam, 'start_time' dan 'end_time' wajib diisi (HH:MM).")
+		}
+		input.HalfDayPeriod = ""
+	default:
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Durasi tidak valid. Hanya 'full_day', 'half_day', atau 'hourly' yang diterima.")
+	}
+
+	if input.Duration != models.LeaveDurationFullDay && input.StartDate != input.EndDate {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Pengajuan setengah hari atau per jam hanya berlaku untuk satu tanggal.")
+	}
+
+	// Cuti sebagian hari dihitung terhadap jadwal kerja user pada tanggal tersebut.
+	var schedule *models.WorkSchedule
+	if input.Duration != models.LeaveDurationFullDay {
+		schedule, err = h.scheduleRepo.FindApplicableScheduleForUser(c.Context(), userID, input.StartDate)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Tidak ada jadwal kerja pada tanggal %s untuk pengajuan setengah hari atau per jam.", input.StartDate))
+		}
+	}
+
+	input.LeaveDays, err = util.CalculateLeaveDays(input.Duration, input.StartDate, input.EndDate, input.StartTime, input.EndTime, schedule)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
 	// --- Logika Khusus berdasarkan Jenis Pengajuan ---
-	if requestType == "Cuti" {
+	if input.RequestType == "Cuti" {
 		// Validasi 1: Untuk 'Cuti', tanggal mulai dan selesai harus sama (1 hari per pengajuan)
-		if startDateStr != endDateStr {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Untuk pengajuan 'Cuti', tanggal mulai dan tanggal selesai harus sama (1 hari per pengajuan).",
-			})
+		if input.StartDate != input.EndDate {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Untuk pengajuan 'Cuti', tanggal mulai dan tanggal selesai harus sama (1 hari per pengajuan).")
 		}
 
 		// Validasi 2: Cek Duplikasi Pengajuan Cuti di tanggal yang sama (penting!)
-		// Menggunakan FindByUserAndDateAndType karena ia bisa mengecek tumpang tindih untuk satu tanggal.
-		existingCutiOnDate, err := h.leaveRepo.FindByUserAndDateAndType(c.Context(), claims.UserID, startDateStr, "Cuti")
-		if err != nil && err != mongo.ErrNoDocuments {
-			log.Printf("ERROR: Gagal memeriksa duplikasi pengajuan Cuti di tanggal %s untuk user %s: %v", startDateStr, claims.UserID.Hex(), err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa duplikasi pengajuan Cuti."})
+		// Cuti pagi (AM) dan cuti siang (PM), atau cuti per jam yang tidak beririsan, boleh di tanggal yang sama.
+		existingCutiOnDate, err := h.findConflictingLeave(c.Context(), userID, input.StartDate, "Cuti", excludeID, input, schedule)
+		if err != nil {
+			log.Printf("ERROR: Gagal memeriksa duplikasi pengajuan Cuti di tanggal %s untuk user %s: %v", input.StartDate, userID.Hex(), err)
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa duplikasi pengajuan Cuti.")
 		}
 		if existingCutiOnDate != nil {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": fmt.Sprintf("Anda sudah memiliki pengajuan 'Cuti' (pending/disetujui) untuk tanggal %s. Setiap pengajuan cuti hanya untuk satu tanggal.", startDateStr),
-			})
+			return nil, fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("Anda sudah memiliki pengajuan 'Cuti' (pending/disetujui) yang beririsan untuk tanggal %s.", input.StartDate))
 		}
 
-		// Validasi 3: Cek Batasan Tahunan Cuti (Maksimal 12 kali per tahun)
+		// Validasi 3: Cek saldo cuti tahunan (dalam hari, termasuk pecahan setengah hari / per jam)
 		currentYear := parsedStartDate.Year()
-		annualLeaveCount, err := h.leaveRepo.CountByUserIDYearAndType(c.Context(), claims.UserID, currentYear, "Cuti")
+		usedLeaveDays, err := h.leaveRepo.SumLeaveDaysByUserIDYearAndType(c.Context(), userID, currentYear, "Cuti")
 		if err != nil {
-			log.Printf("ERROR: Gagal memeriksa jumlah pengajuan Cuti tahunan untuk user %s di tahun %d: %v", claims.UserID.Hex(), currentYear, err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa batasan pengajuan Cuti tahunan."})
+			log.Printf("ERROR: Gagal memeriksa saldo Cuti tahunan untuk user %s di tahun %d: %v", userID.Hex(), currentYear, err)
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa batasan pengajuan Cuti tahunan.")
 		}
-		if annualLeaveCount >= 12 {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": fmt.Sprintf("Anda telah mencapai batas maksimal 12 kali pengajuan 'Cuti' untuk tahun %d ini.", currentYear),
-			})
+		// Pengajuan pending yang sedang diedit sudah ikut terhitung; jangan dihitung dua kali.
+		if base != nil && base.Status == "pending" && strings.HasPrefix(base.StartDate, fmt.Sprintf("%d-", currentYear)) {
+			if base.LeaveDays > 0 {
+				usedLeaveDays -= base.LeaveDays
+			} else {
+				usedLeaveDays--
+			}
+		}
+		if usedLeaveDays+input.LeaveDays > models.AnnualLeaveDaysLimit {
+			return nil, fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("Saldo 'Cuti' Anda untuk tahun %d tidak mencukupi (terpakai %.2f dari %.0f hari, diajukan %.2f hari).", currentYear, usedLeaveDays, models.AnnualLeaveDaysLimit, input.LeaveDays))
 		}
 
-	} else if strings.EqualFold(requestType, "Sakit") {
+	} else if input.RequestType == "Sakit" {
 		// Validasi: Cek tumpang tindih tanggal untuk pengajuan Sakit (bisa rentang tanggal)
 		for d := parsedStartDate; !d.After(parsedEndDate); d = d.AddDate(0, 0, 1) {
 			dateStr := d.Format("2006-01-02")
-			existingSakitOnDate, err := h.leaveRepo.FindByUserAndDateAndType(c.Context(), claims.UserID, dateStr, "Sakit")
-			if err != nil && err != mongo.ErrNoDocuments {
-				log.Printf("ERROR: Gagal memeriksa tumpang tindih pengajuan Sakit di tanggal %s untuk user %s: %v", dateStr, claims.UserID.Hex(), err)
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Gagal memeriksa pengajuan Sakit sebelumnya.",
-				})
+			existingSakitOnDate, err := h.findConflictingLeave(c.Context(), userID, dateStr, "Sakit", excludeID, input, schedule)
+			if err != nil {
+				log.Printf("ERROR: Gagal memeriksa tumpang tindih pengajuan Sakit di tanggal %s untuk user %s: %v", dateStr, userID.Hex(), err)
+				return nil, fiber.NewError(fiber.StatusInternalServerError, "Gagal memeriksa pengajuan Sakit sebelumnya.")
 			}
 			if existingSakitOnDate != nil {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-					"error": fmt.Sprintf("Anda sudah memiliki pengajuan 'Sakit' (pending/disetujui) yang tumpang tindih untuk tanggal %s.", dateStr),
-				})
+				return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Anda sudah memiliki pengajuan 'Sakit' (pending/disetujui) yang tumpang tindih untuk tanggal %s.", dateStr))
 			}
 		}
 	}
 
-	// --- Logika Lampiran (wajib untuk Sakit) ---
-	var attachmentURL string
-	file, err := c.FormFile("attachment")
-	if err == nil && file != nil {
-		if file.Size > 2*1024*1024 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Ukuran file maksimal 2MB"})
-		}
-		allowedExtensions := map[string]bool{".pdf": true, ".jpg": true, ".jpeg": true, ".png": true}
-		ext := strings.ToLower(filepath.Ext(file.Filename))
-		if !allowedExtensions[ext] {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format file tidak didukung (hanya .pdf, .jpg, .jpeg, .png)"})
-		}
+	return input, nil
+}
 
-		bucket, err := config.GetGridFSBucket()
+// findConflictingLeave mengembalikan pengajuan pending/approved lain berjenis requestType yang
+// beririsan dengan input pada tanggal date, atau nil jika tidak ada. schedule adalah jadwal kerja
+// pada tanggal tersebut dan wajib diisi jika input sebagian hari.
+func (h *LeaveRequestHandler) findConflictingLeave(ctx context.Context, userID primitive.ObjectID, date, requestType string, excludeID primitive.ObjectID, input *leaveFormInput, schedule *models.WorkSchedule) (*models.LeaveRequest, error) {
+	existing, err := h.leaveRepo.FindByUserAndDateAndType(ctx, userID, date, requestType, excludeID)
+	if err != nil {
+		return nil, err
+	}
+	candidate := &models.LeaveRequest{
+		Duration:      input.Duration,
+		HalfDayPeriod: input.HalfDayPeriod,
+		StartTime:     input.StartTime,
+		EndTime:       input.EndTime,
+	}
+	for i := range existing {
+		overlap, err := util.LeavesOverlap(schedule, candidate, &existing[i])
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengakses penyimpanan file"})
+			return nil, err
 		}
-		src, err := file.Open()
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuka file"})
+		if overlap {
+			return &existing[i], nil
 		}
-		defer src.Close()
+	}
+	return nil, nil
+}
 
-		uploadFileName := fmt.Sprintf("%d_%s", time.Now().Unix(), strings.ReplaceAll(file.Filename, " ", "_"))
-		uploadStream, err := bucket.OpenUploadStream(uploadFileName)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal upload file"})
-		}
-		defer uploadStream.Close()
+// uploadLeaveAttachment menyimpan lampiran form "attachment" ke GridFS dan mengembalikan URL-nya,
+// atau string kosong jika tidak ada lampiran yang dikirim.
+func uploadLeaveAttachment(c *fiber.Ctx) (string, error) {
+	file, err := c.FormFile("attachment")
+	if err != nil || file == nil {
+		return "", nil
+	}
+	if file.Size > 2*1024*1024 {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Ukuran file maksimal 2MB")
+	}
+	allowedExtensions := map[string]bool{".pdf": true, ".jpg": true, ".jpeg": true, ".png": true}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !allowedExtensions[ext] {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Format file tidak didukung (hanya .pdf, .jpg, .jpeg, .png)")
+	}
 
-		if _, err := io.Copy(uploadStream, src); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan file"})
-		}
-		attachmentURL = fmt.Sprintf("/api/v1/files/%s", uploadStream.FileID.(primitive.ObjectID).Hex())
-	} else if requestType == "Sakit" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Lampiran wajib untuk pengajuan Sakit"})
+	bucket, err := config.GetGridFSBucket()
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Gagal mengakses penyimpanan file")
 	}
+	src, err := file.Open()
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Gagal membuka file")
+	}
+	defer src.Close()
 
-	// --- Buat dan Simpan Pengajuan ---
-	newRequest := &models.LeaveRequest{
-		ID:            primitive.NewObjectID(),
-		UserID:        claims.UserID,
-		StartDate:     startDateStr,
-		EndDate:       endDateStr, // EndDate tetap disimpan, bahkan jika sama dengan StartDate untuk Cuti
-		Reason:        reason,
-		Status:        "pending",
-		RequestType:   requestType,
-		AttachmentURL: attachmentURL,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
-
-	_, createErr := h.leaveRepo.Create(newRequest)
-	if createErr != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan data pengajuan"})
+	uploadFileName := fmt.Sprintf("%d_%s", time.Now().Unix(), strings.ReplaceAll(file.Filename, " ", "_"))
+	uploadStream, err := bucket.OpenUploadStream(uploadFileName)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Gagal upload file")
 	}
+	defer uploadStream.Close()
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Pengajuan berhasil dikirim", "request": newRequest})
+	if _, err := io.Copy(uploadStream, src); err != nil {
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Gagal menyimpan file")
+	}
+	return fmt.Sprintf("/api/v1/files/%s", uploadStream.FileID.(primitive.ObjectID).Hex()), nil
+}
+
+// buildApprovals menentukan rantai persetujuan untuk pemohon dan jenis pengajuan.
+func (h *LeaveRequestHandler) buildApprovals(ctx context.Context, userID primitive.ObjectID, requestType string) (*primitive.ObjectID, []models.ApprovalStep, error) {
+	requester, err := h.userRepo.FindUserByID(ctx, userID)
+	if err != nil || requester == nil {
+		log.Printf("ERROR: Gagal mengambil data user %s untuk rantai persetujuan: %v", userID.Hex(), err)
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Gagal mengambil data karyawan")
+	}
+	chain, err := h.chainRepo.FindBestMatch(ctx, requestType, requester.Department)
+	if err != nil {
+		log.Printf("ERROR: Gagal menentukan rantai persetujuan untuk user %s: %v", userID.Hex(), err)
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Gagal menentukan rantai persetujuan")
+	}
+	var chainID *primitive.ObjectID
+	stepDefinitions := models.DefaultApprovalSteps()
+	if chain != nil {
+		chainID = &chain.ID
+		stepDefinitions = chain.Steps
+	}
+	return chainID, h.resolveApprovalSteps(ctx, requester, stepDefinitions), nil
 }
 
 // GetAllLeaveRequests godoc
 // @Summary Get All Leave Requests
-// @Description Mengambil semua pengajuan izin/cuti/sakit (admin only)
+// @Description Mengambil pengajuan izin/cuti/sakit dengan filter, sorting, dan pagination (admin only)
 // @Tags Admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// PERBAIKAN DI SINI: Ubah tipe di Swagger doc
-// @Success 200 {array} models.LeaveRequestWithUser "Daftar pengajuan berhasil diambil dengan detail user"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 10, max: 100)"
+// @Param status query string false "Filter by status (pending, approved, rejected, cancelled, withdrawn)"
+// @Param request_type query string false "Filter by jenis pengajuan (Cuti, Sakit)"
+// @Param user_id query string false "Filter by User ID"
+// @Param department query string false "Filter by departemen pemohon"
+// @Param start_date query string false "Pengajuan yang beririsan mulai tanggal ini (YYYY-MM-DD)"
+// @Param end_date query string false "Pengajuan yang beririsan sampai tanggal ini (YYYY-MM-DD)"
+// @Param sort_by query string false "Urutkan berdasarkan (created_at, start_date, status; default: created_at)"
+// @Param sort_order query string false "Arah urutan (asc, desc; default: desc)"
+// @Success 200 {object} object{data=[]models.LeaveRequestWithUser,total=int,page=int,limit=int} "Daftar pengajuan berhasil diambil dengan detail user"
+// @Failure 400 {object} object{error=string} "Invalid parameters"
 // @Failure 500 {object} object{error=string} "Gagal mengambil data pengajuan"
 // @Router /leave-requests [get]
 func (h *LeaveRequestHandler) GetAllLeaveRequests(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	status := c.Query("status", "")
+	requestType := c.Query("request_type", "")
+	userIDParam := c.Query("user_id", "")
+	department := c.Query("department", "")
+	startDateStr := c.Query("start_date", "")
+	endDateStr := c.Query("end_date", "")
+	sortBy := c.Query("sort_by", "created_at")
+	sortOrder := c.Query("sort_order", "desc")
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	allowedSorts := map[string]bool{"created_at": true, "start_date": true, "status": true}
+	if !allowedSorts[sortBy] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "sort_by tidak valid. Gunakan created_at, start_date, atau status."})
+	}
+	direction := -1
+	if sortOrder == "asc" {
+		direction = 1
+	} else if sortOrder != "desc" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "sort_order tidak valid. Gunakan asc atau desc."})
+	}
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	if requestType != "" {
+		filter["request_type"] = requestType
+	}
+	if userIDParam != "" {
+		objID, err := primitive.ObjectIDFromHex(userIDParam)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format User ID tidak valid."})
+		}
+		filter["user_id"] = objID
+	}
+
+	// Rentang tanggal: ambil pengajuan yang beririsan dengan [start_date, end_date].
+	if startDateStr != "" {
+		if _, err := time.Parse("2006-01-02", startDateStr); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format start_date tidak valid (YYYY-MM-DD)."})
+		}
+		filter["end_date"] = bson.M{"$gte": startDateStr}
+	}
+	if endDateStr != "" {
+		if _, err := time.Parse("2006-01-02", endDateStr); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format end_date tidak valid (YYYY-MM-DD)."})
+		}
+		filter["start_date"] = bson.M{"$lte": endDateStr}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
 
-	requests, err := h.leaveRepo.FindAll()
+	if department != "" {
+		userIDs, err := h.userRepo.FindUserIDsByDepartment(ctx, department)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data pengajuan: " + err.Error()})
+		}
+		if objID, ok := filter["user_id"].(primitive.ObjectID); ok {
+			inDepartment := false
+			for _, id := range userIDs {
+				if id == objID {
+					inDepartment = true
+					break
+				}
+			}
+			if !inDepartment {
+				userIDs = []primitive.ObjectID{}
+			}
+		}
+		filter["user_id"] = bson.M{"$in": userIDs}
+	}
+
+	sort := bson.D{{Key: sortBy, Value: direction}, {Key: "_id", Value: direction}}
+	requests, total, err := h.leaveRepo.FindAllWithUserDetails(ctx, filter, sort, int64(page), int64(limit))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mengambil data pengajuan: %v", err)})
 	}
-	return c.Status(fiber.StatusOK).JSON(requests)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":  requests,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
 }
 
 // GetMyLeaveRequests godoc
@@ -239,7 +780,7 @@ func (h *LeaveRequestHandler) GetMyLeaveRequests(c *fiber.Ctx) error {
 
 // UploadAttachment godoc
 // @Summary Upload Attachment for Leave Request
-// @Description Mengunggah file lampiran untuk pengajuan izin/cuti/sakit
+// @Description Mengunggah file lampiran untuk pengajuan izin/cuti/sakit milik sendiri
 // @Tags Leave Request
 // @Accept multipart/form-data
 // @Produce json
@@ -248,9 +789,17 @@ func (h *LeaveRequestHandler) GetMyLeaveRequests(c *fiber.Ctx) error {
 // @Param attachment formData file true "File lampiran"
 // @Success 200 {object} object{message=string,file_url=string} "File berhasil diunggah"
 // @Failure 400 {object} object{error=string} "ID tidak valid atau file tidak ditemukan"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 403 {object} object{error=string} "Bukan pengajuan milik sendiri"
+// @Failure 404 {object} object{error=string} "Pengajuan tidak ditemukan"
 // @Failure 500 {object} object{error=string} "Gagal menyimpan file"
 // @Router /leave-requests/{id}/attachment [post]
 func (h *LeaveRequestHandler) UploadAttachment(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Klaim token tidak valid atau sesi rusak"})
+	}
+
 	id := c.Params("id")
 	log.Println("[UploadAttachment] ID:", id)
 
@@ -259,6 +808,20 @@ func (h *LeaveRequestHandler) UploadAttachment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID pengajuan tidak valid"})
 	}
 
+	// Lampiran menjadi bukti pengajuan (mis. surat dokter), sehingga hanya pemilik pengajuan
+	// yang boleh menggantinya.
+	leaveRequest, err := h.leaveRepo.FindByID(reqID)
+	if err != nil {
+		log.Printf("ERROR: Gagal mengambil pengajuan %s: %v", reqID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa pengajuan"})
+	}
+	if leaveRequest == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pengajuan tidak ditemukan"})
+	}
+	if leaveRequest.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda hanya dapat mengunggah lampiran untuk pengajuan milik sendiri"})
+	}
+
 	fileHeader, err := c.FormFile("attachment")
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File tidak ditemukan"})
@@ -299,6 +862,7 @@ func (h *LeaveRequestHandler) UploadAttachment(c *fiber.Ctx) error {
 		log.Println("Gagal menyimpan URL lampiran ke DB:", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Gagal menyimpan file ke database"})
 	}
+	recordAudit(c, models.AuditActionLeaveAttachmentUpload, models.AuditTargetLeaveRequest, reqID.Hex(), nil, fiber.Map{"attachment_url": fileURL})
 
 	return c.Status(200).JSON(fiber.Map{
 		"message":  "File berhasil diunggah",
@@ -308,19 +872,32 @@ func (h *LeaveRequestHandler) UploadAttachment(c *fiber.Ctx) error {
 
 // UpdateLeaveRequestStatus godoc
 // @Summary Update Leave Request Status
-// @Description Memperbarui status pengajuan izin/cuti/sakit (admin only)
+// @Description Memberikan keputusan (approved/rejected) pada langkah persetujuan yang sedang aktif (admin only). Pengajuan baru menjadi 'approved' setelah langkah terakhir disetujui; penolakan di langkah mana pun mengakhiri rantai. Jika persetujuan membuat jumlah karyawan yang hadir di departemen di bawah batas minimum, respons berisi staffing_warnings (kebijakan 'warn') atau ditolak dengan 409 (kebijakan 'block').
 // @Tags Admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Leave Request ID"
 // @Param payload body models.LeaveRequestUpdatePayload true "Data update status"
-// @Success 200 {object} object{message=string} "Status pengajuan berhasil diperbarui"
+// @Success 200 {object} object{message=string,status=string,staffing_warnings=[]models.StaffingConflict} "Status pengajuan berhasil diperbarui"
 // @Failure 400 {object} object{error=string} "ID tidak valid atau payload tidak valid"
+// @Failure 403 {object} object{error=string} "Bukan approver untuk langkah yang sedang aktif, atau pengajuan milik sendiri"
 // @Failure 404 {object} object{error=string} "Pengajuan tidak ditemukan"
+// @Failure 409 {object} object{error=string,conflicts=[]models.StaffingConflict} "Pengajuan sudah diputuskan, sedang diproses approver lain, atau melanggar batas minimum staf"
 // @Failure 500 {object} object{error=string} "Gagal memperbarui status"
 // @Router /leave-requests/{id}/status [put]
 func (h *LeaveRequestHandler) UpdateLeaveRequestStatus(c *fiber.Ctx) error {
+	return h.decideApprovalStep(c, models.AuditActionLeaveStatusUpdate)
+}
+
+// decideApprovalStep menyimpan keputusan pada langkah persetujuan yang sedang aktif. auditAction
+// membedakan keputusan lewat endpoint admin dan endpoint approver di audit log.
+func (h *LeaveRequestHandler) decideApprovalStep(c *fiber.Ctx, auditAction string) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Klaim token tidak valid atau sesi rusak"})
+	}
+
 	id := c.Params("id")
 	reqID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -359,6 +936,22 @@ func (h *LeaveRequestHandler) UpdateLeaveRequestStatus(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pengajuan tidak ditemukan"})
 	}
 
+	if originalRequest.Status == "cancelled" || originalRequest.Status == "withdrawn" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("Pengajuan sudah berstatus '%s' dan tidak dapat diproses lagi.", originalRequest.Status),
+		})
+	}
+	if originalRequest.WithdrawalRequested {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Pengajuan sedang menunggu konfirmasi penarikan. Gunakan endpoint penarikan untuk memprosesnya.",
+		})
+	}
+	if originalRequest.Status != "pending" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("Pengajuan sudah diputuskan dengan status '%s'.", originalRequest.Status),
+		})
+	}
+
 	// ✅ Validasi agar hanya Cuti dan Sakit yang boleh
 	if originalRequest.RequestType != "Cuti" && originalRequest.RequestType != "Sakit" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -366,116 +959,613 @@ func (h *LeaveRequestHandler) UpdateLeaveRequestStatus(c *fiber.Ctx) error {
 		})
 	}
 
-	// Proses update status di database
-	updateResult, err := h.leaveRepo.UpdateStatus(reqID, payload.Status, payload.Note)
+	// Pengajuan lama belum punya rantai persetujuan: perlakukan sebagai satu langkah oleh admin.
+	approvals := originalRequest.Approvals
+	stepIndex := originalRequest.CurrentStep
+	if len(approvals) == 0 {
+		approvals = models.NewApprovalSteps([]models.ApprovalStepDefinition{models.AdminApprovalStep("Persetujuan Admin")})
+		stepIndex = 0
+	}
+	if stepIndex < 0 || stepIndex >= len(approvals) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Rantai persetujuan pengajuan ini tidak valid."})
+	}
+
+	// Pemohon tidak boleh memutuskan pengajuannya sendiri di langkah mana pun, termasuk admin.
+	if claims.UserID == originalRequest.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda tidak dapat memutuskan pengajuan milik sendiri."})
+	}
+
+	step := approvals[stepIndex]
+	var delegation *models.ApprovalDelegation
+	canAct, err := h.canActOnApprovalStep(c.Context(), claims, &step)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Gagal memperbarui status pengajuan cuti",
-		})
+		log.Printf("ERROR: Gagal memeriksa wewenang persetujuan user %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa wewenang persetujuan"})
 	}
-	if updateResult.MatchedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pengajuan dengan ID ini tidak ditemukan"})
+	if !canAct {
+		delegation, err = h.findDelegationForStep(c.Context(), claims, &step)
+		if err != nil {
+			log.Printf("ERROR: Gagal memeriksa delegasi persetujuan user %s: %v", claims.UserID.Hex(), err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa delegasi persetujuan"})
+		}
+		if delegation == nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": fmt.Sprintf("Anda bukan approver untuk langkah '%s' pada pengajuan ini.", step.Name),
+			})
+		}
 	}
 
-	// Parse tanggal
-	startDate, parseErr := time.Parse("2006-01-02", originalRequest.StartDate)
-	if parseErr != nil {
-		log.Printf("ERROR: Gagal parse start_date %s: %v", originalRequest.StartDate, parseErr)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Format tanggal mulai tidak valid.",
-		})
+	// Cek batas minimum staf departemen sebelum menyetujui.
+	var staffingWarnings []models.StaffingConflict
+	if payload.Status == "approved" {
+		conflicts, policy, err := h.checkStaffing(c.Context(), originalRequest)
+		if err != nil {
+			log.Printf("ERROR: Gagal memeriksa batas minimum staf untuk pengajuan %s: %v", reqID.Hex(), err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memeriksa ketersediaan staf departemen"})
+		}
+		if len(conflicts) > 0 && policy == models.StaffingPolicyBlock {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":     "Persetujuan ditolak: jumlah karyawan yang hadir akan di bawah batas minimum departemen.",
+				"conflicts": conflicts,
+			})
+		}
+		staffingWarnings = conflicts
 	}
-	endDate, parseErr := time.Parse("2006-01-02", originalRequest.EndDate)
-	if parseErr != nil {
-		log.Printf("ERROR: Gagal parse end_date %s: %v", originalRequest.EndDate, parseErr)
+
+	decidedAt := time.Now()
+	step.Decision = payload.Status
+	step.ActorID = &claims.UserID
+	step.ActorEmail = claims.Email
+	if delegation != nil {
+		step.OnBehalfOfID = &delegation.DelegatorID
+		step.DelegationID = &delegation.ID
+	}
+	step.Note = payload.Note
+	step.DecidedAt = &decidedAt
+	approvals[stepIndex] = step
+
+	// Persetujuan di langkah selain terakhir hanya memajukan rantai; penolakan langsung mengakhiri.
+	newStatus := payload.Status
+	if payload.Status == "approved" && stepIndex < len(approvals)-1 {
+		newStatus = "pending"
+	}
+
+	// Keputusan dan seluruh perubahan absensi disimpan dalam satu transaksi:
+	// jika salah satu penulisan absensi gagal, status pengajuan juga tidak berubah.
+	err = config.WithTransaction(c.Context(), func(sessCtx mongo.SessionContext) error {
+		updateResult, err := h.leaveRepo.RecordApprovalDecision(sessCtx, reqID, stepIndex, approvals, newStatus, payload.Note)
+		if err != nil {
+			return err
+		}
+		if updateResult.MatchedCount == 0 {
+			return errLeaveRequestChanged
+		}
+		if newStatus == "pending" {
+			return nil
+		}
+		return h.attendanceRepo.ApplyLeaveDecision(sessCtx, originalRequest, newStatus, payload.Note)
+	})
+	if errors.Is(err, errLeaveRequestChanged) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Pengajuan sudah diproses oleh approver lain, silakan muat ulang data."})
+	}
+	if err != nil {
+		log.Printf("ERROR: Gagal menyimpan keputusan pengajuan %s: %v", reqID.Hex(), err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Format tanggal berakhir tidak valid.",
+			"error": "Gagal memperbarui status pengajuan cuti. Tidak ada perubahan yang disimpan.",
 		})
 	}
 
-	// Proses absensi berdasarkan status baru
-	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
-		currentDateStr := d.Format("2006-01-02")
+	decided, err := h.leaveRepo.FindByID(reqID)
+	if err != nil {
+		log.Printf("ERROR: Gagal membaca pengajuan %s setelah keputusan untuk audit log: %v", reqID.Hex(), err)
+	}
+	recordAudit(c, auditAction, models.AuditTargetLeaveRequest, reqID.Hex(), originalRequest, decided)
+	switch newStatus {
+	case "approved":
+		h.recordLeaveActivity(c.Context(), models.ActivityLeaveApproved, originalRequest, &claims.UserID, "Pengajuan "+leaveActivitySubject(originalRequest)+" disetujui")
+	case "rejected":
+		h.recordLeaveActivity(c.Context(), models.ActivityLeaveRejected, originalRequest, &claims.UserID, "Pengajuan "+leaveActivitySubject(originalRequest)+" ditolak")
+	}
+
+	if newStatus == "pending" {
+		response := fiber.Map{
+			"message": fmt.Sprintf("Langkah '%s' disetujui. Menunggu persetujuan langkah berikutnya: '%s'.", step.Name, approvals[stepIndex+1].Name),
+			"status":  newStatus,
+		}
+		if len(staffingWarnings) > 0 {
+			response["staffing_warnings"] = staffingWarnings
+		}
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+
+	response := fiber.Map{
+		"message": "Status pengajuan berhasil diperbarui",
+		"status":  newStatus,
+	}
+	if len(staffingWarnings) > 0 {
+		response["staffing_warnings"] = staffingWarnings
+	}
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// checkStaffing menghitung konflik batas minimum staf jika pengajuan disetujui, beserta kebijakan
+// departemen pemohon. Departemen tanpa MinStaffing tidak pernah menghasilkan konflik.
+func (h *LeaveRequestHandler) checkStaffing(ctx context.Context, request *models.LeaveRequest) ([]models.StaffingConflict, string, error) {
+	requester, err := h.userRepo.FindUserByID(ctx, request.UserID)
+	if err != nil || requester == nil || requester.Department == "" {
+		return nil, "", err
+	}
+	dept, err := h.deptRepo.FindDepartmentByName(ctx, requester.Department)
+	if err != nil || dept == nil || dept.MinStaffing == nil || *dept.MinStaffing <= 0 {
+		// FindDepartmentByName mengembalikan error jika departemen tidak ada; anggap tanpa aturan.
+		return nil, "", nil
+	}
+
+	members, err := h.userRepo.FindUsersByDepartment(ctx, dept.Name)
+	if err != nil {
+		return nil, "", err
+	}
+	memberIDs := make([]primitive.ObjectID, 0, len(members))
+	for _, member := range members {
+		memberIDs = append(memberIDs, member.ID)
+	}
+
+	approved, err := h.leaveRepo.FindInDateRangeForUsers(ctx, memberIDs, request.StartDate, request.EndDate, []string{"approved"})
+	if err != nil {
+		return nil, "", err
+	}
+
+	conflicts, err := util.FindStaffingConflicts(request, len(members), *dept.MinStaffing, approved)
+	if err != nil {
+		return nil, "", err
+	}
+
+	policy := dept.StaffingPolicy
+	if policy == "" {
+		policy = models.StaffingPolicyWarn
+	}
+	return conflicts, policy, nil
+}
+
+// GetLeaveCalendar godoc
+// @Summary Get Department Leave Calendar
+// @Description Mengambil kalender cuti harian (approved dan pending) untuk satu departemen, lengkap dengan jumlah karyawan, batas minimum staf, dan penanda hari yang kekurangan staf (admin only). Rentang maksimal 92 hari.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param department query string true "Nama departemen"
+// @Param start_date query string true "Tanggal mulai (YYYY-MM-DD)"
+// @Param end_date query string true "Tanggal selesai (YYYY-MM-DD)"
+// @Success 200 {object} object{department=string,min_staffing=int,staffing_policy=string,days=[]models.LeaveCalendarDay} "Kalender cuti departemen"
+// @Failure 400 {object} object{error=string} "Parameter tidak valid"
+// @Failure 404 {object} object{error=string} "Departemen tidak ditemukan"
+// @Failure 500 {object} object{error=string} "Gagal mengambil kalender cuti"
+// @Router /leave-requests/calendar [get]
+func (h *LeaveRequestHandler) GetLeaveCalendar(c *fiber.Ctx) error {
+	department := c.Query("department", "")
+	startDateStr := c.Query("start_date", "")
+	endDateStr := c.Query("end_date", "")
+	if department == "" || startDateStr == "" || endDateStr == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Parameter department, start_date, dan end_date wajib diisi"})
+	}
+
+	startDate, errStart := time.Parse("2006-01-02", startDateStr)
+	endDate, errEnd := time.Parse("2006-01-02", endDateStr)
+	if errStart != nil || errEnd != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format tanggal tidak valid (YYYY-MM-DD)"})
+	}
+	if endDate.Before(startDate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tanggal selesai tidak boleh sebelum tanggal mulai"})
+	}
+	if endDate.Sub(startDate) > 91*24*time.Hour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Rentang kalender maksimal 92 hari"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	dept, err := h.deptRepo.FindDepartmentByName(ctx, department)
+	if err != nil || dept == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Departemen tidak ditemukan"})
+	}
+
+	members, err := h.userRepo.FindUsersByDepartment(ctx, dept.Name)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil karyawan departemen"})
+	}
+	memberIDs := make([]primitive.ObjectID, 0, len(members))
+	for _, member := range members {
+		memberIDs = append(memberIDs, member.ID)
+	}
+
+	requests, err := h.leaveRepo.FindInDateRangeForUsers(ctx, memberIDs, startDateStr, endDateStr, []string{"approved", "pending"})
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil kalender cuti"})
+	}
+
+	minStaffing := 0
+	if dept.MinStaffing != nil {
+		minStaffing = *dept.MinStaffing
+	}
+	days, err := util.BuildLeaveCalendar(startDateStr, endDateStr, len(members), minStaffing, requests)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	policy := dept.StaffingPolicy
+	if policy == "" {
+		policy = models.StaffingPolicyWarn
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"department":      dept.Name,
+		"min_staffing":    minStaffing,
+		"staffing_policy": policy,
+		"days":            days,
+	})
+}
+
+// DecideLeaveRequest godoc
+// @Summary Decide Leave Request Approval Step
+// @Description Memberikan keputusan pada langkah persetujuan yang sedang aktif oleh approver yang ditunjuk rantai persetujuan (misal: atasan langsung atau kepala departemen).
+// @Tags Leave Request
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Leave Request ID"
+// @Param payload body models.LeaveRequestUpdatePayload true "Keputusan (approved/rejected) dan catatan"
+// @Success 200 {object} object{message=string,status=string} "Keputusan berhasil disimpan"
+// @Failure 400 {object} object{error=string} "ID tidak valid atau payload tidak valid"
+// @Failure 403 {object} object{error=string} "Bukan approver untuk langkah yang sedang aktif, atau pengajuan milik sendiri"
+// @Failure 404 {object} object{error=string} "Pengajuan tidak ditemukan"
+// @Failure 409 {object} object{error=string} "Pengajuan sudah diputuskan"
+// @Failure 500 {object} object{error=string} "Gagal menyimpan keputusan"
+// @Router /leave-requests/{id}/decision [put]
+func (h *LeaveRequestHandler) DecideLeaveRequest(c *fiber.Ctx) error {
+	return h.decideApprovalStep(c, models.AuditActionLeaveDecision)
+}
+
+// GetMyPendingApprovals godoc
+// @Summary Get Leave Requests Awaiting My Approval
+// @Description Mengambil pengajuan pending yang langkah persetujuan aktifnya menunggu keputusan user yang sedang login
+// @Tags Leave Request
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.LeaveRequestWithUser "Daftar pengajuan yang menunggu persetujuan"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 500 {object} object{error=string} "Gagal mengambil data pengajuan"
+// @Router /leave-requests/approvals/pending [get]
+func (h *LeaveRequestHandler) GetMyPendingApprovals(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Klaim token tidak valid atau sesi rusak"})
+	}
+
+	approverIDs := []primitive.ObjectID{claims.UserID}
+	delegations, err := h.delegationRepo.FindActiveForDelegate(c.Context(), claims.UserID, todayWIB())
+	if err != nil {
+		log.Printf("ERROR: Gagal mengambil delegasi aktif user %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data pengajuan"})
+	}
+	// Hanya langkah yang menunjuk delegator secara langsung yang ikut didelegasikan (lihat findDelegationForStep).
+	for _, d := range delegations {
+		approverIDs = append(approverIDs, d.DelegatorID)
+	}
+
+	canApproveAll, err := h.roleRepo.HasPermission(c.Context(), claims.Role, models.PermissionLeaveApprove)
+	if err != nil {
+		log.Printf("ERROR: Gagal memeriksa izin role %s: %v", claims.Role, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data pengajuan"})
+	}
+
+	requests, err := h.leaveRepo.FindPendingForApprover(c.Context(), approverIDs, []string{claims.Role}, canApproveAll)
+	if err != nil {
+		log.Printf("ERROR: Gagal mengambil pengajuan yang menunggu persetujuan user %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data pengajuan"})
+	}
+	return c.Status(fiber.StatusOK).JSON(requests)
+}
+
+// canActOnApprovalStep memeriksa apakah user pada claims adalah approver untuk langkah tersebut:
+// user yang ditunjuk langsung, anggota role yang dituju, atau pemegang izin leave.approve yang boleh
+// memutuskan langkah mana pun (misalnya saat atasan berhalangan, atau langkah pengganti admin).
+func (h *LeaveRequestHandler) canActOnApprovalStep(ctx context.Context, claims *models.Claims, step *models.ApprovalStep) (bool, error) {
+	switch step.ApproverType {
+	case models.ApproverTypeRole:
+		if claims.Role == step.ApproverRole {
+			return true, nil
+		}
+	case models.ApproverTypeUser, models.ApproverTypeManager, models.ApproverTypeDepartmentHead:
+		if step.ApproverID != nil && *step.ApproverID == claims.UserID {
+			return true, nil
+		}
+	default:
+		return false, nil
+	}
+	return h.roleRepo.HasPermission(ctx, claims.Role, models.PermissionLeaveApprove)
+}
+
+// findDelegationForStep mencari delegasi aktif hari ini yang memberi user pada claims hak
+// untuk memutuskan langkah tersebut atas nama approver aslinya. Mengembalikan nil jika tidak ada.
+// Delegasi hanya meneruskan langkah yang menunjuk delegator secara langsung (ApproverID); wewenang
+// berbasis role tidak ikut didelegasikan, sehingga delegate yang ingin memutuskan langkah role harus
+// memiliki wewenang itu sendiri. Data delegator dibaca ulang agar delegasi dari akun yang sudah
+// dihapus atau nonaktif tidak berlaku lagi.
+func (h *LeaveRequestHandler) findDelegationForStep(ctx context.Context, claims *models.Claims, step *models.ApprovalStep) (*models.ApprovalDelegation, error) {
+	if step.ApproverType == models.ApproverTypeRole || step.ApproverID == nil {
+		return nil, nil
+	}
 
-		existingAttendance, err := h.attendanceRepo.FindAttendanceByUserAndDate(
-			c.Context(), originalRequest.UserID, currentDateStr,
-		)
-		if err != nil && err != mongo.ErrNoDocuments {
-			log.Printf("ERROR: Gagal mencari absensi untuk user %s tanggal %s: %v",
-				originalRequest.UserID.Hex(), currentDateStr, err)
+	delegations, err := h.delegationRepo.FindActiveForDelegate(ctx, claims.UserID, todayWIB())
+	if err != nil {
+		return nil, err
+	}
+	for i := range delegations {
+		if delegations[i].DelegatorID != *step.ApproverID {
+			continue
+		}
+		delegator, err := h.userRepo.FindUserByID(ctx, delegations[i].DelegatorID)
+		if err != nil {
+			return nil, err
+		}
+		if delegator == nil || delegator.EmploymentStatus == models.EmploymentStatusInactive {
 			continue
 		}
+		return &delegations[i], nil
+	}
+	return nil, nil
+}
 
-		if payload.Status == "approved" {
-			if existingAttendance == nil {
-				attendanceRecord := &models.Attendance{
-					ID:        primitive.NewObjectID(),
-					UserID:    originalRequest.UserID,
-					Date:      currentDateStr,
-					CheckIn:   "",
-					CheckOut:  "",
-					Status:    originalRequest.RequestType,
-					Note:      fmt.Sprintf("Disetujui: %s. Catatan admin: %s", originalRequest.Reason, payload.Note),
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
-				}
-				_, createErr := h.attendanceRepo.CreateAttendance(c.Context(), attendanceRecord)
-				if createErr != nil {
-					log.Printf("ERROR: Gagal menyimpan absensi baru untuk tanggal %s (approved): %v", currentDateStr, createErr)
-				}
-			} else {
-				updatePayload := models.AttendanceUpdatePayload{
-					Status:   originalRequest.RequestType,
-					Note:     fmt.Sprintf("Disetujui: %s. Catatan admin: %s", originalRequest.Reason, payload.Note),
-					CheckIn:  "",
-					CheckOut: "",
-				}
-				_, updateErr := h.attendanceRepo.UpdateAttendance(c.Context(), existingAttendance.ID, &updatePayload)
-				if updateErr != nil {
-					log.Printf("ERROR: Gagal memperbarui absensi existing untuk tanggal %s (approved): %v", currentDateStr, updateErr)
+// todayWIB mengembalikan tanggal hari ini (YYYY-MM-DD) dalam zona waktu Asia/Jakarta.
+func todayWIB() string {
+	wib, _ := time.LoadLocation("Asia/Jakarta")
+	return time.Now().In(wib).Format("2006-01-02")
+}
+
+// resolveApprovalSteps membuat langkah persetujuan dan mengisi ApproverID untuk langkah
+// atasan langsung / kepala departemen berdasarkan data pemohon saat pengajuan dibuat.
+// Langkah yang approvernya tidak dapat ditentukan (atau adalah pemohon sendiri) dialihkan ke
+// langkah pengganti admin, yang dapat diputuskan semua pemegang izin leave.approve.
+func (h *LeaveRequestHandler) resolveApprovalSteps(ctx context.Context, requester *models.User, definitions []models.ApprovalStepDefinition) []models.ApprovalStep {
+	steps := models.NewApprovalSteps(definitions)
+	for i := range steps {
+		var approverID *primitive.ObjectID
+		switch steps[i].ApproverType {
+		case models.ApproverTypeManager:
+			approverID = requester.ManagerID
+		case models.ApproverTypeDepartmentHead:
+			if requester.Department != "" {
+				// FindDepartmentByName mengembalikan error jika departemen tidak ada; cukup dialihkan ke admin.
+				if dept, err := h.deptRepo.FindDepartmentByName(ctx, requester.Department); err == nil && dept != nil {
+					approverID = dept.HeadID
 				}
 			}
-		} else if payload.Status == "rejected" {
-			if existingAttendance != nil {
-				if existingAttendance.Status == "Sakit" || existingAttendance.Status == "Cuti" {
-					if existingAttendance.CheckIn == "" && existingAttendance.CheckOut == "" {
-						updatePayload := models.AttendanceUpdatePayload{
-							Status:   "Tidak Absen",
-							Note:     fmt.Sprintf("Pengajuan ditolak: %s. Catatan admin: %s", originalRequest.Reason, payload.Note),
-							CheckIn:  "",
-							CheckOut: "",
-						}
-						_, updateErr := h.attendanceRepo.UpdateAttendance(c.Context(), existingAttendance.ID, &updatePayload)
-						if updateErr != nil {
-							log.Printf("ERROR: Gagal memperbarui absensi existing untuk tanggal %s (rejected): %v", currentDateStr, updateErr)
-						}
-					}
-				}
-			} else {
-				newAttendance := &models.Attendance{
-					ID:        primitive.NewObjectID(),
-					UserID:    originalRequest.UserID,
-					Date:      currentDateStr,
-					CheckIn:   "",
-					CheckOut:  "",
-					Status:    "Tidak Absen",
-					Note:      fmt.Sprintf("Pengajuan ditolak: %s. Catatan admin: %s", originalRequest.Reason, payload.Note),
-					CreatedAt: time.Now(),
-					UpdatedAt: time.Now(),
-				}
-				_, createErr := h.attendanceRepo.CreateAttendance(c.Context(), newAttendance)
-				if createErr != nil {
-					log.Printf("ERROR: Gagal membuat absensi default untuk tanggal %s (rejected): %v", currentDateStr, createErr)
-				}
+		default:
+			continue
+		}
+
+		if approverID == nil || *approverID == requester.ID {
+			fallback := models.AdminApprovalStep(steps[i].Name)
+			steps[i].ApproverType = fallback.ApproverType
+			steps[i].ApproverRole = fallback.ApproverRole
+			steps[i].ApproverID = nil
+			continue
+		}
+		steps[i].ApproverID = approverID
+	}
+	return steps
+}
+
+// CancelLeaveRequest godoc
+// @Summary Cancel or Withdraw Leave Request
+// @Description Membatalkan pengajuan milik sendiri. Pengajuan 'pending' langsung dibatalkan. Pengajuan 'approved' yang tanggalnya belum berjalan diajukan untuk ditarik dan menunggu konfirmasi admin.
+// @Tags Leave Request
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Leave Request ID"
+// @Param payload body models.LeaveRequestCancelPayload false "Alasan pembatalan/penarikan"
+// @Success 200 {object} object{message=string} "Pengajuan berhasil dibatalkan"
+// @Success 202 {object} object{message=string} "Permintaan penarikan dikirim, menunggu konfirmasi admin"
+// @Failure 400 {object} object{error=string} "ID tidak valid atau pengajuan tidak dapat dibatalkan"
+// @Failure 403 {object} object{error=string} "Bukan pengajuan milik sendiri"
+// @Failure 404 {object} object{error=string} "Pengajuan tidak ditemukan"
+// @Failure 409 {object} object{error=string} "Penarikan sudah diajukan"
+// @Failure 500 {object} object{error=string} "Gagal memproses pembatalan"
+// @Router /leave-requests/{id}/cancel [post]
+func (h *LeaveRequestHandler) CancelLeaveRequest(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Klaim token tidak valid atau sesi rusak"})
+	}
+
+	reqID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+
+	var payload models.LeaveRequestCancelPayload
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&payload); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload tidak valid"})
+		}
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	request, err := h.leaveRepo.FindByID(reqID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mencari pengajuan: %v", err)})
+	}
+	if request == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pengajuan tidak ditemukan"})
+	}
+	if request.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda hanya dapat membatalkan pengajuan milik sendiri."})
+	}
+
+	switch request.Status {
+	case "pending":
+		result, err := h.leaveRepo.CancelPendingRequest(c.Context(), reqID, payload.Reason)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membatalkan pengajuan"})
+		}
+		if result.ModifiedCount == 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Pengajuan sudah diproses admin dan tidak lagi berstatus pending."})
+		}
+		h.recordLeaveAudit(c, models.AuditActionLeaveCancel, request)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Pengajuan berhasil dibatalkan"})
+
+	case "approved":
+		wib, _ := time.LoadLocation("Asia/Jakarta")
+		today := time.Now().In(wib).Format("2006-01-02")
+		if request.StartDate <= today {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Pengajuan yang tanggalnya sudah berjalan atau lewat tidak dapat ditarik."})
+		}
+		if request.WithdrawalRequested {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Penarikan untuk pengajuan ini sudah diajukan dan menunggu konfirmasi admin."})
+		}
+		result, err := h.leaveRepo.RequestWithdrawal(c.Context(), reqID, payload.Reason)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengajukan penarikan"})
+		}
+		if result.ModifiedCount == 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Status pengajuan berubah, silakan muat ulang data."})
+		}
+		h.recordLeaveAudit(c, models.AuditActionLeaveCancel, request)
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "Permintaan penarikan dikirim, menunggu konfirmasi admin"})
+
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Pengajuan berstatus '%s' tidak dapat dibatalkan.", request.Status),
+		})
+	}
+}
+
+// ResolveLeaveWithdrawal godoc
+// @Summary Resolve Leave Withdrawal
+// @Description Menyetujui atau menolak permintaan penarikan pengajuan yang sudah approved (admin only). Jika disetujui, record absensi yang dibuat oleh persetujuan pengajuan tersebut dihapus, sedangkan record yang sudah ada sebelumnya dikembalikan ke status dan catatan semula.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Leave Request ID"
+// @Param payload body models.LeaveWithdrawalDecisionPayload true "Keputusan penarikan"
+// @Success 200 {object} object{message=string,removed_attendances=int,restored_attendances=int} "Penarikan berhasil diproses"
+// @Failure 400 {object} object{error=string} "ID atau payload tidak valid, atau tanggal sudah lewat"
+// @Failure 404 {object} object{error=string} "Pengajuan tidak ditemukan"
+// @Failure 409 {object} object{error=string} "Tidak ada permintaan penarikan"
+// @Failure 500 {object} object{error=string} "Gagal memproses penarikan"
+// @Router /leave-requests/{id}/withdrawal [put]
+func (h *LeaveRequestHandler) ResolveLeaveWithdrawal(c *fiber.Ctx) error {
+	reqID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID tidak valid"})
+	}
+
+	var payload models.LeaveWithdrawalDecisionPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload tidak valid"})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	request, err := h.leaveRepo.FindByID(reqID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mencari pengajuan: %v", err)})
+	}
+	if request == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Pengajuan tidak ditemukan"})
+	}
+	if request.Status != "approved" || !request.WithdrawalRequested {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Pengajuan ini tidak memiliki permintaan penarikan yang menunggu."})
+	}
+
+	approved := payload.Decision == "approved"
+	var removed, restored int64
+	if approved {
+		wib, _ := time.LoadLocation("Asia/Jakarta")
+		today := time.Now().In(wib).Format("2006-01-02")
+		if request.StartDate <= today {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tanggal pengajuan sudah berjalan, penarikan tidak dapat disetujui."})
+		}
+	}
+
+	// Pengembalian absensi dan perubahan status penarikan disimpan dalam satu transaksi.
+	err = config.WithTransaction(c.Context(), func(sessCtx mongo.SessionContext) error {
+		removed, restored = 0, 0
+		if approved {
+			var err error
+			removed, restored, err = h.attendanceRepo.RevertLeaveAttendances(sessCtx, reqID)
+			if err != nil {
+				return err
 			}
 		}
+
+		result, err := h.leaveRepo.ResolveWithdrawal(sessCtx, reqID, approved, payload.Note)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return errLeaveRequestChanged
+		}
+		return nil
+	})
+	if errors.Is(err, errLeaveRequestChanged) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Status pengajuan berubah, silakan muat ulang data."})
+	}
+	if err != nil {
+		log.Printf("ERROR: Gagal memproses penarikan pengajuan %s: %v", reqID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses penarikan. Tidak ada perubahan yang disimpan."})
 	}
 
+	h.recordLeaveAudit(c, models.AuditActionLeaveWithdrawalResolve, request)
+
+	message := "Penarikan ditolak, pengajuan tetap disetujui"
+	if approved {
+		message = "Penarikan disetujui, pengajuan ditarik"
+	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Status pengajuan berhasil diperbarui",
+		"message":              message,
+		"removed_attendances":  removed,
+		"restored_attendances": restored,
+	})
+}
+
+// recordLeaveAudit mencatat perubahan pengajuan dengan membandingkan before dengan keadaan
+// pengajuan saat ini di database.
+func (h *LeaveRequestHandler) recordLeaveAudit(c *fiber.Ctx, action string, before *models.LeaveRequest) {
+	after, err := h.leaveRepo.FindByID(before.ID)
+	if err != nil {
+		log.Printf("ERROR: Gagal membaca pengajuan %s untuk audit log: %v", before.ID.Hex(), err)
+	}
+	recordAudit(c, action, models.AuditTargetLeaveRequest, before.ID.Hex(), before, after)
+}
+
+// recordLeaveActivity mencatat aktivitas pengajuan ke feed aktivitas atas nama pemilik pengajuan.
+func (h *LeaveRequestHandler) recordLeaveActivity(ctx context.Context, activityType string, request *models.LeaveRequest, actorID *primitive.ObjectID, message string) {
+	recordActivity(ctx, h.activityRepo, models.Activity{
+		Type:        activityType,
+		UserID:      request.UserID,
+		ActorID:     actorID,
+		ReferenceID: &request.ID,
+		Message:     message,
 	})
 }
 
+// leaveActivitySubject menjelaskan pengajuan untuk pesan aktivitas, contoh: "Cuti tanggal 2024-05-01".
+func leaveActivitySubject(request *models.LeaveRequest) string {
+	if request.EndDate == "" || request.EndDate == request.StartDate {
+		return fmt.Sprintf("%s tanggal %s", request.RequestType, request.StartDate)
+	}
+	return fmt.Sprintf("%s tanggal %s s/d %s", request.RequestType, request.StartDate, request.EndDate)
+}
+
 // GetLeaveSummary godoc
 // @Summary Get Leave Request Summary for current user
 // @Description Mengambil ringkasan jumlah pengajuan cuti (per bulan dan per tahun) untuk karyawan yang sedang login.
@@ -505,17 +1595,31 @@ func (h *LeaveRequestHandler) GetLeaveSummary(c *fiber.Ctx) error {
 	}
 
 	currentYear := time.Now().Year()
-	monthlyCount := int64(0) 
+	monthlyCount := int64(0)
 	annualCount, err := h.leaveRepo.CountByUserIDYearAndType(c.Context(), claims.UserID, currentYear, "Cuti")
 	if err != nil {
 		log.Printf("ERROR: Gagal menghitung cuti tahunan untuk user %s: %v", claims.UserID.Hex(), err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil ringkasan cuti tahunan."})
 	}
 
+	usedDays, err := h.leaveRepo.SumLeaveDaysByUserIDYearAndType(c.Context(), claims.UserID, currentYear, "Cuti")
+	if err != nil {
+		log.Printf("ERROR: Gagal menghitung saldo cuti tahunan untuk user %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil ringkasan cuti tahunan."})
+	}
+
+	remainingDays := models.AnnualLeaveDaysLimit - usedDays
+	if remainingDays < 0 {
+		remainingDays = 0
+	}
+
 	response := models.LeaveSummaryResponse{
-		CurrentMonthLeaveCount: monthlyCount, 
+		CurrentMonthLeaveCount: monthlyCount,
 		AnnualLeaveCount:       annualCount,
+		AnnualLeaveDaysUsed:    usedDays,
+		AnnualLeaveDaysLimit:   models.AnnualLeaveDaysLimit,
+		RemainingLeaveDays:     remainingDays,
 	}
 
 	return c.Status(fiber.StatusOK).JSON(response)
-}
\ No newline at end of file
+}
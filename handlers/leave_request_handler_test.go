@@ -0,0 +1,69 @@
+package handlers_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/handlers"
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+// memoryLeaveRepo menyimpan pengajuan berdasarkan ID. Method lain tidak diimplementasikan.
+type memoryLeaveRepo struct {
+	repository.LeaveRequestRepository
+	requests map[primitive.ObjectID]models.LeaveRequest
+}
+
+func (r *memoryLeaveRepo) FindByID(id primitive.ObjectID) (*models.LeaveRequest, error) {
+	request, found := r.requests[id]
+	if !found {
+		return nil, nil
+	}
+	return &request, nil
+}
+
+func TestUploadAttachmentOnlyForOwnRequest(t *testing.T) {
+	owner := primitive.NewObjectID()
+	request := models.LeaveRequest{ID: primitive.NewObjectID(), UserID: owner, RequestType: "Sakit"}
+	h := handlers.NewLeaveRequestHandler(
+		&memoryLeaveRepo{requests: map[primitive.ObjectID]models.LeaveRequest{request.ID: request}},
+		nil, nil, nil, nil, nil, nil, nil, nil,
+	)
+
+	tests := []struct {
+		name       string
+		caller     *models.Claims
+		requestID  primitive.ObjectID
+		wantStatus int
+	}{
+		// Pemilik lolos pemeriksaan kepemilikan; request tanpa file lalu ditolak 400.
+		{"pemilik pengajuan", &models.Claims{UserID: owner, Role: models.RoleKaryawan}, request.ID, fiber.StatusBadRequest},
+		{"pengajuan milik user lain", &models.Claims{UserID: primitive.NewObjectID(), Role: models.RoleKaryawan}, request.ID, fiber.StatusForbidden},
+		{"pengajuan tidak ada", &models.Claims{UserID: owner, Role: models.RoleKaryawan}, primitive.NewObjectID(), fiber.StatusNotFound},
+		{"tanpa user login (API key)", nil, request.ID, fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post("/leave-requests/:id/attachment", func(c *fiber.Ctx) error {
+				if tt.caller != nil {
+					c.Locals("user", tt.caller)
+				}
+				return c.Next()
+			}, h.UploadAttachment)
+
+			resp, err := app.Test(httptest.NewRequest("POST", "/leave-requests/"+tt.requestID.Hex()+"/attachment", nil), 10000)
+			if err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+type ManagerHandler struct {
+	userRepo       *repository.UserRepository
+	deptRepo       repository.DepartmentRepository
+	attendanceRepo repository.AttendanceRepository
+	leaveRepo      repository.LeaveRequestRepository
+	roleRepo       repository.RoleRepository
+}
+
+func NewManagerHandler(
+	userRepo *repository.UserRepository,
+	deptRepo repository.DepartmentRepository,
+	attendanceRepo repository.AttendanceRepository,
+	leaveRepo repository.LeaveRequestRepository,
+	roleRepo repository.RoleRepository,
+) *ManagerHandler {
+	return &ManagerHandler{
+		userRepo:       userRepo,
+		deptRepo:       deptRepo,
+		attendanceRepo: attendanceRepo,
+		leaveRepo:      leaveRepo,
+		roleRepo:       roleRepo,
+	}
+}
+
+// findTeamMembers mengembalikan anggota tim seorang manager: bawahan langsung (manager_id)
+// ditambah seluruh anggota departemen yang ia kepalai.
+func findTeamMembers(ctx context.Context, userRepo *repository.UserRepository, deptRepo repository.DepartmentRepository, managerID primitive.ObjectID) ([]models.User, error) {
+	departments, err := deptRepo.FindDepartmentsByHead(ctx, managerID)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(departments))
+	for _, dept := range departments {
+		names = append(names, dept.Name)
+	}
+	return userRepo.FindTeamMembers(ctx, managerID, names)
+}
+
+// isTeamMember memeriksa apakah userID termasuk dalam tim managerID.
+func isTeamMember(ctx context.Context, userRepo *repository.UserRepository, deptRepo repository.DepartmentRepository, managerID, userID primitive.ObjectID) (bool, error) {
+	members, err := findTeamMembers(ctx, userRepo, deptRepo, managerID)
+	if err != nil {
+		return false, err
+	}
+	for _, member := range members {
+		if member.ID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func teamMemberIDs(members []models.User) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.ID)
+	}
+	return ids
+}
+
+// GetMyTeam godoc
+// @Summary Get My Team
+// @Description Mengambil daftar anggota tim manager (bawahan langsung dan anggota departemen yang dikepalai), tanpa data gaji
+// @Tags Manager
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.TeamMember "Daftar anggota tim"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 403 {object} object{error=string} "Akses ditolak"
+// @Failure 500 {object} object{error=string} "Gagal mengambil data tim"
+// @Router /manager/team [get]
+func (h *ManagerHandler) GetMyTeam(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	members, err := findTeamMembers(ctx, h.userRepo, h.deptRepo, claims.UserID)
+	if err != nil {
+		log.Printf("ERROR: Gagal mengambil tim manager %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data tim"})
+	}
+
+	team := make([]models.TeamMember, 0, len(members))
+	for i := range members {
+		team = append(team, members[i].ToTeamMember())
+	}
+	return c.Status(fiber.StatusOK).JSON(team)
+}
+
+// GetTeamAttendanceHistory godoc
+// @Summary Get Team Attendance History
+// @Description Mengambil riwayat kehadiran anggota tim dengan filter dan pagination (manager)
+// @Tags Manager
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 10, max: 100)"
+// @Param user_id query string false "Filter by User ID (harus anggota tim)"
+// @Param start_date query string false "Filter by Start Date (YYYY-MM-DD)"
+// @Param end_date query string false "Filter by End Date (YYYY-MM-DD)"
+// @Success 200 {object} object{data=[]models.AttendanceWithUser,total=int,page=int,limit=int} "Riwayat kehadiran tim berhasil diambil"
+// @Failure 400 {object} object{error=string} "Invalid parameters"
+// @Failure 403 {object} object{error=string} "User bukan anggota tim"
+// @Failure 500 {object} object{error=string} "Internal server error"
+// @Router /manager/attendance/history [get]
+func (h *ManagerHandler) GetTeamAttendanceHistory(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi"})
+	}
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	userIDParam := c.Query("user_id", "")
+	startDateStr := c.Query("start_date", "")
+	endDateStr := c.Query("end_date", "")
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	members, err := findTeamMembers(ctx, h.userRepo, h.deptRepo, claims.UserID)
+	if err != nil {
+		log.Printf("ERROR: Gagal mengambil tim manager %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data tim"})
+	}
+	ids := teamMemberIDs(members)
+
+	filter := bson.M{"user_id": bson.M{"$in": ids}}
+	if userIDParam != "" {
+		objID, err := primitive.ObjectIDFromHex(userIDParam)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format User ID tidak valid."})
+		}
+		inTeam := false
+		for _, id := range ids {
+			if id == objID {
+				inTeam = true
+				break
+			}
+		}
+		if !inTeam {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak. User tersebut bukan anggota tim Anda."})
+		}
+		filter["user_id"] = objID
+	}
+
+	if startDateStr != "" && endDateStr != "" {
+		filter["date"] = bson.M{"$gte": startDateStr, "$lte": endDateStr}
+	} else if startDateStr != "" {
+		filter["date"] = bson.M{"$gte": startDateStr}
+	} else if endDateStr != "" {
+		filter["date"] = bson.M{"$lte": endDateStr}
+	}
+
+	attendances, total, err := h.attendanceRepo.GetAllAttendancesWithUserDetails(ctx, filter, int64(page), int64(limit))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil riwayat kehadiran: " + err.Error()})
+	}
+	if attendances == nil {
+		attendances = []models.AttendanceWithUser{}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":  attendances,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// GetTeamLeaveRequests godoc
+// @Summary Get Team Leave Requests
+// @Description Mengambil pengajuan cuti/sakit anggota tim (manager)
+// @Tags Manager
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Filter by status (pending, approved, rejected, cancelled, withdrawn)"
+// @Success 200 {array} models.LeaveRequestWithUser "Daftar pengajuan tim"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 500 {object} object{error=string} "Gagal mengambil data pengajuan"
+// @Router /manager/leave-requests [get]
+func (h *ManagerHandler) GetTeamLeaveRequests(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	members, err := findTeamMembers(ctx, h.userRepo, h.deptRepo, claims.UserID)
+	if err != nil {
+		log.Printf("ERROR: Gagal mengambil tim manager %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data tim"})
+	}
+	if len(members) == 0 {
+		return c.Status(fiber.StatusOK).JSON([]models.LeaveRequestWithUser{})
+	}
+
+	requests, err := h.leaveRepo.FindByUserIDs(ctx, teamMemberIDs(members), c.Query("status", ""))
+	if err != nil {
+		log.Printf("ERROR: Gagal mengambil pengajuan tim manager %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data pengajuan"})
+	}
+	return c.Status(fiber.StatusOK).JSON(requests)
+}
+
+// GetTeamDashboard godoc
+// @Summary Get Team Dashboard
+// @Description Ringkasan tim untuk manager: jumlah anggota, kehadiran hari ini per status, anggota yang cuti, dan pengajuan yang menunggu persetujuan
+// @Tags Manager
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.TeamDashboardStats "Ringkasan tim"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 500 {object} object{error=string} "Gagal mengambil data dashboard tim"
+// @Router /manager/dashboard [get]
+func (h *ManagerHandler) GetTeamDashboard(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	members, err := findTeamMembers(ctx, h.userRepo, h.deptRepo, claims.UserID)
+	if err != nil {
+		log.Printf("ERROR: Gagal mengambil tim manager %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data dashboard tim"})
+	}
+
+	stats := models.TeamDashboardStats{
+		TotalAnggota:     int64(len(members)),
+		KehadiranHariIni: map[string]int64{},
+	}
+
+	canApproveAll, err := h.roleRepo.HasPermission(ctx, claims.Role, models.PermissionLeaveApprove)
+	if err != nil {
+		log.Printf("ERROR: Gagal memeriksa izin role %s: %v", claims.Role, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data dashboard tim"})
+	}
+	pending, err := h.leaveRepo.FindPendingForApprover(ctx, []primitive.ObjectID{claims.UserID}, []string{claims.Role}, canApproveAll)
+	if err != nil {
+		log.Printf("ERROR: Gagal menghitung pengajuan menunggu untuk %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data dashboard tim"})
+	}
+	stats.PengajuanMenungguSaya = int64(len(pending))
+
+	if len(members) == 0 {
+		return c.Status(fiber.StatusOK).JSON(stats)
+	}
+
+	wib, _ := time.LoadLocation("Asia/Jakarta")
+	today := time.Now().In(wib).Format("2006-01-02")
+	ids := teamMemberIDs(members)
+
+	counts, err := h.attendanceRepo.CountByStatusForUsersAndDate(ctx, ids, today)
+	if err != nil {
+		log.Printf("ERROR: Gagal menghitung kehadiran tim %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data dashboard tim"})
+	}
+	var recorded int64
+	for status, count := range counts {
+		stats.KehadiranHariIni[status] = count
+		recorded += count
+	}
+	stats.BelumAbsen = stats.TotalAnggota - recorded
+
+	onLeave, err := h.leaveRepo.CountApprovedOnDateForUsers(ctx, ids, today)
+	if err != nil {
+		log.Printf("ERROR: Gagal menghitung anggota tim yang cuti %s: %v", claims.UserID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil data dashboard tim"})
+	}
+	stats.CutiHariIni = onLeave
+
+	return c.Status(fiber.StatusOK).JSON(stats)
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/paseto"
+	util "Sistem-Manajemen-Karyawan/pkg/utils"
+)
+
+// oidcStateDuration adalah batas waktu user menyelesaikan login di identity provider.
+const oidcStateDuration = 10 * time.Minute
+
+// passwordLoginDisabled mengembalikan true jika domain email wajib login lewat SSO
+// (PASSWORD_LOGIN_DISABLED_DOMAINS).
+func (h *AuthHandler) passwordLoginDisabled(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, d := range h.passwordLoginDisabledDomains {
+		if strings.ToLower(d) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// StartOIDCLogin godoc
+// @Summary Start SSO Login
+// @Description Memulai login SSO OpenID Connect. Mengembalikan URL halaman login identity provider; setelah login, IdP mengarahkan browser ke OIDC_REDIRECT_URL dengan query code dan state yang harus dikirim ke /auth/oidc/callback. Dengan ?redirect=true, server langsung mengarahkan browser ke IdP.
+// @Tags Auth
+// @Produce json
+// @Param redirect query bool false "Langsung redirect ke identity provider"
+// @Success 200 {object} object{authorization_url=string,expires_at=string} "URL login identity provider"
+// @Success 302 "Redirect ke identity provider"
+// @Failure 404 {object} object{error=string} "Login SSO tidak diaktifkan"
+// @Failure 502 {object} object{error=string} "Identity provider tidak dapat dihubungi"
+// @Router /auth/oidc/login [get]
+func (h *AuthHandler) StartOIDCLogin(c *fiber.Ctx) error {
+	if h.oidcProvider == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Login SSO tidak diaktifkan"})
+	}
+
+	state, err := paseto.NewRandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memulai login SSO"})
+	}
+	nonce, err := paseto.NewRandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memulai login SSO"})
+	}
+	codeVerifier, err := paseto.NewRandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memulai login SSO"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	authURL, err := h.oidcProvider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		log.Printf("ERROR: Gagal membuat URL login SSO: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "Identity provider tidak dapat dihubungi"})
+	}
+
+	loginState := &models.OIDCLoginState{
+		StateHash:    paseto.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcStateDuration),
+	}
+	if _, err := h.oidcStateRepo.Create(ctx, loginState); err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memulai login SSO"})
+	}
+
+	if c.QueryBool("redirect", false) {
+		return c.Redirect(authURL, fiber.StatusFound)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"authorization_url": authURL,
+		"expires_at":        loginState.ExpiresAt,
+	})
+}
+
+// OIDCCallback godoc
+// @Summary Complete SSO Login
+// @Description Menyelesaikan login SSO: menukar code dari identity provider, mencocokkan klaim email dengan user yang terdaftar, lalu mengembalikan token seperti /auth/login (termasuk tantangan 2FA jika user memakai 2FA).
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body models.OIDCCallbackPayload true "Code dan state dari identity provider"
+// @Success 200 {object} object{message=string,token=string,token_expires_at=string,refresh_token=string,refresh_token_expires_at=string,user=models.User,two_factor_required=bool,challenge_token=string} "Login berhasil, atau tantangan 2FA jika user memakai 2FA"
+// @Failure 400 {object} object{error=string} "State tidak valid atau kedaluwarsa"
+// @Failure 401 {object} object{error=string} "Login SSO ditolak atau email tidak terdaftar"
+// @Failure 403 {object} object{error=string} "Akun tidak aktif"
+// @Failure 404 {object} object{error=string} "Login SSO tidak diaktifkan"
+// @Failure 423 {object} object{error=string,locked_until=string} "Akun dikunci sementara"
+// @Router /auth/oidc/callback [post]
+func (h *AuthHandler) OIDCCallback(c *fiber.Ctx) error {
+	if h.oidcProvider == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Login SSO tidak diaktifkan"})
+	}
+
+	var payload models.OIDCCallbackPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body", "details": err.Error()})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 15*time.Second)
+	defer cancel()
+
+	loginState, err := h.oidcStateRepo.Consume(ctx, paseto.HashToken(payload.State))
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error internal server"})
+	}
+	if loginState == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "State login SSO tidak valid atau kedaluwarsa. Silakan mulai login ulang."})
+	}
+
+	idToken, err := h.oidcProvider.Exchange(ctx, payload.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		log.Printf("WARNING: Login SSO ditolak: %v", err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Login SSO gagal diverifikasi"})
+	}
+
+	event := models.SecurityEvent{
+		Email:     idToken.Email,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		Detail:    fmt.Sprintf("SSO (sub %s)", idToken.Subject),
+	}
+
+	if idToken.Email == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Identity provider tidak mengirim klaim email"})
+	}
+	if idToken.EmailVerified != nil && !*idToken.EmailVerified {
+		event.Type = models.SecurityEventLoginFailed
+		event.Reason = models.LoginFailureSSOUnverified
+		recordSecurityEvent(ctx, h.securityRepo, event)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Email belum diverifikasi di identity provider"})
+	}
+
+	user, err := h.userRepo.FindUserByEmail(ctx, idToken.Email)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error internal server"})
+	}
+	if user == nil {
+		event.Type = models.SecurityEventLoginFailed
+		event.Reason = models.LoginFailureSSOUnknownEmail
+		recordSecurityEvent(ctx, h.securityRepo, event)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Email tidak terdaftar sebagai karyawan. Hubungi admin."})
+	}
+	event.UserID = &user.ID
+
+	if user.EmploymentStatus == models.EmploymentStatusInactive {
+		event.Type = models.SecurityEventLoginFailed
+		event.Reason = models.LoginFailureAccountInactive
+		recordSecurityEvent(ctx, h.securityRepo, event)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": errAccountInactive})
+	}
+
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		event.Type = models.SecurityEventLoginFailed
+		event.Reason = models.LoginFailureAccountLocked
+		recordSecurityEvent(ctx, h.securityRepo, event)
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error":        "Akun dikunci sementara karena terlalu banyak percobaan login gagal.",
+			"locked_until": user.LockedUntil,
+		})
+	}
+
+	// User di domain khusus SSO tidak pernah memakai password awal dari admin, sehingga kewajiban
+	// mengganti password awal tidak berlaku.
+	if user.IsFirstLogin && h.passwordLoginDisabled(user.Email) {
+		if err := h.userRepo.UpdateUserFirstLoginStatus(ctx, user.ID, false); err != nil {
+			log.Printf("ERROR: Gagal memperbarui status login pertama user %s: %v", user.ID.Hex(), err)
+		} else {
+			user.IsFirstLogin = false
+		}
+	}
+
+	// 2FA lokal tetap berlaku untuk login SSO agar tidak menjadi jalan pintas melewati 2FA.
+	if user.TwoFactorEnabled {
+		return h.startTwoFactorChallenge(ctx, c, user)
+	}
+
+	event.Type = models.SecurityEventLoginSuccess
+	recordSecurityEvent(ctx, h.securityRepo, event)
+
+	return h.respondLoginSuccess(ctx, c, user)
+}
@@ -0,0 +1,367 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/handlers"
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/oidc"
+	"Sistem-Manajemen-Karyawan/pkg/oidc/oidctest"
+	"Sistem-Manajemen-Karyawan/pkg/paseto"
+	"Sistem-Manajemen-Karyawan/pkg/password"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+const oidcTestRedirectURL = "https://karyawan.example.com/sso/callback"
+
+// Repository palsu menyimpan data di memori. Method yang tidak dipakai login SSO tidak
+// diimplementasikan (memanggilnya akan panic karena interface yang di-embed bernilai nil).
+
+type memoryOIDCStateRepo struct {
+	mu     sync.Mutex
+	states map[string]models.OIDCLoginState
+}
+
+func (r *memoryOIDCStateRepo) Create(ctx context.Context, state *models.OIDCLoginState) (*mongo.InsertOneResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state.ID = primitive.NewObjectID()
+	r.states[state.StateHash] = *state
+	return &mongo.InsertOneResult{InsertedID: state.ID}, nil
+}
+
+func (r *memoryOIDCStateRepo) Consume(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, found := r.states[stateHash]
+	delete(r.states, stateHash)
+	if !found || time.Now().After(state.ExpiresAt) {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+type memoryTokenRepo struct {
+	repository.TokenRepository
+	sessions      []models.Session
+	refreshTokens []models.RefreshToken
+}
+
+func (r *memoryTokenRepo) CreateSession(ctx context.Context, session *models.Session) (*mongo.InsertOneResult, error) {
+	r.sessions = append(r.sessions, *session)
+	return &mongo.InsertOneResult{}, nil
+}
+
+func (r *memoryTokenRepo) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) (*mongo.InsertOneResult, error) {
+	r.refreshTokens = append(r.refreshTokens, *token)
+	return &mongo.InsertOneResult{InsertedID: token.ID}, nil
+}
+
+type memorySecurityRepo struct {
+	repository.SecurityEventRepository
+	events []models.SecurityEvent
+}
+
+func (r *memorySecurityRepo) Create(ctx context.Context, event *models.SecurityEvent) (*mongo.InsertOneResult, error) {
+	r.events = append(r.events, *event)
+	return &mongo.InsertOneResult{}, nil
+}
+
+type memoryTwoFactorRepo struct {
+	repository.TwoFactorRepository
+	challenges []models.TwoFactorChallenge
+}
+
+func (r *memoryTwoFactorRepo) CreateChallenge(ctx context.Context, challenge *models.TwoFactorChallenge) (*mongo.InsertOneResult, error) {
+	r.challenges = append(r.challenges, *challenge)
+	return &mongo.InsertOneResult{}, nil
+}
+
+type oidcTestEnv struct {
+	idp       *oidctest.IdP
+	app       *fiber.App
+	tokens    *memoryTokenRepo
+	security  *memorySecurityRepo
+	twoFactor *memoryTwoFactorRepo
+	maker     *paseto.PasetoMaker
+}
+
+// newOIDCTestEnv menyiapkan AuthHandler dengan identity provider palsu. UserRepository memakai
+// client MongoDB dari mt sehingga respons database diatur lewat mt.AddMockResponses.
+func newOIDCTestEnv(mt *mtest.T, ssoOnlyDomains []string) *oidcTestEnv {
+	mt.Helper()
+
+	idp, err := oidctest.NewIdP("sistem-karyawan", "rahasia", oidcTestRedirectURL)
+	if err != nil {
+		mt.Fatalf("NewIdP: %v", err)
+	}
+	mt.Cleanup(idp.Close)
+
+	maker, err := paseto.NewPasetoMaker(&paseto.Keyring{
+		PrimaryKeyID: "test",
+		Keys:         map[string][]byte{"test": bytes.Repeat([]byte{7}, 32)},
+	})
+	if err != nil {
+		mt.Fatalf("NewPasetoMaker: %v", err)
+	}
+
+	previousConn := config.MongoConn
+	config.MongoConn = mt.Client
+	mt.Cleanup(func() { config.MongoConn = previousConn })
+
+	env := &oidcTestEnv{
+		idp:       idp,
+		tokens:    &memoryTokenRepo{},
+		security:  &memorySecurityRepo{},
+		twoFactor: &memoryTwoFactorRepo{},
+		maker:     maker,
+	}
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:    idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  oidcTestRedirectURL,
+	})
+	h := handlers.NewAuthHandler(
+		repository.NewUserRepository(), env.tokens, env.security, env.twoFactor, nil, maker,
+		"Sistem Karyawan", nil,
+		provider, &memoryOIDCStateRepo{states: map[string]models.OIDCLoginState{}},
+		ssoOnlyDomains, password.DefaultPolicy(), nil,
+	)
+
+	env.app = fiber.New()
+	env.app.Get("/auth/oidc/login", h.StartOIDCLogin)
+	env.app.Post("/auth/oidc/callback", h.OIDCCallback)
+	return env
+}
+
+// login memulai login SSO, mensimulasikan user login di IdP dengan claims, lalu memanggil
+// callback. Mengembalikan status, body respons, dan state yang dipakai.
+func (env *oidcTestEnv) login(t testing.TB, claims oidctest.Claims) (int, map[string]interface{}, string) {
+	t.Helper()
+
+	status, body := env.request(t, "GET", "/auth/oidc/login", nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("StartOIDCLogin status = %d, body = %v", status, body)
+	}
+	authURL, _ := body["authorization_url"].(string)
+	code, state, err := env.idp.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	status, body = env.callback(t, code, state)
+	return status, body, state
+}
+
+func (env *oidcTestEnv) callback(t testing.TB, code, state string) (int, map[string]interface{}) {
+	t.Helper()
+	return env.request(t, "POST", "/auth/oidc/callback", models.OIDCCallbackPayload{Code: code, State: state})
+}
+
+func (env *oidcTestEnv) request(t testing.TB, method, path string, payload interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var reqBody io.Reader
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("json.Marshal: %v", err)
+		}
+		reqBody = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reqBody)
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	resp, err := env.app.Test(req, 10000)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("respons bukan JSON: %v", err)
+	}
+	return resp.StatusCode, body
+}
+
+// userFound adalah respons find MongoDB yang berisi satu user.
+func userFound(mt *mtest.T, user models.User) bson.D {
+	mt.Helper()
+	raw, err := bson.Marshal(user)
+	if err != nil {
+		mt.Fatalf("bson.Marshal: %v", err)
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		mt.Fatalf("bson.Unmarshal: %v", err)
+	}
+	return mtest.CreateCursorResponse(0, config.DBName+"."+config.UserCollection, mtest.FirstBatch, doc)
+}
+
+// userNotFound adalah respons find MongoDB tanpa dokumen.
+func userNotFound() bson.D {
+	return mtest.CreateCursorResponse(0, config.DBName+"."+config.UserCollection, mtest.FirstBatch)
+}
+
+func TestOIDCCallback(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("email terdaftar ditautkan ke user dan mendapat token", func(mt *mtest.T) {
+		env := newOIDCTestEnv(mt, nil)
+		user := models.User{ID: primitive.NewObjectID(), Name: "Budi", Email: "budi@example.com", Role: "karyawan", CreatedAt: time.Now()}
+		mt.AddMockResponses(userFound(mt, user))
+
+		status, body, state := env.login(mt, oidctest.Claims{"sub": "idp-budi", "email": user.Email, "email_verified": true})
+		if status != fiber.StatusOK {
+			mt.Fatalf("status = %d, body = %v", status, body)
+		}
+
+		claims, err := env.maker.ValidateToken(body["token"].(string))
+		if err != nil {
+			mt.Fatalf("token tidak valid: %v", err)
+		}
+		if claims.UserID != user.ID {
+			mt.Errorf("token untuk user %s, want %s", claims.UserID.Hex(), user.ID.Hex())
+		}
+		if got := body["user"].(map[string]interface{})["id"]; got != user.ID.Hex() {
+			mt.Errorf("user.id = %v, want %s", got, user.ID.Hex())
+		}
+		if len(env.tokens.sessions) != 1 || env.tokens.sessions[0].UserID != user.ID {
+			mt.Errorf("sesi = %+v, want satu sesi untuk user", env.tokens.sessions)
+		}
+		if len(env.tokens.refreshTokens) != 1 || env.tokens.refreshTokens[0].UserID != user.ID {
+			mt.Errorf("refresh token = %+v, want satu refresh token untuk user", env.tokens.refreshTokens)
+		}
+		if len(env.security.events) != 1 {
+			mt.Fatalf("kejadian keamanan = %+v, want 1", env.security.events)
+		}
+		event := env.security.events[0]
+		if event.Type != models.SecurityEventLoginSuccess || event.UserID == nil || *event.UserID != user.ID {
+			mt.Errorf("kejadian keamanan = %+v, want login_success untuk user", event)
+		}
+
+		// State hanya berlaku sekali.
+		if status, body := env.callback(mt, "code-lain", state); status != fiber.StatusBadRequest {
+			mt.Errorf("callback ulang status = %d, body = %v, want 400", status, body)
+		}
+	})
+
+	mt.Run("email tidak terdaftar ditolak", func(mt *mtest.T) {
+		env := newOIDCTestEnv(mt, nil)
+		mt.AddMockResponses(userNotFound())
+
+		status, body, _ := env.login(mt, oidctest.Claims{"sub": "idp-asing", "email": "asing@example.com", "email_verified": true})
+		if status != fiber.StatusUnauthorized {
+			mt.Fatalf("status = %d, body = %v, want 401", status, body)
+		}
+		if _, ok := body["token"]; ok {
+			mt.Error("respons berisi token")
+		}
+		if len(env.tokens.sessions) != 0 {
+			mt.Errorf("sesi dibuat untuk email yang tidak terdaftar: %+v", env.tokens.sessions)
+		}
+		if len(env.security.events) != 1 || env.security.events[0].Reason != models.LoginFailureSSOUnknownEmail {
+			mt.Errorf("kejadian keamanan = %+v, want %s", env.security.events, models.LoginFailureSSOUnknownEmail)
+		}
+	})
+
+	mt.Run("user tidak aktif ditolak", func(mt *mtest.T) {
+		env := newOIDCTestEnv(mt, nil)
+		user := models.User{ID: primitive.NewObjectID(), Name: "Dewi", Email: "dewi@example.com", Role: "karyawan", EmploymentStatus: models.EmploymentStatusInactive, CreatedAt: time.Now()}
+		mt.AddMockResponses(userFound(mt, user))
+
+		status, body, _ := env.login(mt, oidctest.Claims{"sub": "idp-dewi", "email": user.Email, "email_verified": true})
+		if status != fiber.StatusForbidden {
+			mt.Fatalf("status = %d, body = %v, want 403", status, body)
+		}
+		if len(env.tokens.sessions) != 0 {
+			mt.Errorf("sesi dibuat untuk user tidak aktif: %+v", env.tokens.sessions)
+		}
+		if len(env.security.events) != 1 || env.security.events[0].Reason != models.LoginFailureAccountInactive {
+			mt.Errorf("kejadian keamanan = %+v, want %s", env.security.events, models.LoginFailureAccountInactive)
+		}
+	})
+
+	mt.Run("email belum diverifikasi ditolak tanpa mencari user", func(mt *mtest.T) {
+		env := newOIDCTestEnv(mt, nil)
+
+		status, body, _ := env.login(mt, oidctest.Claims{"sub": "idp-budi", "email": "budi@example.com", "email_verified": false})
+		if status != fiber.StatusUnauthorized {
+			mt.Fatalf("status = %d, body = %v, want 401", status, body)
+		}
+		if len(env.security.events) != 1 || env.security.events[0].Reason != models.LoginFailureSSOUnverified {
+			mt.Errorf("kejadian keamanan = %+v, want %s", env.security.events, models.LoginFailureSSOUnverified)
+		}
+	})
+
+	mt.Run("ID token tidak valid ditolak", func(mt *mtest.T) {
+		env := newOIDCTestEnv(mt, nil)
+		env.idp.IssueIDToken = func(c oidctest.Claims) string {
+			c["aud"] = "client-lain"
+			return env.idp.SignIDToken(c)
+		}
+
+		status, body, _ := env.login(mt, oidctest.Claims{"sub": "idp-budi", "email": "budi@example.com"})
+		if status != fiber.StatusUnauthorized {
+			mt.Fatalf("status = %d, body = %v, want 401", status, body)
+		}
+		if len(env.tokens.sessions) != 0 {
+			mt.Errorf("sesi dibuat dari ID token yang tidak valid: %+v", env.tokens.sessions)
+		}
+	})
+
+	mt.Run("user domain SSO tidak wajib mengganti password awal", func(mt *mtest.T) {
+		env := newOIDCTestEnv(mt, []string{"example.com"})
+		user := models.User{ID: primitive.NewObjectID(), Name: "Sari", Email: "sari@example.com", Role: "karyawan", IsFirstLogin: true, CreatedAt: time.Now()}
+		mt.AddMockResponses(userFound(mt, user), mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		status, body, _ := env.login(mt, oidctest.Claims{"sub": "idp-sari", "email": user.Email, "email_verified": true})
+		if status != fiber.StatusOK {
+			mt.Fatalf("status = %d, body = %v", status, body)
+		}
+		if _, ok := body["password_change_required"]; ok {
+			mt.Errorf("password_change_required dikirim untuk user SSO: %v", body)
+		}
+		if first := body["user"].(map[string]interface{})["is_first_login"]; first != false {
+			mt.Errorf("is_first_login = %v, want false", first)
+		}
+	})
+
+	mt.Run("user dengan 2FA mendapat tantangan, bukan token", func(mt *mtest.T) {
+		env := newOIDCTestEnv(mt, nil)
+		user := models.User{ID: primitive.NewObjectID(), Name: "Andi", Email: "andi@example.com", Role: "admin", TwoFactorEnabled: true, CreatedAt: time.Now()}
+		mt.AddMockResponses(userFound(mt, user))
+
+		status, body, _ := env.login(mt, oidctest.Claims{"sub": "idp-andi", "email": user.Email, "email_verified": true})
+		if status != fiber.StatusOK {
+			mt.Fatalf("status = %d, body = %v", status, body)
+		}
+		if body["two_factor_required"] != true || body["challenge_token"] == nil {
+			mt.Errorf("respons = %v, want tantangan 2FA", body)
+		}
+		if _, ok := body["token"]; ok {
+			mt.Error("respons berisi token sebelum 2FA")
+		}
+		if len(env.twoFactor.challenges) != 1 || env.twoFactor.challenges[0].UserID != user.ID {
+			mt.Errorf("tantangan 2FA = %+v, want satu untuk user", env.twoFactor.challenges)
+		}
+		if len(env.tokens.sessions) != 0 {
+			mt.Errorf("sesi dibuat sebelum 2FA: %+v", env.tokens.sessions)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/password"
+)
+
+// passwordPolicyViolations memeriksa password baru user terhadap kebijakan password, termasuk
+// larangan memakai ulang password terakhir.
+func passwordPolicyViolations(policy *password.Policy, user *models.User, newPassword string) []string {
+	violations := policy.Validate(newPassword)
+	if policy.IsReused(newPassword, user.Password, user.PasswordHistory) {
+		violations = append(violations, fmt.Sprintf("Password tidak boleh sama dengan %d password terakhir.", policy.HistorySize))
+	}
+	return violations
+}
+
+func respondPasswordPolicyViolation(c *fiber.Ctx, violations []string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":      "Password tidak memenuhi kebijakan password",
+		"violations": violations,
+	})
+}
+
+// passwordExpired mengembalikan true jika password user melewati umur maksimum kebijakan password.
+// User yang belum pernah mengganti password dihitung dari waktu akun dibuat. Domain khusus SSO
+// tidak memakai password sehingga tidak pernah kedaluwarsa.
+func (h *AuthHandler) passwordExpired(user *models.User) bool {
+	if h.passwordLoginDisabled(user.Email) {
+		return false
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return h.passwordPolicy.IsExpired(changedAt, time.Now())
+}
+
+// GetPasswordPolicy godoc
+// @Summary Get Password Policy
+// @Description Mengambil kebijakan password yang berlaku (panjang, jenis karakter wajib, jumlah riwayat yang tidak boleh dipakai ulang, dan umur maksimum password) untuk ditampilkan di form password
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} password.Policy "Kebijakan password"
+// @Router /auth/password-policy [get]
+func (h *AuthHandler) GetPasswordPolicy(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(h.passwordPolicy)
+}
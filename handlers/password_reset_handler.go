@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/notifier"
+	"Sistem-Manajemen-Karyawan/pkg/paseto"
+	"Sistem-Manajemen-Karyawan/pkg/password"
+	util "Sistem-Manajemen-Karyawan/pkg/utils"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+// Aturan token reset password.
+const (
+	passwordResetTokenDuration = 30 * time.Minute
+	passwordResetMaxPerWindow  = 3 // permintaan per user dalam passwordResetWindow
+	passwordResetWindow        = time.Hour
+)
+
+type PasswordResetHandler struct {
+	userRepo  *repository.UserRepository
+	tokenRepo repository.TokenRepository
+	resetRepo repository.PasswordResetRepository
+	notifier  notifier.Notifier
+	resetURL  string
+	policy    *password.Policy
+}
+
+func NewPasswordResetHandler(
+	userRepo *repository.UserRepository,
+	tokenRepo repository.TokenRepository,
+	resetRepo repository.PasswordResetRepository,
+	n notifier.Notifier,
+	resetURL string,
+	policy *password.Policy,
+) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		resetRepo: resetRepo,
+		notifier:  n,
+		resetURL:  resetURL,
+		policy:    policy,
+	}
+}
+
+// passwordResetRequestedMessage selalu dikembalikan agar endpoint tidak bisa dipakai untuk menebak email terdaftar.
+const passwordResetRequestedMessage = "Jika email terdaftar, instruksi reset password telah dikirim."
+
+// RequestPasswordReset godoc
+// @Summary Request Password Reset
+// @Description Mengirim token reset password sekali pakai (berlaku 30 menit) ke email user. Respons selalu sama, baik email terdaftar maupun tidak.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body models.PasswordResetRequestPayload true "Email akun"
+// @Success 200 {object} object{message=string} "Permintaan diproses"
+// @Failure 400 {object} models.ValidationErrorResponse "Payload tidak valid"
+// @Failure 500 {object} object{error=string} "Gagal memproses permintaan reset password"
+// @Router /auth/password-reset/request [post]
+func (h *PasswordResetHandler) RequestPasswordReset(c *fiber.Ctx) error {
+	var payload models.PasswordResetRequestPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body", "details": err.Error()})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.userRepo.FindUserByEmail(ctx, payload.Email)
+	if err != nil {
+		log.Printf("ERROR: Gagal mencari user untuk reset password: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses permintaan reset password"})
+	}
+	if user == nil {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": passwordResetRequestedMessage})
+	}
+
+	recent, err := h.resetRepo.CountRecentForUser(ctx, user.ID, time.Now().Add(-passwordResetWindow))
+	if err != nil {
+		log.Printf("ERROR: Gagal memeriksa permintaan reset password user %s: %v", user.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses permintaan reset password"})
+	}
+	if recent >= passwordResetMaxPerWindow {
+		log.Printf("WARNING: Permintaan reset password user %s melebihi batas, diabaikan", user.ID.Hex())
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": passwordResetRequestedMessage})
+	}
+
+	token, err := paseto.NewRandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses permintaan reset password"})
+	}
+
+	// Hanya token terbaru yang berlaku.
+	if _, err := h.resetRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		log.Printf("ERROR: Gagal membatalkan token reset lama user %s: %v", user.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses permintaan reset password"})
+	}
+	resetToken := &models.PasswordResetToken{
+		UserID:      user.ID,
+		TokenHash:   paseto.HashToken(token),
+		ExpiresAt:   time.Now().Add(passwordResetTokenDuration),
+		RequestedIP: c.IP(),
+	}
+	if _, err := h.resetRepo.Create(ctx, resetToken); err != nil {
+		log.Printf("ERROR: Gagal menyimpan token reset user %s: %v", user.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memproses permintaan reset password"})
+	}
+
+	err = h.notifier.Send(ctx, notifier.Message{
+		To:      []string{user.Email},
+		Subject: "Reset Password",
+		Body:    h.resetMessageBody(user.Name, token),
+	})
+	if err != nil {
+		// Respons tetap sama agar kegagalan pengiriman tidak membocorkan bahwa email terdaftar.
+		log.Printf("ERROR: Gagal mengirim token reset password ke user %s: %v", user.ID.Hex(), err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": passwordResetRequestedMessage})
+}
+
+func (h *PasswordResetHandler) resetMessageBody(name, token string) string {
+	instruction := fmt.Sprintf("Token reset password Anda: %s", token)
+	if h.resetURL != "" {
+		instruction = fmt.Sprintf("Buka tautan berikut untuk membuat password baru: %s?token=%s", h.resetURL, url.QueryEscape(token))
+	}
+	return fmt.Sprintf("Halo %s,\n\nKami menerima permintaan reset password untuk akun Anda.\n%s\n\nToken berlaku %d menit dan hanya bisa dipakai sekali. Abaikan pesan ini jika Anda tidak memintanya.",
+		name, instruction, int(passwordResetTokenDuration.Minutes()))
+}
+
+// ConfirmPasswordReset godoc
+// @Summary Confirm Password Reset
+// @Description Mengganti password menggunakan token reset. Token hanya bisa dipakai sekali; setelah berhasil semua sesi user diakhiri.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body models.PasswordResetConfirmPayload true "Token reset dan password baru"
+// @Success 200 {object} object{message=string} "Password berhasil direset"
+// @Failure 400 {object} object{error=string,violations=[]string} "Payload tidak valid, token tidak valid/kedaluwarsa, atau password tidak memenuhi kebijakan"
+// @Failure 500 {object} object{error=string} "Gagal mereset password"
+// @Router /auth/password-reset/confirm [post]
+func (h *PasswordResetHandler) ConfirmPasswordReset(c *fiber.Ctx) error {
+	var payload models.PasswordResetConfirmPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body", "details": err.Error()})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	tokenHash := paseto.HashToken(payload.Token)
+	resetToken, err := h.resetRepo.FindActive(ctx, tokenHash)
+	if err != nil {
+		log.Printf("ERROR: Gagal memeriksa token reset password: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mereset password"})
+	}
+	if resetToken == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token reset tidak valid, sudah dipakai, atau telah kedaluwarsa"})
+	}
+
+	user, err := h.userRepo.FindUserByID(ctx, resetToken.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mereset password"})
+	}
+	if user == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token reset tidak valid, sudah dipakai, atau telah kedaluwarsa"})
+	}
+
+	// Token baru dipakai setelah password baru lolos kebijakan, agar user bisa mencoba lagi.
+	if violations := passwordPolicyViolations(h.policy, user, payload.NewPassword); len(violations) > 0 {
+		return respondPasswordPolicyViolation(c, violations)
+	}
+	consumed, err := h.resetRepo.Consume(ctx, tokenHash)
+	if err != nil {
+		log.Printf("ERROR: Gagal memakai token reset password: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mereset password"})
+	}
+	if consumed == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token reset tidak valid, sudah dipakai, atau telah kedaluwarsa"})
+	}
+
+	hashedPassword, err := password.HashPassword(payload.NewPassword)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal hash password baru"})
+	}
+
+	history := h.policy.NextHistory(user.Password, user.PasswordHistory)
+	if err := h.userRepo.UpdateUserPassword(ctx, user.ID, hashedPassword, history); err != nil {
+		log.Printf("ERROR: Gagal menyimpan password baru user %s: %v", user.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mereset password"})
+	}
+	recordAudit(c, models.AuditActionUserPasswordReset, models.AuditTargetUser, user.ID.Hex(), nil, nil)
+
+	// Pemilik email sudah terbukti, jadi penguncian karena login gagal ikut dibuka.
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		if err := h.userRepo.ResetLoginFailures(ctx, user.ID); err != nil {
+			log.Printf("ERROR: Gagal membuka kunci akun user %s setelah reset password: %v", user.ID.Hex(), err)
+		}
+	}
+
+	// Sesi lama (mungkin milik pihak yang mengetahui password lama) diakhiri.
+	if _, err := revokeAllUserTokens(ctx, h.tokenRepo, user.ID); err != nil {
+		log.Printf("ERROR: Password user %s direset tetapi gagal mencabut sesi: %v", user.ID.Hex(), err)
+	}
+
+	err = h.notifier.Send(ctx, notifier.Message{
+		To:      []string{user.Email},
+		Subject: "Password Anda telah diubah",
+		Body:    fmt.Sprintf("Halo %s,\n\nPassword akun Anda baru saja direset. Jika ini bukan Anda, segera hubungi admin.", user.Name),
+	})
+	if err != nil {
+		log.Printf("WARNING: Gagal mengirim notifikasi reset password ke user %s: %v", user.ID.Hex(), err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Password berhasil direset. Silakan login dengan password baru."})
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"Sistem-Manajemen-Karyawan/models"
+	util "Sistem-Manajemen-Karyawan/pkg/utils"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+// roleNamePattern membatasi nama role karena nama disimpan di user dan token.
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{2,49}$`)
+
+type RoleHandler struct {
+	roleRepo repository.RoleRepository
+	userRepo *repository.UserRepository
+}
+
+func NewRoleHandler(roleRepo repository.RoleRepository, userRepo *repository.UserRepository) *RoleHandler {
+	return &RoleHandler{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+	}
+}
+
+// hasPermission memeriksa izin role user untuk pengecekan di dalam handler (misal: data sendiri
+// vs data semua user). Kegagalan membaca role dianggap tidak berizin.
+func hasPermission(ctx context.Context, roleRepo repository.RoleRepository, claims *models.Claims, permission string) bool {
+	if claims.APIKeyID != nil {
+		return claims.HasAPIKeyPermission(permission)
+	}
+	allowed, err := roleRepo.HasPermission(ctx, claims.Role, permission)
+	if err != nil {
+		log.Printf("ERROR: Gagal memeriksa izin %s untuk role %s: %v", permission, claims.Role, err)
+		return false
+	}
+	return allowed
+}
+
+// validatePermissions memastikan semua izin dikenal dan membuang duplikat.
+func validatePermissions(permissions []string) ([]string, error) {
+	seen := make(map[string]bool, len(permissions))
+	result := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		if p == models.PermissionAll {
+			return nil, fmt.Errorf("izin '%s' hanya untuk role admin", models.PermissionAll)
+		}
+		if !models.IsKnownPermission(p) {
+			return nil, fmt.Errorf("izin '%s' tidak dikenal", p)
+		}
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// GetAllPermissions godoc
+// @Summary Get All Permissions
+// @Description Mengambil katalog izin yang dapat diberikan ke role
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.PermissionInfo "Daftar izin"
+// @Router /admin/permissions [get]
+func (h *RoleHandler) GetAllPermissions(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(models.Permissions)
+}
+
+// GetAllRoles godoc
+// @Summary Get All Roles
+// @Description Mengambil semua role beserta izinnya
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Role "Daftar role"
+// @Failure 500 {object} object{error=string} "Gagal mengambil role"
+// @Router /admin/roles [get]
+func (h *RoleHandler) GetAllRoles(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	roles, err := h.roleRepo.FindAll(ctx)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mengambil role: %v", err)})
+	}
+	return c.Status(fiber.StatusOK).JSON(roles)
+}
+
+// CreateRole godoc
+// @Summary Create Role
+// @Description Membuat role kustom dengan daftar izin tertentu. Nama role berupa huruf kecil, angka, dan garis bawah (3-50 karakter) dan tidak bisa diubah setelah dibuat.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param role body models.CreateRolePayload true "Data role"
+// @Success 201 {object} object{message=string,data=models.Role} "Role berhasil dibuat"
+// @Failure 400 {object} object{error=string} "Payload tidak valid"
+// @Failure 409 {object} object{error=string} "Nama role sudah dipakai"
+// @Failure 500 {object} object{error=string} "Gagal membuat role"
+// @Router /admin/roles [post]
+func (h *RoleHandler) CreateRole(c *fiber.Ctx) error {
+	var payload models.CreateRolePayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body", "details": err.Error()})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+	if !roleNamePattern.MatchString(payload.Name) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Nama role hanya boleh berisi huruf kecil, angka, dan garis bawah, diawali huruf"})
+	}
+	permissions, err := validatePermissions(payload.Permissions)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	role := &models.Role{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Permissions: permissions,
+	}
+	if _, err := h.roleRepo.Create(ctx, role); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Nama role sudah dipakai"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal membuat role: %v", err)})
+	}
+	recordAudit(c, models.AuditActionRoleCreate, models.AuditTargetRole, role.ID.Hex(), nil, role)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Role berhasil dibuat",
+		"data":    role,
+	})
+}
+
+// UpdateRole godoc
+// @Summary Update Role
+// @Description Mengubah deskripsi dan/atau daftar izin role. Izin role admin tidak bisa diubah agar selalu ada role dengan akses penuh.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Param role body models.UpdateRolePayload true "Data role"
+// @Success 200 {object} object{message=string} "Role berhasil diupdate"
+// @Failure 400 {object} object{error=string} "Payload tidak valid"
+// @Failure 403 {object} object{error=string} "Izin role admin tidak bisa diubah"
+// @Failure 404 {object} object{error=string} "Role tidak ditemukan"
+// @Failure 500 {object} object{error=string} "Gagal mengupdate role"
+// @Router /admin/roles/{id} [put]
+func (h *RoleHandler) UpdateRole(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format ID role tidak valid"})
+	}
+
+	var payload models.UpdateRolePayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body", "details": err.Error()})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	role, err := h.roleRepo.FindByID(ctx, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mencari role: %v", err)})
+	}
+	if role == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role tidak ditemukan"})
+	}
+
+	update := bson.M{}
+	if payload.Description != nil {
+		update["description"] = *payload.Description
+	}
+	if payload.Permissions != nil {
+		if role.Name == models.RoleAdmin {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Izin role admin tidak bisa diubah"})
+		}
+		permissions, err := validatePermissions(payload.Permissions)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		update["permissions"] = permissions
+	}
+	if len(update) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tidak ada field yang akan diupdate"})
+	}
+
+	if _, err := h.roleRepo.Update(ctx, objID, update); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mengupdate role: %v", err)})
+	}
+	updated, err := h.roleRepo.FindByID(ctx, objID)
+	if err != nil {
+		log.Printf("ERROR: Gagal membaca role %s setelah update untuk audit log: %v", objID.Hex(), err)
+	}
+	recordAudit(c, models.AuditActionRoleUpdate, models.AuditTargetRole, objID.Hex(), role, updated)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Role berhasil diupdate"})
+}
+
+// DeleteRole godoc
+// @Summary Delete Role
+// @Description Menghapus role kustom. Role sistem dan role yang masih dipakai user tidak bisa dihapus.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Success 200 {object} object{message=string} "Role berhasil dihapus"
+// @Failure 400 {object} object{error=string} "Format ID role tidak valid"
+// @Failure 403 {object} object{error=string} "Role sistem tidak bisa dihapus"
+// @Failure 404 {object} object{error=string} "Role tidak ditemukan"
+// @Failure 409 {object} object{error=string,users=int} "Role masih dipakai user"
+// @Failure 500 {object} object{error=string} "Gagal menghapus role"
+// @Router /admin/roles/{id} [delete]
+func (h *RoleHandler) DeleteRole(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format ID role tidak valid"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	role, err := h.roleRepo.FindByID(ctx, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mencari role: %v", err)})
+	}
+	if role == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role tidak ditemukan"})
+	}
+	if role.IsSystem {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Role sistem tidak bisa dihapus"})
+	}
+
+	inUse, err := h.userRepo.CountDocuments(ctx, bson.M{"role": role.Name})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal memeriksa pemakaian role: %v", err)})
+	}
+	if inUse > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Role masih dipakai user. Pindahkan user ke role lain terlebih dahulu.",
+			"users": inUse,
+		})
+	}
+
+	result, err := h.roleRepo.Delete(ctx, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal menghapus role: %v", err)})
+	}
+	if result.DeletedCount == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role tidak ditemukan"})
+	}
+	recordAudit(c, models.AuditActionRoleDelete, models.AuditTargetRole, objID.Hex(), role, nil)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Role berhasil dihapus"})
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+type SecurityHandler struct {
+	userRepo     *repository.UserRepository
+	securityRepo repository.SecurityEventRepository
+}
+
+func NewSecurityHandler(userRepo *repository.UserRepository, securityRepo repository.SecurityEventRepository) *SecurityHandler {
+	return &SecurityHandler{
+		userRepo:     userRepo,
+		securityRepo: securityRepo,
+	}
+}
+
+// recordSecurityEvent menyimpan kejadian keamanan. Kegagalan hanya dicatat di log agar
+// alur utama (misal: login) tidak ikut gagal.
+func recordSecurityEvent(ctx context.Context, securityRepo repository.SecurityEventRepository, event models.SecurityEvent) {
+	if _, err := securityRepo.Create(ctx, &event); err != nil {
+		log.Printf("ERROR: Gagal mencatat kejadian keamanan %s: %v", event.Type, err)
+	}
+}
+
+// GetMySecurityHistory godoc
+// @Summary Get My Security History
+// @Description Mengambil riwayat keamanan akun user yang sedang login (login berhasil/gagal, penguncian akun), terbaru lebih dulu
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "Filter jenis kejadian" Enums(login_success, login_failed, account_locked, account_unlocked, session_revoked)
+// @Param page query int false "Nomor halaman (default: 1)"
+// @Param limit query int false "Jumlah item per halaman (default: 20, maks: 100)"
+// @Success 200 {object} object{data=[]models.SecurityEvent,total=int,page=int,limit=int} "Riwayat keamanan"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 500 {object} object{error=string} "Gagal mengambil riwayat keamanan"
+// @Router /users/security-history [get]
+func (h *SecurityHandler) GetMySecurityHistory(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+	return h.respondSecurityHistory(c, claims.UserID)
+}
+
+// GetUserSecurityHistory godoc
+// @Summary Get User Security History
+// @Description Mengambil riwayat keamanan akun user tertentu, termasuk percobaan login gagal (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param type query string false "Filter jenis kejadian" Enums(login_success, login_failed, account_locked, account_unlocked, session_revoked)
+// @Param page query int false "Nomor halaman (default: 1)"
+// @Param limit query int false "Jumlah item per halaman (default: 20, maks: 100)"
+// @Success 200 {object} object{data=[]models.SecurityEvent,total=int,page=int,limit=int} "Riwayat keamanan"
+// @Failure 400 {object} object{error=string} "Format ID user tidak valid"
+// @Failure 500 {object} object{error=string} "Gagal mengambil riwayat keamanan"
+// @Router /admin/users/{id}/security-history [get]
+func (h *SecurityHandler) GetUserSecurityHistory(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format ID user tidak valid"})
+	}
+	return h.respondSecurityHistory(c, userID)
+}
+
+func (h *SecurityHandler) respondSecurityHistory(c *fiber.Ctx, userID primitive.ObjectID) error {
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	eventType := c.Query("type", "")
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	events, total, err := h.securityRepo.FindByUser(ctx, userID, eventType, int64(page), int64(limit))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mengambil riwayat keamanan: %v", err)})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"data":  events,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// UnlockUser godoc
+// @Summary Unlock User Account
+// @Description Membuka kunci akun yang terkunci karena login gagal berulang dan mereset hitungan login gagal (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} object{message=string} "Akun berhasil dibuka"
+// @Failure 400 {object} object{error=string} "Format ID user tidak valid"
+// @Failure 404 {object} object{error=string} "User tidak ditemukan"
+// @Failure 500 {object} object{error=string} "Gagal membuka kunci akun"
+// @Router /admin/users/{id}/unlock [post]
+func (h *SecurityHandler) UnlockUser(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format ID user tidak valid"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mencari user: %v", err)})
+	}
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user tidak ditemukan"})
+	}
+
+	if err := h.userRepo.ResetLoginFailures(ctx, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "gagal membuka kunci akun"})
+	}
+
+	recordAudit(c, models.AuditActionUserUnlock, models.AuditTargetUser, userID.Hex(),
+		fiber.Map{"failed_login_count": user.FailedLoginCount, "locked_until": user.LockedUntil}, nil)
+	recordSecurityEvent(ctx, h.securityRepo, models.SecurityEvent{
+		UserID:    &userID,
+		Type:      models.SecurityEventAccountUnlocked,
+		IPAddress: c.IP(),
+		ActorID:   &claims.UserID,
+		Detail:    fmt.Sprintf("Dibuka oleh admin %s (sebelumnya %d kali login gagal)", claims.Email, user.FailedLoginCount),
+	})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Akun berhasil dibuka"})
+}
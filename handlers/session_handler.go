@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/repository"
+)
+
+type SessionHandler struct {
+	tokenRepo    repository.TokenRepository
+	userRepo     *repository.UserRepository
+	securityRepo repository.SecurityEventRepository
+}
+
+func NewSessionHandler(tokenRepo repository.TokenRepository, userRepo *repository.UserRepository, securityRepo repository.SecurityEventRepository) *SessionHandler {
+	return &SessionHandler{
+		tokenRepo:    tokenRepo,
+		userRepo:     userRepo,
+		securityRepo: securityRepo,
+	}
+}
+
+// GetMySessions godoc
+// @Summary Get My Active Sessions
+// @Description Mengambil daftar sesi login aktif milik user yang sedang login: perangkat, IP, waktu login, dan waktu terakhir dipakai. Sesi yang dipakai request ini ditandai current=true.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Session "Daftar sesi aktif"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 500 {object} object{error=string} "Gagal mengambil sesi"
+// @Router /users/sessions [get]
+func (h *SessionHandler) GetMySessions(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+	return h.respondSessions(c, claims.UserID, claims.SessionID)
+}
+
+// RevokeMySession godoc
+// @Summary Sign Out a Session
+// @Description Mengakhiri salah satu sesi milik user yang sedang login, misalnya perangkat yang hilang. Access token dan refresh token sesi tersebut langsung tidak berlaku.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} object{message=string} "Sesi berhasil diakhiri"
+// @Failure 400 {object} object{error=string} "Format ID sesi tidak valid"
+// @Failure 404 {object} object{error=string} "Sesi tidak ditemukan"
+// @Failure 500 {object} object{error=string} "Gagal mengakhiri sesi"
+// @Router /users/sessions/{sessionId} [delete]
+func (h *SessionHandler) RevokeMySession(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+	return h.revokeSession(c, claims.UserID, nil)
+}
+
+// GetUserSessions godoc
+// @Summary Get User Active Sessions
+// @Description Mengambil daftar sesi login aktif user tertentu
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {array} models.Session "Daftar sesi aktif"
+// @Failure 400 {object} object{error=string} "Format ID user tidak valid"
+// @Failure 404 {object} object{error=string} "User tidak ditemukan"
+// @Failure 500 {object} object{error=string} "Gagal mengambil sesi"
+// @Router /admin/users/{id}/sessions [get]
+func (h *SessionHandler) GetUserSessions(c *fiber.Ctx) error {
+	userID, err := h.findTargetUser(c)
+	if userID == nil {
+		return err
+	}
+	return h.respondSessions(c, *userID, "")
+}
+
+// RevokeUserSession godoc
+// @Summary Sign Out a User Session
+// @Description Mengakhiri salah satu sesi user tertentu tanpa mengeluarkan user dari perangkat lain
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param sessionId path string true "Session ID"
+// @Success 200 {object} object{message=string} "Sesi berhasil diakhiri"
+// @Failure 400 {object} object{error=string} "Format ID tidak valid"
+// @Failure 404 {object} object{error=string} "User atau sesi tidak ditemukan"
+// @Failure 500 {object} object{error=string} "Gagal mengakhiri sesi"
+// @Router /admin/users/{id}/sessions/{sessionId} [delete]
+func (h *SessionHandler) RevokeUserSession(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+	userID, err := h.findTargetUser(c)
+	if userID == nil {
+		return err
+	}
+	return h.revokeSession(c, *userID, &claims.UserID)
+}
+
+// findTargetUser membaca parameter :id dan memastikan user-nya ada. Jika tidak, respons error
+// dikirim dan ID yang dikembalikan nil.
+func (h *SessionHandler) findTargetUser(c *fiber.Ctx) (*primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format ID user tidak valid"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userRepo.FindUserByID(ctx, objID)
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mencari user: %v", err)})
+	}
+	if user == nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user tidak ditemukan"})
+	}
+	return &objID, nil
+}
+
+func (h *SessionHandler) respondSessions(c *fiber.Ctx, userID primitive.ObjectID, currentSessionID string) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	sessions, err := h.tokenRepo.FindActiveSessionsForUser(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mengambil sesi: %v", err)})
+	}
+	for i := range sessions {
+		sessions[i].Current = currentSessionID != "" && sessions[i].SessionID == currentSessionID
+	}
+	return c.Status(fiber.StatusOK).JSON(sessions)
+}
+
+// revokeSession mengakhiri sesi :sessionId milik userID. actorID diisi jika dilakukan oleh admin.
+func (h *SessionHandler) revokeSession(c *fiber.Ctx, userID primitive.ObjectID, actorID *primitive.ObjectID) error {
+	sessionObjID, err := primitive.ObjectIDFromHex(c.Params("sessionId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Format ID sesi tidak valid"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	session, err := h.tokenRepo.FindSessionByID(ctx, sessionObjID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("Gagal mencari sesi: %v", err)})
+	}
+	// Sesi milik user lain diperlakukan sebagai tidak ditemukan.
+	if session == nil || session.UserID != userID || !session.IsActive(time.Now()) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Sesi tidak ditemukan atau sudah berakhir"})
+	}
+
+	if _, err := h.tokenRepo.RevokeSession(ctx, session.SessionID); err != nil {
+		log.Printf("ERROR: Gagal mengakhiri sesi %s user %s: %v", session.ID.Hex(), userID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengakhiri sesi"})
+	}
+
+	recordAudit(c, models.AuditActionSessionRevoke, models.AuditTargetSession, session.ID.Hex(), nil, nil)
+	recordSecurityEvent(ctx, h.securityRepo, models.SecurityEvent{
+		UserID:    &userID,
+		Type:      models.SecurityEventSessionRevoked,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		ActorID:   actorID,
+		Detail:    fmt.Sprintf("Sesi %s (%s, IP %s) diakhiri", session.ID.Hex(), session.Device, session.LastSeenIP),
+	})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Sesi berhasil diakhiri"})
+}
@@ -0,0 +1,523 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	qrcode "github.com/skip2/go-qrcode"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/paseto"
+	"Sistem-Manajemen-Karyawan/pkg/password"
+	"Sistem-Manajemen-Karyawan/pkg/totp"
+	util "Sistem-Manajemen-Karyawan/pkg/utils"
+)
+
+// Aturan langkah kedua login dan kode pemulihan.
+const (
+	twoFactorChallengeDuration    = 5 * time.Minute
+	twoFactorChallengeMaxAttempts = 5
+	recoveryCodeCount             = 10
+)
+
+// twoFactorRequiredForRole mengembalikan true jika role wajib memakai 2FA (TWO_FACTOR_REQUIRED_ROLES).
+func (h *AuthHandler) twoFactorRequiredForRole(role string) bool {
+	for _, required := range h.twoFactorRequiredRoles {
+		if required == role {
+			return true
+		}
+	}
+	return false
+}
+
+// startTwoFactorChallenge membuat tantangan 2FA setelah password terbukti benar.
+func (h *AuthHandler) startTwoFactorChallenge(ctx context.Context, c *fiber.Ctx, user *models.User) error {
+	token, err := paseto.NewRandomToken(32)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat tantangan 2FA"})
+	}
+
+	challenge := &models.TwoFactorChallenge{
+		UserID:    user.ID,
+		TokenHash: paseto.HashToken(token),
+		ExpiresAt: time.Now().Add(twoFactorChallengeDuration),
+	}
+	if _, err := h.twoFactorRepo.CreateChallenge(ctx, challenge); err != nil {
+		log.Printf("ERROR: Gagal membuat tantangan 2FA untuk user %s: %v", user.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat tantangan 2FA"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":              "Masukkan kode dari aplikasi authenticator atau kode pemulihan.",
+		"two_factor_required":  true,
+		"challenge_token":      token,
+		"challenge_expires_at": challenge.ExpiresAt,
+	})
+}
+
+// newRecoveryCodes membuat kode pemulihan baru (format xxxxx-xxxxx) beserta hash-nya untuk disimpan.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(secret[:5] + "-" + secret[5:10])
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode menormalkan kode (tanpa spasi, huruf kecil) sebelum di-hash.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+	return paseto.HashToken(normalized)
+}
+
+// SetupTwoFactor godoc
+// @Summary Start 2FA Enrolment
+// @Description Membuat secret TOTP baru dan mengembalikan otpauth URI beserta QR code untuk dipindai aplikasi authenticator. 2FA baru aktif setelah dikonfirmasi di /auth/2fa/enable.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.TwoFactorSetupResponse "Secret dan QR code 2FA"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 409 {object} object{error=string} "2FA sudah aktif"
+// @Failure 500 {object} object{error=string} "Gagal menyiapkan 2FA"
+// @Router /auth/2fa/setup [post]
+func (h *AuthHandler) SetupTwoFactor(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userRepo.FindUserByID(ctx, claims.UserID)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+	if user.TwoFactorEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "2FA sudah aktif. Nonaktifkan terlebih dahulu untuk mendaftarkan perangkat baru."})
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyiapkan 2FA"})
+	}
+	if err := h.userRepo.SetPendingTwoFactorSecret(ctx, user.ID, secret); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyiapkan 2FA"})
+	}
+	recordAudit(c, models.AuditActionUserTwoFactorSetup, models.AuditTargetUser, user.ID.Hex(), nil, nil)
+
+	uri := totp.URI(h.twoFactorIssuer, user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat QR code 2FA"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.TwoFactorSetupResponse{
+		Secret:     secret,
+		OtpauthURI: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// EnableTwoFactor godoc
+// @Summary Confirm 2FA Enrolment
+// @Description Mengaktifkan 2FA dengan kode pertama dari aplikasi authenticator. Mengembalikan kode pemulihan (hanya ditampilkan sekali) dan mengakhiri semua sesi sehingga user perlu login ulang.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body models.TwoFactorCodePayload true "Kode TOTP"
+// @Success 200 {object} object{message=string,recovery_codes=[]string} "2FA aktif"
+// @Failure 400 {object} object{error=string} "Kode tidak valid atau pendaftaran belum dimulai"
+// @Failure 401 {object} object{error=string} "Tidak terautentikasi"
+// @Failure 409 {object} object{error=string} "2FA sudah aktif"
+// @Failure 500 {object} object{error=string} "Gagal mengaktifkan 2FA"
+// @Router /auth/2fa/enable [post]
+func (h *AuthHandler) EnableTwoFactor(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+
+	var payload models.TwoFactorCodePayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userRepo.FindUserByID(ctx, claims.UserID)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+	if user.TwoFactorEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "2FA sudah aktif."})
+	}
+	if user.TwoFactorPendingSecret == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Mulai pendaftaran 2FA melalui /auth/2fa/setup terlebih dahulu."})
+	}
+
+	step, valid := totp.Validate(user.TwoFactorPendingSecret, payload.Code, time.Now())
+	if !valid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Kode 2FA tidak valid"})
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat kode pemulihan"})
+	}
+	if err := h.userRepo.EnableTwoFactor(ctx, user.ID, user.TwoFactorPendingSecret, step, hashes); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengaktifkan 2FA"})
+	}
+
+	recordAudit(c, models.AuditActionUserTwoFactorEnable, models.AuditTargetUser, user.ID.Hex(),
+		fiber.Map{"two_factor_enabled": false}, fiber.Map{"two_factor_enabled": true})
+	recordSecurityEvent(ctx, h.securityRepo, models.SecurityEvent{
+		UserID:    &user.ID,
+		Type:      models.SecurityEventTwoFactorEnabled,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	})
+
+	// Sesi yang dibuka tanpa 2FA diakhiri.
+	if _, err := revokeAllUserTokens(ctx, h.tokenRepo, user.ID); err != nil {
+		log.Printf("ERROR: 2FA user %s aktif tetapi gagal mencabut sesi lama: %v", user.ID.Hex(), err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":        "2FA berhasil diaktifkan. Simpan kode pemulihan di tempat aman, lalu login kembali.",
+		"recovery_codes": codes,
+	})
+}
+
+// VerifyTwoFactorLogin godoc
+// @Summary Verify 2FA Login
+// @Description Langkah kedua login: menukar challenge_token dari /auth/login dan kode TOTP (atau kode pemulihan) dengan access token dan refresh token. Maksimal 5 percobaan per tantangan.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body models.TwoFactorVerifyPayload true "Token tantangan dan kode"
+// @Success 200 {object} object{message=string,token=string,token_expires_at=string,refresh_token=string,refresh_token_expires_at=string,user=models.User} "Login berhasil"
+// @Failure 400 {object} object{error=string} "Payload tidak valid"
+// @Failure 401 {object} object{error=string} "Tantangan atau kode tidak valid"
+// @Failure 423 {object} object{error=string,locked_until=string} "Akun dikunci sementara"
+// @Failure 500 {object} object{error=string} "Error internal server"
+// @Router /auth/2fa/verify [post]
+func (h *AuthHandler) VerifyTwoFactorLogin(c *fiber.Ctx) error {
+	var payload models.TwoFactorVerifyPayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body", "details": err.Error()})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+	if (payload.Code == "") == (payload.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Isi salah satu: code atau recovery_code"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	challenge, err := h.twoFactorRepo.RegisterChallengeAttempt(ctx, paseto.HashToken(payload.ChallengeToken), twoFactorChallengeMaxAttempts)
+	if err != nil {
+		log.Printf("ERROR: Gagal memeriksa tantangan 2FA: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error internal server"})
+	}
+	if challenge == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tantangan 2FA tidak valid atau telah kedaluwarsa. Silakan login ulang."})
+	}
+
+	user, err := h.userRepo.FindUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error internal server"})
+	}
+	if user == nil || !user.TwoFactorEnabled {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tantangan 2FA tidak valid atau telah kedaluwarsa. Silakan login ulang."})
+	}
+
+	now := time.Now()
+	event := models.SecurityEvent{
+		UserID:    &user.ID,
+		Email:     user.Email,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+	if user.EmploymentStatus == models.EmploymentStatusInactive {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": errAccountInactive})
+	}
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error":        "Akun dikunci sementara karena terlalu banyak percobaan login gagal.",
+			"locked_until": user.LockedUntil,
+		})
+	}
+
+	valid := false
+	usedRecoveryCode := false
+	if payload.Code != "" {
+		if step, ok := totp.Validate(user.TwoFactorSecret, payload.Code, now); ok {
+			// Kode yang sama tidak boleh dipakai dua kali.
+			valid, err = h.userRepo.UseTwoFactorStep(ctx, user.ID, step)
+		}
+	} else {
+		valid, err = h.userRepo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(payload.RecoveryCode))
+		usedRecoveryCode = valid
+	}
+	if err != nil {
+		log.Printf("ERROR: Gagal memverifikasi kode 2FA user %s: %v", user.ID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error internal server"})
+	}
+
+	if !valid {
+		event.Type = models.SecurityEventLoginFailed
+		event.Reason = models.LoginFailureInvalidTwoFactor
+		recordSecurityEvent(ctx, h.securityRepo, event)
+
+		failedCount, err := h.userRepo.RegisterFailedLogin(ctx, user.ID)
+		if err != nil {
+			log.Printf("ERROR: Gagal mencatat login gagal user %s: %v", user.ID.Hex(), err)
+		} else if failedCount >= loginLockAfterFailures {
+			lockedUntil := now.Add(loginLockDuration)
+			if err := h.userRepo.LockUser(ctx, user.ID, lockedUntil); err != nil {
+				log.Printf("ERROR: Gagal mengunci akun user %s: %v", user.ID.Hex(), err)
+			} else {
+				recordSecurityEvent(ctx, h.securityRepo, models.SecurityEvent{
+					UserID:    &user.ID,
+					Type:      models.SecurityEventAccountLocked,
+					IPAddress: event.IPAddress,
+					UserAgent: event.UserAgent,
+					Detail:    fmt.Sprintf("Dikunci hingga %s setelah %d kali login gagal", lockedUntil.Format(time.RFC3339), failedCount),
+				})
+			}
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Kode 2FA tidak valid"})
+	}
+
+	result, err := h.twoFactorRepo.ConsumeChallenge(ctx, challenge.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Error internal server"})
+	}
+	if result.ModifiedCount == 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tantangan 2FA tidak valid atau telah kedaluwarsa. Silakan login ulang."})
+	}
+
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		if err := h.userRepo.ResetLoginFailures(ctx, user.ID); err != nil {
+			log.Printf("ERROR: Gagal mereset hitungan login gagal user %s: %v", user.ID.Hex(), err)
+		}
+	}
+	if usedRecoveryCode {
+		recordSecurityEvent(ctx, h.securityRepo, models.SecurityEvent{
+			UserID:    &user.ID,
+			Type:      models.SecurityEventRecoveryCodeUsed,
+			IPAddress: event.IPAddress,
+			UserAgent: event.UserAgent,
+			Detail:    fmt.Sprintf("Sisa kode pemulihan: %d", len(user.RecoveryCodeHashes)-1),
+		})
+	}
+	event.Type = models.SecurityEventLoginSuccess
+	recordSecurityEvent(ctx, h.securityRepo, event)
+
+	return h.respondLoginSuccess(ctx, c, user)
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable 2FA
+// @Description Menonaktifkan 2FA milik user yang sedang login. Membutuhkan password dan kode TOTP yang valid. Tidak diizinkan untuk role yang wajib 2FA.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body models.TwoFactorDisablePayload true "Password dan kode TOTP"
+// @Success 200 {object} object{message=string} "2FA dinonaktifkan"
+// @Failure 400 {object} object{error=string} "Payload tidak valid atau 2FA belum aktif"
+// @Failure 401 {object} object{error=string} "Password atau kode salah"
+// @Failure 403 {object} object{error=string} "Role wajib memakai 2FA"
+// @Failure 500 {object} object{error=string} "Gagal menonaktifkan 2FA"
+// @Router /auth/2fa/disable [post]
+func (h *AuthHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+
+	var payload models.TwoFactorDisablePayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userRepo.FindUserByID(ctx, claims.UserID)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+	if !user.TwoFactorEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "2FA belum aktif."})
+	}
+	if h.twoFactorRequiredForRole(user.Role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Role Anda wajib memakai 2FA sehingga tidak dapat dinonaktifkan."})
+	}
+	if !password.CheckPasswordHash(payload.Password, user.Password) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Password salah"})
+	}
+	if _, valid := totp.Validate(user.TwoFactorSecret, payload.Code, time.Now()); !valid {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Kode 2FA tidak valid"})
+	}
+
+	if err := h.userRepo.DisableTwoFactor(ctx, user.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menonaktifkan 2FA"})
+	}
+
+	recordAudit(c, models.AuditActionUserTwoFactorDisable, models.AuditTargetUser, user.ID.Hex(),
+		fiber.Map{"two_factor_enabled": true}, fiber.Map{"two_factor_enabled": false})
+	recordSecurityEvent(ctx, h.securityRepo, models.SecurityEvent{
+		UserID:    &user.ID,
+		Type:      models.SecurityEventTwoFactorDisabled,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "2FA berhasil dinonaktifkan."})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate 2FA Recovery Codes
+// @Description Membuat ulang kode pemulihan 2FA; semua kode lama tidak berlaku lagi. Membutuhkan kode TOTP yang valid.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param payload body models.TwoFactorCodePayload true "Kode TOTP"
+// @Success 200 {object} object{message=string,recovery_codes=[]string} "Kode pemulihan baru"
+// @Failure 400 {object} object{error=string} "Payload tidak valid atau 2FA belum aktif"
+// @Failure 401 {object} object{error=string} "Kode 2FA tidak valid"
+// @Failure 500 {object} object{error=string} "Gagal membuat kode pemulihan"
+// @Router /auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+
+	var payload models.TwoFactorCodePayload
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if errors := util.ValidateStruct(payload); errors != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errors})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userRepo.FindUserByID(ctx, claims.UserID)
+	if err != nil || user == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	}
+	if !user.TwoFactorEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "2FA belum aktif."})
+	}
+	if _, valid := totp.Validate(user.TwoFactorSecret, payload.Code, time.Now()); !valid {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Kode 2FA tidak valid"})
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat kode pemulihan"})
+	}
+	if err := h.userRepo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal membuat kode pemulihan"})
+	}
+
+	recordAudit(c, models.AuditActionUserRecoveryCodesRenew, models.AuditTargetUser, user.ID.Hex(), nil, nil)
+	recordSecurityEvent(ctx, h.securityRepo, models.SecurityEvent{
+		UserID:    &user.ID,
+		Type:      models.SecurityEventRecoveryCodesRenewed,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":        "Kode pemulihan baru berhasil dibuat. Kode lama tidak berlaku lagi.",
+		"recovery_codes": codes,
+	})
+}
+
+// ResetUserTwoFactor godoc
+// @Summary Reset User 2FA
+// @Description Menonaktifkan 2FA user yang kehilangan perangkat dan kode pemulihannya, lalu mengakhiri semua sesinya. User dengan role wajib 2FA harus mendaftar ulang saat login berikutnya (admin only).
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} object{message=string} "2FA user berhasil direset"
+// @Failure 400 {object} object{error=string} "Format ID user tidak valid"
+// @Failure 404 {object} object{error=string} "User tidak ditemukan"
+// @Failure 500 {object} object{error=string} "Gagal mereset 2FA user"
+// @Router /admin/users/{id}/2fa/reset [post]
+func (h *AuthHandler) ResetUserTwoFactor(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*models.Claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau data sesi rusak"})
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format ID user tidak valid"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mencari user: %v", err)})
+	}
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user tidak ditemukan"})
+	}
+
+	if err := h.userRepo.DisableTwoFactor(ctx, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "gagal mereset 2FA user"})
+	}
+	if _, err := revokeAllUserTokens(ctx, h.tokenRepo, userID); err != nil {
+		log.Printf("ERROR: 2FA user %s direset tetapi gagal mencabut sesi: %v", userID.Hex(), err)
+	}
+
+	recordAudit(c, models.AuditActionUserTwoFactorReset, models.AuditTargetUser, userID.Hex(),
+		fiber.Map{"two_factor_enabled": user.TwoFactorEnabled}, fiber.Map{"two_factor_enabled": false})
+	recordSecurityEvent(ctx, h.securityRepo, models.SecurityEvent{
+		UserID:    &userID,
+		Type:      models.SecurityEventTwoFactorReset,
+		IPAddress: c.IP(),
+		ActorID:   &claims.UserID,
+		Detail:    fmt.Sprintf("Direset oleh admin %s", claims.Email),
+	})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "2FA user berhasil direset"})
+}
@@ -3,9 +3,10 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"log"	
+	"log"
 	"strings"
 	"time"
 
@@ -16,15 +17,20 @@ import (
 
 	"Sistem-Manajemen-Karyawan/config"
 	"Sistem-Manajemen-Karyawan/models"
-	"github.com/fogleman/gg"
 	util "Sistem-Manajemen-Karyawan/pkg/utils"
 	"Sistem-Manajemen-Karyawan/repository"
+	"github.com/fogleman/gg"
 )
 
 type UserHandler struct {
 	userRepo  *repository.UserRepository
 	deptRepo  repository.DepartmentRepository
 	leaveRepo repository.LeaveRequestRepository
+	tokenRepo repository.TokenRepository
+	roleRepo  repository.RoleRepository
+
+	activityRepo   repository.ActivityRepository
+	attendanceRepo repository.AttendanceRepository
 }
 
 // Perbarui konstruktor untuk menginisialisasi semua repository yang dibutuhkan.
@@ -34,18 +40,77 @@ func NewUserHandler(
 	userRepo *repository.UserRepository,
 	deptRepo repository.DepartmentRepository,
 	leaveRepo repository.LeaveRequestRepository,
-
+	tokenRepo repository.TokenRepository,
+	roleRepo repository.RoleRepository,
+	activityRepo repository.ActivityRepository,
+	attendanceRepo repository.AttendanceRepository,
 ) *UserHandler {
 	return &UserHandler{
 		userRepo:  userRepo,
 		deptRepo:  deptRepo,
 		leaveRepo: leaveRepo,
+		tokenRepo: tokenRepo,
+		roleRepo:  roleRepo,
+
+		activityRepo:   activityRepo,
+		attendanceRepo: attendanceRepo,
+	}
+}
+
+// findManagerID memvalidasi manager_id: formatnya harus ObjectID dan atasannya harus user aktif
+// yang terdaftar. Jika userID diisi (update user), atasan tidak boleh user itu sendiri atau
+// bawahannya, baik langsung maupun tidak langsung, agar rantai atasan tidak membentuk siklus.
+func findManagerID(ctx context.Context, userRepo *repository.UserRepository, hex string, userID primitive.ObjectID) (primitive.ObjectID, *fiber.Error) {
+	managerID, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "format manager_id tidak valid")
+	}
+	if !userID.IsZero() && managerID == userID {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "user tidak dapat menjadi atasan dirinya sendiri")
+	}
+	manager, err := userRepo.FindUserByID(ctx, managerID)
+	if err != nil {
+		log.Printf("ERROR: Gagal memeriksa atasan %s: %v", hex, err)
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusInternalServerError, "gagal memeriksa manager_id")
+	}
+	if manager == nil {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "manager_id tidak ditemukan")
+	}
+	if manager.EmploymentStatus == models.EmploymentStatusInactive {
+		return primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "manager_id merujuk ke user yang tidak aktif")
+	}
+	if userID.IsZero() {
+		return managerID, nil
+	}
+
+	// Telusuri atasan dari calon atasan ke atas. visited mencegah loop tak berujung jika data
+	// lama sudah berisi siklus yang tidak melibatkan userID.
+	visited := map[primitive.ObjectID]bool{managerID: true}
+	for current := manager; current.ManagerID != nil; {
+		nextID := *current.ManagerID
+		if nextID == userID {
+			return primitive.NilObjectID, fiber.NewError(fiber.StatusBadRequest, "manager_id tidak valid: user tersebut adalah bawahan dari user yang diupdate")
+		}
+		if visited[nextID] {
+			break
+		}
+		visited[nextID] = true
+		next, err := userRepo.FindUserByID(ctx, nextID)
+		if err != nil {
+			log.Printf("ERROR: Gagal menelusuri rantai atasan %s: %v", nextID.Hex(), err)
+			return primitive.NilObjectID, fiber.NewError(fiber.StatusInternalServerError, "gagal memeriksa manager_id")
+		}
+		if next == nil {
+			break
+		}
+		current = next
 	}
+	return managerID, nil
 }
 
 // GetUserByID godoc
 // @Summary Get User by ID
-// @Description Mendapatkan detail user berdasarkan ID (user hanya bisa melihat data diri sendiri, admin bisa melihat semua)
+// @Description Mendapatkan detail user berdasarkan ID (user hanya bisa melihat data diri sendiri, manager bisa melihat anggota timnya tanpa data gaji, role dengan izin users.read.all bisa melihat semua)
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -70,13 +135,25 @@ func (h *UserHandler) GetUserByID(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "tidak terautentikasi atau klaim token tidak valid"})
 	}
 
-	if claims.Role != "admin" && claims.UserID.Hex() != idParam {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "akses ditolak. anda hanya dapat melihat profile anda sendiri."})
-	}
-
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
 
+	// Manager boleh melihat anggota timnya, tetapi tanpa data gaji.
+	viewAsManager := false
+	if claims.UserID.Hex() != idParam && !hasPermission(ctx, h.roleRepo, claims, models.PermissionUsersReadAll) {
+		if hasPermission(ctx, h.roleRepo, claims, models.PermissionTeamRead) {
+			inTeam, err := isTeamMember(ctx, h.userRepo, h.deptRepo, claims.UserID, objID)
+			if err != nil {
+				log.Printf("Error checking team membership: %v", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "gagal memeriksa keanggotaan tim"})
+			}
+			viewAsManager = inTeam
+		}
+		if !viewAsManager {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "akses ditolak. anda hanya dapat melihat profile anda sendiri."})
+		}
+	}
+
 	user, err := h.userRepo.FindUserByID(ctx, objID)
 
 	if user == nil {
@@ -93,6 +170,10 @@ func (h *UserHandler) GetUserByID(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mendapatkan user: %v", err)})
 	}
 
+	if viewAsManager {
+		return c.Status(fiber.StatusOK).JSON(user.ToTeamMember())
+	}
+
 	user.Password = ""
 	return c.Status(fiber.StatusOK).JSON(user)
 }
@@ -158,7 +239,7 @@ func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
 
 // UpdateUser godoc
 // @Summary Update User
-// @Description Update data user (user hanya bisa update data diri sendiri, admin bisa update semua, karyawan sekarang bisa mengubah email sendiri)
+// @Description Update data user (user hanya bisa update data diri sendiri, role dengan izin users.write bisa update semua termasuk role, karyawan sekarang bisa mengubah email sendiri). Kirim "manager_id": null untuk menghapus atasan.
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -184,8 +265,12 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "tidak terautentikasi atau klaim token tidak valid"})
 	}
 
-	// Memeriksa otorisasi: User yang login harus sama dengan user yang diupdate, ATAU user yang login harus admin.
-	if claims.Role != "admin" && claims.UserID.Hex() != idParam {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	// Memeriksa otorisasi: User yang login harus sama dengan user yang diupdate, ATAU memiliki izin users.write.
+	canWriteAll := hasPermission(ctx, h.roleRepo, claims, models.PermissionUsersWrite)
+	if !canWriteAll && claims.UserID.Hex() != idParam {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "akses ditolak. anda hanya dapat mengupdate profil anda sendiri."})
 	}
 
@@ -199,12 +284,16 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	}
 
 	updateData := bson.M{}
-	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
-	defer cancel()
+	roleChanged := false
 
-	// Logika update berdasarkan peran
-	if claims.Role != "admin" {
-		// Jika user bukan admin (karyawan), hanya izinkan update untuk Photo, Address, dan EMAIL
+	// manager_id: null menghapus atasan; string kosong atau field yang tidak dikirim tidak diubah.
+	var unsetFields []string
+	var rawPayload map[string]json.RawMessage
+	clearManager := json.Unmarshal(c.Body(), &rawPayload) == nil && string(rawPayload["manager_id"]) == "null"
+
+	// Logika update berdasarkan izin
+	if !canWriteAll {
+		// Jika user tidak memiliki izin users.write, hanya izinkan update untuk Photo, Address, dan EMAIL
 		if payload.Photo != "" {
 			updateData["photo"] = payload.Photo
 		}
@@ -226,13 +315,14 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		}
 
 		// Batasi perubahan lain untuk non-admin
-		if payload.Name != "" ||
-			payload.Position != "" || payload.Department != "" || payload.BaseSalary != 0 {
+		if payload.Name != "" || payload.Role != "" ||
+			payload.Position != "" || payload.Department != "" || payload.BaseSalary != 0 || payload.ManagerID != "" || clearManager ||
+			payload.EmploymentStatus != "" {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "akses ditolak. anda tidak diizinkan mengubah nama, posisi, departemen, atau gaji dasar.",
+				"error": "akses ditolak. anda tidak diizinkan mengubah nama, role, posisi, departemen, atasan, status kepegawaian, atau gaji dasar.",
 			})
 		}
-	} else { // Jika user adalah admin, izinkan update semua bidang
+	} else { // Jika user memiliki izin users.write, izinkan update semua bidang
 		if payload.Name != "" {
 			updateData["name"] = payload.Name
 		}
@@ -263,13 +353,44 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		if payload.Photo != "" {
 			updateData["photo"] = payload.Photo
 		}
+		if payload.EmploymentStatus != "" {
+			updateData["employment_status"] = payload.EmploymentStatus
+		}
+		if clearManager {
+			unsetFields = append(unsetFields, "manager_id")
+		} else if payload.ManagerID != "" {
+			managerID, ferr := findManagerID(ctx, h.userRepo, payload.ManagerID, objID)
+			if ferr != nil {
+				return c.Status(ferr.Code).JSON(fiber.Map{"error": ferr.Message})
+			}
+			updateData["manager_id"] = managerID
+		}
+		if payload.Role != "" {
+			role, err := h.roleRepo.FindByName(ctx, payload.Role)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "gagal memeriksa role"})
+			}
+			if role == nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("role '%s' tidak ditemukan", payload.Role)})
+			}
+			updateData["role"] = payload.Role
+			roleChanged = true
+		}
 	}
 
-	if len(updateData) == 0 {
+	if len(updateData) == 0 && len(unsetFields) == 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "tidak ada field yang akan diupdate"})
 	}
 
-	result, err := h.userRepo.UpdateUser(ctx, objID, updateData)
+	before, err := h.userRepo.FindUserByID(ctx, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mencari user: %v", err)})
+	}
+	if before == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "user tidak ditemukan"})
+	}
+
+	result, err := h.userRepo.UpdateUser(ctx, objID, updateData, unsetFields)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mengupdate user: %v", err)})
 	}
@@ -277,6 +398,40 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "user tidak ditemukan atau tidak ada perubahan"})
 	}
 
+	// Role tersimpan di access token; token lama ditolak agar client melakukan refresh
+	// dan mendapat token dengan role baru.
+	// User yang dinonaktifkan tidak boleh memakai sesi yang masih berjalan.
+	if payload.EmploymentStatus == models.EmploymentStatusInactive && before.EmploymentStatus != models.EmploymentStatusInactive {
+		if _, err := revokeAllUserTokens(ctx, h.tokenRepo, objID); err != nil {
+			log.Printf("ERROR: User %s dinonaktifkan tetapi gagal mencabut tokennya: %v", objID.Hex(), err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user dinonaktifkan, tetapi gagal mencabut sesi user"})
+		}
+	} else if roleChanged {
+		if err := h.tokenRepo.RevokeAccessTokensForUser(ctx, objID, time.Now()); err != nil {
+			log.Printf("ERROR: Role user %s diubah tetapi gagal mencabut access token lama: %v", objID.Hex(), err)
+		}
+	}
+
+	after, err := h.userRepo.FindUserByID(ctx, objID)
+	if err != nil {
+		log.Printf("ERROR: Gagal membaca user %s setelah update untuk audit log: %v", objID.Hex(), err)
+	}
+	recordAudit(c, models.AuditActionUserUpdate, models.AuditTargetUser, objID.Hex(), before, after)
+	if after != nil {
+		var changedFields []string
+		for _, change := range auditChanges(before, after) {
+			changedFields = append(changedFields, change.Field)
+		}
+		if len(changedFields) > 0 {
+			recordActivity(ctx, h.activityRepo, models.Activity{
+				Type:    models.ActivityProfileUpdated,
+				UserID:  objID,
+				ActorID: activityActor(c),
+				Message: "Memperbarui data profil: " + strings.Join(changedFields, ", "),
+			})
+		}
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "user berhasil diupdate"})
 }
 
@@ -303,6 +458,11 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 	defer cancel()
 
+	before, err := h.userRepo.FindUserByID(ctx, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mencari user: %v", err)})
+	}
+
 	result, err := h.userRepo.DeleteUser(ctx, objID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal menghapus user: %v", err)})
@@ -311,12 +471,62 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "user tidak ditemukan"})
 	}
 
+	// Token user yang dihapus tidak boleh dipakai lagi.
+	if _, err := revokeAllUserTokens(ctx, h.tokenRepo, objID); err != nil {
+		log.Printf("ERROR: User %s dihapus tetapi gagal mencabut tokennya: %v", objID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user dihapus, tetapi gagal mencabut sesi user"})
+	}
+	recordAudit(c, models.AuditActionUserDelete, models.AuditTargetUser, objID.Hex(), before, nil)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "user berhasil dihapus"})
 }
 
+// RevokeUserSessions godoc
+// @Summary Revoke All Sessions of a User
+// @Description Mengeluarkan user dari semua sesi: semua refresh token dicabut dan access token yang sudah terbit ditolak (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} object{message=string,revoked_sessions=int} "Semua sesi user berhasil dicabut"
+// @Failure 400 {object} object{error=string} "Format ID user tidak valid"
+// @Failure 404 {object} object{error=string} "User tidak ditemukan"
+// @Failure 500 {object} object{error=string} "Gagal mencabut sesi user"
+// @Router /admin/users/{id}/revoke-sessions [post]
+func (h *UserHandler) RevokeUserSessions(c *fiber.Ctx) error {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format ID user tidak valid"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.userRepo.FindUserByID(ctx, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fmt.Sprintf("gagal mencari user: %v", err)})
+	}
+	if user == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user tidak ditemukan"})
+	}
+
+	revoked, err := revokeAllUserTokens(ctx, h.tokenRepo, objID)
+	if err != nil {
+		log.Printf("ERROR: Gagal mencabut sesi user %s: %v", objID.Hex(), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "gagal mencabut sesi user"})
+	}
+	recordAudit(c, models.AuditActionUserSessionsRevoke, models.AuditTargetUser, objID.Hex(), nil, nil)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":          "Semua sesi user berhasil dicabut",
+		"revoked_sessions": revoked,
+	})
+}
+
 // GetDashboardStats godoc
 // @Summary Get Dashboard Statistics
-// @Description Mendapatkan berbagai statistik untuk dashboard admin (admin only)
+// @Description Mendapatkan statistik dashboard admin: karyawan aktif (berdasarkan status kepegawaian, tanpa akun admin), karyawan cuti penuh hari ini (cuti setengah hari atau per jam tidak dihitung), kehadiran hari ini per status, karyawan dan posisi baru 30 hari terakhir, beserta tren terhadap periode sebelumnya
 // @Tags Admin
 // @Accept json
 // @Produce json
@@ -336,13 +546,32 @@ func (h *UserHandler) GetDashboardStats(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung total user."})
 	}
 
-	activeUsers, err := h.userRepo.CountDocuments(ctx, bson.M{"role": "karyawan"})
+	// Akun admin bukan karyawan, sama seperti FindAllActiveUsers.
+	activeUsers, err := h.userRepo.CountDocuments(ctx, bson.M{
+		"role":              bson.M{"$ne": models.RoleAdmin},
+		"employment_status": bson.M{"$ne": models.EmploymentStatusInactive},
+	})
 	if err != nil {
 		log.Printf("Error menghitung karyawan aktif: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung karyawan aktif."})
 	}
 
-	karyawanCuti := int64(0)
+	// Statistik harian memakai tanggal WIB dan dibandingkan dengan hari yang sama minggu lalu.
+	wib, _ := time.LoadLocation("Asia/Jakarta")
+	now := time.Now().In(wib)
+	today := now.Format("2006-01-02")
+	lastWeek := now.AddDate(0, 0, -7).Format("2006-01-02")
+
+	karyawanCuti, err := h.leaveRepo.CountApprovedOnDate(ctx, today)
+	if err != nil {
+		log.Printf("Error menghitung karyawan cuti: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung karyawan cuti."})
+	}
+	karyawanCutiLastWeek, err := h.leaveRepo.CountApprovedOnDate(ctx, lastWeek)
+	if err != nil {
+		log.Printf("Error menghitung karyawan cuti minggu lalu: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung karyawan cuti."})
+	}
 
 	pendingLeavesCount, err := h.leaveRepo.CountPendingRequests(ctx)
 	if err != nil {
@@ -350,13 +579,36 @@ func (h *UserHandler) GetDashboardStats(c *fiber.Ctx) error {
 		pendingLeavesCount = 0
 	}
 
-	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
-	newPositions, err := h.userRepo.CountDocuments(ctx, bson.M{"created_at": bson.M{"$gte": thirtyDaysAgo}})
+	thirtyDaysAgo := now.AddDate(0, 0, -30)
+	sixtyDaysAgo := now.AddDate(0, 0, -60)
+	newEmployees, err := h.userRepo.CountDocuments(ctx, bson.M{"created_at": bson.M{"$gte": thirtyDaysAgo}})
+	if err != nil {
+		log.Printf("Error menghitung karyawan baru: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung karyawan baru."})
+	}
+	prevNewEmployees, err := h.userRepo.CountDocuments(ctx, bson.M{"created_at": bson.M{"$gte": sixtyDaysAgo, "$lt": thirtyDaysAgo}})
+	if err != nil {
+		log.Printf("Error menghitung karyawan baru periode sebelumnya: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung karyawan baru."})
+	}
+
+	newPositions, prevNewPositions, err := h.countNewPositions(ctx, thirtyDaysAgo, sixtyDaysAgo)
 	if err != nil {
 		log.Printf("Error menghitung posisi baru: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung posisi baru."})
 	}
 
+	attendanceToday, err := h.attendanceRepo.CountByStatusForDate(ctx, today)
+	if err != nil {
+		log.Printf("Error menghitung kehadiran hari ini: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung kehadiran hari ini."})
+	}
+	attendanceLastWeek, err := h.attendanceRepo.CountByStatusForDate(ctx, lastWeek)
+	if err != nil {
+		log.Printf("Error menghitung kehadiran minggu lalu: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghitung kehadiran hari ini."})
+	}
+
 	totalDepartemen, err := h.deptRepo.CountDocuments(ctx, bson.M{})
 	if err != nil {
 		log.Printf("Error menghitung total departemen: %v", err)
@@ -389,9 +641,11 @@ func (h *UserHandler) GetDashboardStats(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mendecode distribusi departemen."})
 	}
 
-	latestActivities := []string{
-		"Sistem HR-System dimulai.",
-		"Admin login ke dashboard.",
+	// Feed aktivitas hanya pelengkap; jika gagal dibaca, statistik lain tetap dikirim.
+	latestActivities, _, err := h.activityRepo.Find(ctx, "", 1, dashboardActivityLimit)
+	if err != nil {
+		log.Printf("Error mengambil aktivitas terbaru: %v", err)
+		latestActivities = []models.Activity{}
 	}
 
 	stats := &models.DashboardStats{
@@ -399,15 +653,61 @@ func (h *UserHandler) GetDashboardStats(c *fiber.Ctx) error {
 		KaryawanAktif:             activeUsers,
 		KaryawanCuti:              karyawanCuti,
 		PendingLeaveRequestsCount: pendingLeavesCount,
+		KaryawanBaru:              newEmployees,
 		PosisiBaru:                newPositions,
 		TotalDepartemen:           totalDepartemen,
-		DistribusiDepartemen:      departmentDistribution,
-		AktivitasTerbaru:          latestActivities,
+		KehadiranHariIni:          attendanceToday,
+		Tren: models.DashboardTrend{
+			KaryawanCuti: karyawanCuti - karyawanCutiLastWeek,
+			KaryawanBaru: newEmployees - prevNewEmployees,
+			PosisiBaru:   newPositions - prevNewPositions,
+			Hadir:        attendanceToday["Hadir"] - attendanceLastWeek["Hadir"],
+			Terlambat:    attendanceToday["Terlambat"] - attendanceLastWeek["Terlambat"],
+			Alpha:        attendanceToday["Alpha"] - attendanceLastWeek["Alpha"],
+		},
+		DistribusiDepartemen: departmentDistribution,
+		AktivitasTerbaru:     latestActivities,
 	}
 
 	return c.Status(fiber.StatusOK).JSON(stats)
 }
 
+// countNewPositions menghitung posisi yang pertama kali diisi (user pertama dengan posisi tersebut
+// dibuat) sejak since, dan pada periode sebelumnya [prevSince, since).
+func (h *UserHandler) countNewPositions(ctx context.Context, since, prevSince time.Time) (int64, int64, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.D{{Key: "position", Value: bson.D{{Key: "$nin", Value: bson.A{"", nil}}}}}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$position"},
+			{Key: "first_filled_at", Value: bson.D{{Key: "$min", Value: "$created_at"}}},
+		}}},
+		bson.D{{Key: "$match", Value: bson.D{{Key: "first_filled_at", Value: bson.D{{Key: "$gte", Value: prevSince}}}}}},
+	}
+
+	cursor, err := h.userRepo.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var positions []struct {
+		FirstFilledAt time.Time `bson:"first_filled_at"`
+	}
+	if err := cursor.All(ctx, &positions); err != nil {
+		return 0, 0, err
+	}
+
+	var current, previous int64
+	for _, p := range positions {
+		if p.FirstFilledAt.Before(since) {
+			previous++
+		} else {
+			current++
+		}
+	}
+	return current, previous, nil
+}
+
 // UploadProfilePhoto godoc
 // @Summary Upload User Profile Photo
 // @Description Mengunggah foto profil untuk user tertentu. Hanya admin atau user itu sendiri yang bisa mengunggah.
@@ -436,7 +736,7 @@ func (h *UserHandler) UploadProfilePhoto(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tidak terautentikasi atau klaim token tidak valid"})
 	}
 
-	if claims.Role != "admin" && claims.UserID.Hex() != userID {
+	if claims.UserID.Hex() != userID && !hasPermission(c.Context(), h.roleRepo, claims, models.PermissionUsersWrite) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Akses ditolak. Anda hanya dapat mengunggah foto profil Anda sendiri."})
 	}
 
@@ -500,7 +800,7 @@ func (h *UserHandler) UploadProfilePhoto(c *fiber.Ctx) error {
 		"photo_mime": contentType,
 	}
 
-	result, err := h.userRepo.UpdateUser(ctx, objID, updateData)
+	result, err := h.userRepo.UpdateUser(ctx, objID, updateData, nil)
 	if err != nil {
 		log.Printf("Error mengupdate data user: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memperbarui user."})
@@ -508,6 +808,13 @@ func (h *UserHandler) UploadProfilePhoto(c *fiber.Ctx) error {
 	if result.ModifiedCount == 0 {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "User tidak ditemukan atau data tidak berubah."})
 	}
+	recordAudit(c, models.AuditActionUserPhotoUpload, models.AuditTargetUser, objID.Hex(), nil, updateData)
+	recordActivity(ctx, h.activityRepo, models.Activity{
+		Type:    models.ActivityProfileUpdated,
+		UserID:  objID,
+		ActorID: activityActor(c),
+		Message: "Memperbarui foto profil",
+	})
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message":   "Foto profil berhasil diunggah.",
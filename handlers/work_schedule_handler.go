@@ -20,11 +20,13 @@ import (
 
 type WorkScheduleHandler struct {
 	workScheduleRepo *repository.WorkScheduleRepository
+	roleRepo         repository.RoleRepository
 }
 
-func NewWorkScheduleHandler(repo *repository.WorkScheduleRepository) *WorkScheduleHandler {
+func NewWorkScheduleHandler(repo *repository.WorkScheduleRepository, roleRepo repository.RoleRepository) *WorkScheduleHandler {
 	return &WorkScheduleHandler{
 		workScheduleRepo: repo,
+		roleRepo:         roleRepo,
 	}
 }
 
@@ -62,6 +64,8 @@ func (h *WorkScheduleHandler) CreateWorkSchedule(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menyimpan jadwal kerja", "details": err.Error()})
 	}
 
+	recordAudit(c, models.AuditActionWorkScheduleCreate, models.AuditTargetWorkSchedule, createdSchedule.ID.Hex(), nil, createdSchedule)
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Jadwal kerja berhasil ditambahkan", "data": createdSchedule})
 }
 
@@ -123,7 +127,7 @@ func (h *WorkScheduleHandler) GetWorkScheduleById(c *fiber.Ctx) error {
 
 // GetAllWorkSchedules godoc
 // @Summary Get All Work Schedules (for Admin) or My Schedules (for Employee)
-// @Description Mengambil jadwal kerja. Admin melihat semua, karyawan melihat jadwal pribadinya.
+// @Description Mengambil jadwal kerja. Role dengan izin work_schedules.read.all melihat semua, user lain melihat jadwal pribadinya.
 // @Tags Work Schedule
 // @Accept json
 // @Produce json
@@ -157,15 +161,12 @@ func (h *WorkScheduleHandler) GetAllWorkSchedules(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Tipe data token di context tidak valid"})
 	}
 
-	// 2. Ekstrak ID dan Role dari claims
-	role := claims.Role
+	// 2. Ekstrak ID dari claims
 	userID := claims.UserID // Menggunakan UserID dari models.Claims
 	// ==============================================================
 
-	// Logika kondisional di bawah ini sekarang akan bekerja dengan benar
-	if role == "admin" {
-		// --- LOGIKA LAMA UNTUK ADMIN (MELIHAT SEMUA) ---
-		// Kode ini dipertahankan agar admin tetap bisa melihat semua jadwal tanpa filter.
+	// User dengan izin work_schedules.read.all (misal: admin) melihat semua jadwal tanpa filter.
+	if hasPermission(c.Context(), h.roleRepo, claims, models.PermissionWorkSchedulesReadAll) {
 		scheduleRules, err := h.workScheduleRepo.FindAllWithFilter(bson.M{})
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mengambil aturan jadwal"})
@@ -271,6 +272,9 @@ func (h *WorkScheduleHandler) UpdateWorkSchedule(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Validasi gagal: " + err.Error()})
 	}
 
+	// Jadwal yang tidak ditemukan dilaporkan oleh UpdateByID di bawah.
+	before, _ := h.workScheduleRepo.FindByID(objectID)
+
 	err = h.workScheduleRepo.UpdateByID(objectID, &payload)
 	if err != nil {
 		if strings.Contains(err.Error(), "jadwal tidak ditemukan") {
@@ -279,6 +283,9 @@ func (h *WorkScheduleHandler) UpdateWorkSchedule(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal memperbarui jadwal kerja", "details": err.Error()})
 	}
 
+	after, _ := h.workScheduleRepo.FindByID(objectID)
+	recordAudit(c, models.AuditActionWorkScheduleUpdate, models.AuditTargetWorkSchedule, objectID.Hex(), before, after)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Jadwal kerja berhasil diperbarui"})
 }
 
@@ -302,6 +309,9 @@ func (h *WorkScheduleHandler) DeleteWorkSchedule(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID jadwal kerja tidak valid"})
 	}
 
+	// Jadwal yang tidak ditemukan dilaporkan oleh DeleteByID di bawah.
+	before, _ := h.workScheduleRepo.FindByID(objectID)
+
 	err = h.workScheduleRepo.DeleteByID(objectID)
 	if err != nil {
 		if strings.Contains(err.Error(), "jadwal tidak ditemukan") {
@@ -310,5 +320,7 @@ func (h *WorkScheduleHandler) DeleteWorkSchedule(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal menghapus jadwal kerja", "details": err.Error()})
 	}
 
+	recordAudit(c, models.AuditActionWorkScheduleDelete, models.AuditTargetWorkSchedule, objectID.Hex(), before, nil)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Jadwal kerja berhasil dihapus"})
 }
@@ -4,6 +4,10 @@ package main
 
 import (
 	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/handlers"
+	"Sistem-Manajemen-Karyawan/pkg/notifier"
+	"Sistem-Manajemen-Karyawan/pkg/oidc"
+	"Sistem-Manajemen-Karyawan/pkg/paseto"
 	"Sistem-Manajemen-Karyawan/repository"
 	"Sistem-Manajemen-Karyawan/router"
 	// "Sistem-Manajemen-Karyawan/seeder"
@@ -11,6 +15,7 @@ import (
 	"context"
 
 	"log"
+	"time"
 
 	_ "Sistem-Manajemen-Karyawan/docs" // Import ini mungkin digunakan untuk Swagger
 
@@ -80,6 +85,42 @@ func main() {
 	leaveRequestRepo := repository.NewLeaveRequestRepository()
 	// Menggunakan 'deptRepo' sebagai nama variabel untuk DepartmentRepository
 	deptRepo := repository.NewDepartmentRepository() 
+	approvalChainRepo := repository.NewApprovalChainRepository()
+	approvalDelegationRepo := repository.NewApprovalDelegationRepository()
+	tokenRepo := repository.NewTokenRepository()
+	passwordResetRepo := repository.NewPasswordResetRepository()
+	securityEventRepo := repository.NewSecurityEventRepository()
+	twoFactorRepo := repository.NewTwoFactorRepository()
+	roleRepo := repository.NewRoleRepository()
+	apiKeyRepo := repository.NewAPIKeyRepository()
+	oidcStateRepo := repository.NewOIDCStateRepository()
+	auditLogRepo := repository.NewAuditLogRepository()
+	activityRepo := repository.NewActivityRepository()
+
+	ctxRoles, cancelRoles := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := roleRepo.EnsureDefaultRoles(ctxRoles); err != nil {
+		log.Fatal("Gagal menyiapkan role bawaan:", err)
+	}
+	cancelRoles()
+
+	pasetoMaker, err := paseto.NewPasetoMaker(cfg.PasetoKeyring)
+	if err != nil {
+		log.Fatal("Gagal menginisialisasi PASETO:", err)
+	}
+
+	notif, err := notifier.New(cfg.Notifier)
+	if err != nil {
+		log.Fatal("Gagal menginisialisasi notifier:", err)
+	}
+	if cfg.Notifier.Driver != notifier.DriverSMTP {
+		log.Printf("⚠️  PERINGATAN: NOTIFIER_DRIVER=%s, notifikasi (termasuk link reset password) TIDAK dikirim ke pengguna. Gunakan smtp di produksi.", cfg.Notifier.Driver)
+	}
+
+	var oidcProvider *oidc.Provider
+	if cfg.OIDC != nil {
+		oidcProvider = oidc.NewProvider(*cfg.OIDC)
+		log.Printf("Login SSO OIDC aktif dengan issuer %s", cfg.OIDC.IssuerURL)
+	}
 
 	// =======================================================
 	// Panggil Seeders (URUTAN PENTING DI SINI!)
@@ -111,8 +152,25 @@ func main() {
 	if err != nil {
 		log.Fatal("Gagal menambahkan cron job:", err)
 	}
+
+	// Handler pengajuan cuti dipakai bersama oleh rute API dan cron job eskalasi,
+	// sehingga keduanya menjalankan pemeriksaan yang sama.
+	leaveHandler := handlers.NewLeaveRequestHandler(leaveRequestRepo, attendanceRepo, userRepo, deptRepo, approvalChainRepo, approvalDelegationRepo, activityRepo, roleRepo, workScheduleRepo)
+
+	// Pengingat dan eskalasi pengajuan cuti yang terlalu lama pending, setiap hari pukul 08:00.
+	leaveEscalationJob := handlers.NewLeaveEscalationJob(leaveHandler, notif, cfg.LeaveEscalation)
+	_, err = c.AddFunc("0 8 * * *", func() {
+		if err := leaveEscalationJob.Run(context.Background()); err != nil {
+			log.Println("❌ Error saat menjalankan cron job eskalasi pengajuan:", err)
+		}
+	})
+	if err != nil {
+		log.Fatal("Gagal menambahkan cron job eskalasi pengajuan:", err)
+	}
 	c.Start()
 	log.Println("✅ Scheduler untuk status Alpha otomatis telah dimulai.")
+	log.Printf("✅ Scheduler pengingat pengajuan cuti aktif (pengingat: %d hari, tindakan: %s setelah %d hari).",
+		cfg.LeaveEscalation.ReminderAfterDays, cfg.LeaveEscalation.Action, cfg.LeaveEscalation.ActionAfterDays)
 
 	// =======================================================
 	// Setup Fiber App
@@ -123,7 +181,7 @@ func main() {
 
 	// DIUBAH: Pastikan semua instance repository dioper ke SetupRoutes
 	// Urutan argumen di sini harus sesuai dengan yang didefinisikan di router.SetupRoutes
-	router.SetupRoutes(app, userRepo, deptRepo, attendanceRepo, leaveRequestRepo, workScheduleRepo) 
+	router.SetupRoutes(app, userRepo, deptRepo, attendanceRepo, leaveRequestRepo, workScheduleRepo, approvalChainRepo, approvalDelegationRepo, tokenRepo, pasetoMaker, passwordResetRepo, securityEventRepo, twoFactorRepo, roleRepo, apiKeyRepo, oidcStateRepo, auditLogRepo, activityRepo, leaveHandler, notif, cfg.PasswordResetURL, cfg.TwoFactorIssuer, cfg.TwoFactorRequiredRoles, oidcProvider, cfg.PasswordLoginDisabledDomains, cfg.PasswordPolicy)
 
 	log.Printf("Server running on port %s", cfg.Port)
 	// log.Printf("API Documentation: http://localhost:%s/docs/index.html", cfg.Port) // Baris ini bisa diaktifkan jika perlu
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Jenis aktivitas pada feed aktivitas terbaru dashboard admin.
+const (
+	ActivityCheckIn        = "check_in"
+	ActivityLeaveSubmitted = "leave_submitted"
+	ActivityLeaveApproved  = "leave_approved"
+	ActivityLeaveRejected  = "leave_rejected"
+	ActivityEmployeeJoined = "employee_joined"
+	ActivityProfileUpdated = "profile_updated"
+)
+
+// Activity adalah satu kejadian pada feed aktivitas: check-in, pengajuan dan keputusan cuti,
+// karyawan baru, atau perubahan profil. Berbeda dengan AuditLog, Activity ditujukan untuk
+// ditampilkan ke admin sehingga berisi kalimat yang siap dibaca, bukan detail perubahan field.
+type Activity struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Type   string             `json:"type" bson:"type"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"` // karyawan yang bersangkutan
+	// UserName diisi dari data user saat feed dibaca, sehingga selalu mengikuti nama terbaru.
+	UserName string `json:"user_name,omitempty" bson:"user_name,omitempty"`
+	// ActorID adalah user yang melakukan tindakan, misalnya admin yang mendaftarkan karyawan atau
+	// approver yang memutuskan pengajuan. Kosong untuk tindakan sistem dan integrasi API key.
+	ActorID     *primitive.ObjectID `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	ReferenceID *primitive.ObjectID `json:"reference_id,omitempty" bson:"reference_id,omitempty"` // absensi atau pengajuan terkait
+	Message     string              `json:"message" bson:"message"`
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+}
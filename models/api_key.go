@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKeyPrefix menandai API key sehingga mudah dibedakan dari access token PASETO.
+const APIKeyPrefix = "smk_"
+
+// APIKey dipakai integrasi (misal: mesin absensi, tool BI) untuk memanggil API tanpa login user.
+// Hanya hash key yang disimpan; key asli ditampilkan sekali saat dibuat. Izin API key berdiri sendiri
+// (tidak mengikuti role pembuatnya) dan bisa dibatasi ke rentang IP tertentu.
+type APIKey struct {
+	ID           primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name         string              `json:"name" bson:"name"`
+	Prefix       string              `json:"prefix" bson:"prefix"` // beberapa karakter awal key untuk identifikasi
+	KeyHash      string              `json:"-" bson:"key_hash"`
+	Permissions  []string            `json:"permissions" bson:"permissions"`
+	AllowedCIDRs []string            `json:"allowed_cidrs,omitempty" bson:"allowed_cidrs,omitempty"` // kosong berarti semua IP
+	CreatedBy    primitive.ObjectID  `json:"created_by" bson:"created_by"`
+	ExpiresAt    *time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	RevokedAt    *time.Time          `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	RevokedBy    *primitive.ObjectID `json:"revoked_by,omitempty" bson:"revoked_by,omitempty"`
+	LastUsedAt   *time.Time          `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	LastUsedIP   string              `json:"last_used_ip,omitempty" bson:"last_used_ip,omitempty"`
+	CreatedAt    time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// APIKeyExcludedPermissions tidak bisa diberikan ke API key:
+//   - team.read dan leave.approve bergantung pada user yang login (tim milik manager, approver di
+//     rantai persetujuan), sedangkan API key bukan user;
+//   - api_keys.write dan roles.write memberi akses untuk membuat kredensial atau izin baru;
+//   - users.write dan users.security bisa mengubah role, password, dan 2FA user, sehingga key yang
+//     bocor dapat dipakai untuk mengambil alih akun.
+//
+// Key lama yang terlanjur memiliki izin ini tetap ditolak saat dipakai (lihat Claims.HasAPIKeyPermission).
+var APIKeyExcludedPermissions = map[string]bool{
+	PermissionTeamRead:      true,
+	PermissionLeaveApprove:  true,
+	PermissionAPIKeysWrite:  true,
+	PermissionRolesWrite:    true,
+	PermissionUsersWrite:    true,
+	PermissionUsersSecurity: true,
+}
+
+type CreateAPIKeyPayload struct {
+	Name         string     `json:"name" validate:"required,min=3,max=100"`
+	Permissions  []string   `json:"permissions" validate:"required,min=1"`
+	AllowedCIDRs []string   `json:"allowed_cidrs"` // contoh: ["10.0.5.0/24", "203.0.113.7"]
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
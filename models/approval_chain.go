@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Jenis approver pada satu langkah rantai persetujuan.
+const (
+	ApproverTypeRole           = "role"            // siapa saja dengan role tertentu (misal: admin)
+	ApproverTypeUser           = "user"            // user tertentu berdasarkan ID
+	ApproverTypeManager        = "manager"         // atasan langsung pemohon (User.ManagerID)
+	ApproverTypeDepartmentHead = "department_head" // kepala departemen pemohon (Department.HeadID)
+)
+
+// Keputusan pada satu langkah persetujuan.
+const (
+	ApprovalDecisionPending  = "pending"
+	ApprovalDecisionApproved = "approved"
+	ApprovalDecisionRejected = "rejected"
+)
+
+// ApprovalStepDefinition adalah definisi satu langkah di dalam ApprovalChain.
+type ApprovalStepDefinition struct {
+	Name         string              `json:"name" bson:"name" validate:"required,max=100"`
+	ApproverType string              `json:"approver_type" bson:"approver_type" validate:"required,oneof=role user manager department_head"`
+	ApproverRole string              `json:"approver_role,omitempty" bson:"approver_role,omitempty"`
+	ApproverID   *primitive.ObjectID `json:"approver_id,omitempty" bson:"approver_id,omitempty"`
+}
+
+// ApprovalChain mendefinisikan urutan persetujuan untuk jenis pengajuan dan/atau departemen tertentu.
+// RequestType atau Department yang kosong berarti berlaku untuk semua.
+type ApprovalChain struct {
+	ID          primitive.ObjectID       `json:"id,omitempty" bson:"_id,omitempty"`
+	Name        string                   `json:"name" bson:"name"`
+	RequestType string                   `json:"request_type,omitempty" bson:"request_type,omitempty"`
+	Department  string                   `json:"department,omitempty" bson:"department,omitempty"`
+	Steps       []ApprovalStepDefinition `json:"steps" bson:"steps"`
+	CreatedAt   time.Time                `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt   time.Time                `json:"updated_at" bson:"updated_at,omitempty"`
+}
+
+type ApprovalChainPayload struct {
+	Name        string                   `json:"name" validate:"required,min=3,max=100"`
+	RequestType string                   `json:"request_type,omitempty" validate:"omitempty,oneof=Cuti Sakit"`
+	Department  string                   `json:"department,omitempty"`
+	Steps       []ApprovalStepDefinition `json:"steps" validate:"required,min=1,max=10,dive"`
+}
+
+// ApprovalStep adalah salinan langkah rantai persetujuan yang disimpan di LeaveRequest,
+// lengkap dengan keputusan, pelaku, dan catatan.
+type ApprovalStep struct {
+	Level        int                 `json:"level" bson:"level"`
+	Name         string              `json:"name" bson:"name"`
+	ApproverType string              `json:"approver_type" bson:"approver_type"`
+	ApproverRole string              `json:"approver_role,omitempty" bson:"approver_role,omitempty"`
+	ApproverID   *primitive.ObjectID `json:"approver_id,omitempty" bson:"approver_id,omitempty"`
+	Decision     string              `json:"decision" bson:"decision"`
+	ActorID      *primitive.ObjectID `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	ActorEmail   string              `json:"actor_email,omitempty" bson:"actor_email,omitempty"`
+	OnBehalfOfID *primitive.ObjectID `json:"on_behalf_of_id,omitempty" bson:"on_behalf_of_id,omitempty"` // diisi jika keputusan diambil melalui delegasi
+	DelegationID *primitive.ObjectID `json:"delegation_id,omitempty" bson:"delegation_id,omitempty"`
+	Note         string              `json:"note,omitempty" bson:"note,omitempty"`
+	DecidedAt    *time.Time          `json:"decided_at,omitempty" bson:"decided_at,omitempty"`
+}
+
+// DefaultApprovalSteps dipakai jika tidak ada ApprovalChain yang cocok: satu langkah oleh atasan
+// langsung. Jika pemohon tidak punya atasan, langkah ini jatuh ke admin (lihat resolveApprovalSteps).
+func DefaultApprovalSteps() []ApprovalStepDefinition {
+	return []ApprovalStepDefinition{
+		{Name: "Persetujuan Atasan", ApproverType: ApproverTypeManager},
+	}
+}
+
+// AdminApprovalStep adalah langkah pengganti ketika approver sebuah langkah tidak dapat ditentukan.
+// Langkah ini dapat diputuskan semua pemegang izin leave.approve, bukan hanya role admin.
+func AdminApprovalStep(name string) ApprovalStepDefinition {
+	return ApprovalStepDefinition{Name: name, ApproverType: ApproverTypeRole, ApproverRole: RoleAdmin}
+}
+
+// IsAdminStep mengembalikan true jika langkah adalah langkah pengganti admin (lihat AdminApprovalStep).
+func (s *ApprovalStep) IsAdminStep() bool {
+	return s.ApproverType == ApproverTypeRole && s.ApproverRole == RoleAdmin
+}
+
+// NewApprovalSteps membuat daftar ApprovalStep (semua pending) dari definisi rantai.
+func NewApprovalSteps(definitions []ApprovalStepDefinition) []ApprovalStep {
+	steps := make([]ApprovalStep, 0, len(definitions))
+	for i, def := range definitions {
+		steps = append(steps, ApprovalStep{
+			Level:        i + 1,
+			Name:         def.Name,
+			ApproverType: def.ApproverType,
+			ApproverRole: def.ApproverRole,
+			ApproverID:   def.ApproverID,
+			Decision:     ApprovalDecisionPending,
+		})
+	}
+	return steps
+}
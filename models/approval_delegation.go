@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ApprovalDelegation memberikan hak persetujuan seorang approver (Delegator) kepada user lain
+// (Delegate) untuk rentang tanggal tertentu, misalnya selama approver sedang cuti.
+// Delegasi hanya mencakup langkah yang ditujukan langsung ke Delegator; langkah yang ditujukan ke
+// role tidak ikut didelegasikan.
+type ApprovalDelegation struct {
+	ID            primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	DelegatorID   primitive.ObjectID `json:"delegator_id" bson:"delegator_id"`
+	DelegatorRole string             `json:"delegator_role" bson:"delegator_role"` // role delegator saat delegasi dibuat, hanya sebagai catatan
+	DelegateID    primitive.ObjectID `json:"delegate_id" bson:"delegate_id"`
+	StartDate     string             `json:"start_date" bson:"start_date"` // YYYY-MM-DD, inklusif
+	EndDate       string             `json:"end_date" bson:"end_date"`     // YYYY-MM-DD, inklusif
+	Reason        string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	RevokedAt     *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at,omitempty"`
+}
+
+type ApprovalDelegationPayload struct {
+	DelegateID string `json:"delegate_id" validate:"required"`
+	StartDate  string `json:"start_date" validate:"required"`
+	EndDate    string `json:"end_date" validate:"required"`
+	Reason     string `json:"reason,omitempty" validate:"omitempty,max=255"`
+}
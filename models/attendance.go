@@ -13,10 +13,25 @@ type Attendance struct {
 	CheckIn  string             `json:"check_in" bson:"check_in,omitempty"`
 	CheckOut string             `json:"check_out" bson:"check_out,omitempty"`
 
-	Status    string    `json:"status" bson:"status,omitempty" validate:"omitempty,oneof=Hadir Terlambat Sakit Cuti Izin Alpha"`
-	Note      string    `json:"note" bson:"note,omitempty"`
-	CreatedAt time.Time `json:"created_at" bson:"created_at,omitempty"`
-	UpdatedAt time.Time `json:"updated_at" bson:"updated_at,omitempty"`
+	Status string `json:"status" bson:"status,omitempty" validate:"omitempty,oneof=Hadir Terlambat Sakit Cuti Izin Alpha"`
+	Note   string `json:"note" bson:"note,omitempty"`
+	// LateMinutes adalah keterlambatan check-in dari jam masuk jadwal, hanya diisi untuk status Terlambat.
+	LateMinutes int `json:"late_minutes,omitempty" bson:"late_minutes,omitempty"`
+	// LeaveRequestID diisi jika record ini dibuat/diubah oleh persetujuan pengajuan cuti,
+	// sehingga bisa di-rollback saat pengajuan tersebut ditarik.
+	LeaveRequestID *primitive.ObjectID `json:"leave_request_id,omitempty" bson:"leave_request_id,omitempty"`
+	// LeavePrevious menyimpan isi record sebelum diubah oleh persetujuan cuti, untuk dikembalikan
+	// saat pengajuan ditarik. Kosong jika record dibuat oleh persetujuan tersebut.
+	LeavePrevious *AttendanceLeaveSnapshot `json:"leave_previous,omitempty" bson:"leave_previous,omitempty"`
+	CreatedAt     time.Time                `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt     time.Time                `json:"updated_at" bson:"updated_at,omitempty"`
+}
+
+// AttendanceLeaveSnapshot adalah field absensi yang ditimpa oleh persetujuan cuti.
+type AttendanceLeaveSnapshot struct {
+	Status      string `json:"status" bson:"status"`
+	Note        string `json:"note,omitempty" bson:"note,omitempty"`
+	LateMinutes int    `json:"late_minutes,omitempty" bson:"late_minutes,omitempty"`
 }
 
 type AttendanceCreatePayload struct {
@@ -45,6 +60,7 @@ type AttendanceWithUser struct {
 	CheckOut       string             `json:"check_out,omitempty" bson:"check_out,omitempty"`
 	Status         string             `json:"status" bson:"status"`
 	Note           string             `json:"note,omitempty" bson:"note,omitempty"`
+	LateMinutes    int                `json:"late_minutes,omitempty" bson:"late_minutes,omitempty"`
 	UserName       string             `json:"user_name" bson:"user_name"`
 	UserEmail      string             `json:"user_email" bson:"user_email"`
 	UserPhoto      string             `json:"user_photo,omitempty" bson:"user_photo,omitempty"`
@@ -0,0 +1,60 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// AttendanceStatusCounts adalah jumlah record absensi per status beserta total menit keterlambatan.
+type AttendanceStatusCounts struct {
+	Hadir        int64 `json:"hadir" bson:"hadir"`
+	Terlambat    int64 `json:"terlambat" bson:"terlambat"`
+	Sakit        int64 `json:"sakit" bson:"sakit"`
+	Cuti         int64 `json:"cuti" bson:"cuti"`
+	Izin         int64 `json:"izin" bson:"izin"`
+	Alpha        int64 `json:"alpha" bson:"alpha"`
+	TotalRecords int64 `json:"total_records" bson:"total_records"`
+	// TotalLateMinutes tidak mencakup record Terlambat lama yang belum memiliki late_minutes (dihitung 0).
+	TotalLateMinutes int64 `json:"total_late_minutes" bson:"total_late_minutes"`
+	// AttendanceRate adalah persentase hari masuk (Hadir + Terlambat) dari hari yang wajib masuk.
+	// Hari Cuti dan Sakit tidak dihitung sebagai hari wajib masuk.
+	AttendanceRate float64 `json:"attendance_rate" bson:"-"`
+}
+
+// ComputeAttendanceRate mengisi AttendanceRate dari jumlah per status.
+func (c *AttendanceStatusCounts) ComputeAttendanceRate() {
+	present := c.Hadir + c.Terlambat
+	expected := present + c.Izin + c.Alpha
+	if expected == 0 {
+		c.AttendanceRate = 0
+		return
+	}
+	c.AttendanceRate = float64(present) * 100 / float64(expected)
+}
+
+// EmployeeAttendanceSummary adalah ringkasan absensi satu karyawan dalam satu periode.
+type EmployeeAttendanceSummary struct {
+	UserID                 primitive.ObjectID `json:"user_id" bson:"_id"`
+	UserName               string             `json:"user_name" bson:"user_name"`
+	UserDepartment         string             `json:"user_department,omitempty" bson:"user_department,omitempty"`
+	AttendanceStatusCounts `bson:",inline"`
+}
+
+// DepartmentAttendanceSummary adalah ringkasan absensi seluruh karyawan satu departemen dalam satu periode.
+type DepartmentAttendanceSummary struct {
+	Department             string `json:"department" bson:"_id"`
+	Employees              int64  `json:"employees" bson:"employees"` // karyawan yang memiliki record absensi pada periode
+	AttendanceStatusCounts `bson:",inline"`
+}
+
+// DailyAttendanceTrend adalah jumlah absensi per status pada satu tanggal.
+type DailyAttendanceTrend struct {
+	Date                   string `json:"date" bson:"_id"`
+	AttendanceStatusCounts `bson:",inline"`
+}
+
+// LatenessRanking adalah total keterlambatan satu karyawan dalam satu periode.
+type LatenessRanking struct {
+	UserID           primitive.ObjectID `json:"user_id" bson:"_id"`
+	UserName         string             `json:"user_name" bson:"user_name"`
+	UserDepartment   string             `json:"user_department,omitempty" bson:"user_department,omitempty"`
+	LateCount        int64              `json:"late_count" bson:"late_count"`
+	TotalLateMinutes int64              `json:"total_late_minutes" bson:"total_late_minutes"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Jenis objek yang diubah (AuditLog.TargetType).
+const (
+	AuditTargetUser          = "user"
+	AuditTargetDepartment    = "department"
+	AuditTargetLeaveRequest  = "leave_request"
+	AuditTargetApprovalChain = "approval_chain"
+	AuditTargetDelegation    = "approval_delegation"
+	AuditTargetWorkSchedule  = "work_schedule"
+	AuditTargetRole          = "role"
+	AuditTargetAPIKey        = "api_key"
+	AuditTargetAttendance    = "attendance"
+	AuditTargetSession       = "session"
+)
+
+// Aksi yang dicatat di audit log (AuditLog.Action), dengan format <objek>.<aksi>.
+const (
+	AuditActionUserCreate             = "user.create"
+	AuditActionUserUpdate             = "user.update"
+	AuditActionUserDelete             = "user.delete"
+	AuditActionUserPhotoUpload        = "user.photo_upload"
+	AuditActionUserPasswordChange     = "user.password_change"
+	AuditActionUserPasswordReset      = "user.password_reset"
+	AuditActionUserUnlock             = "user.unlock"
+	AuditActionUserSessionsRevoke     = "user.sessions_revoke"
+	AuditActionUserTwoFactorSetup     = "user.two_factor_setup"
+	AuditActionUserTwoFactorEnable    = "user.two_factor_enable"
+	AuditActionUserTwoFactorDisable   = "user.two_factor_disable"
+	AuditActionUserTwoFactorReset     = "user.two_factor_reset"
+	AuditActionUserRecoveryCodesRenew = "user.recovery_codes_renew"
+	AuditActionSessionRevoke          = "session.revoke"
+	AuditActionLogout                 = "auth.logout"
+	AuditActionLogoutAll              = "auth.logout_all"
+	AuditActionRoleCreate             = "role.create"
+	AuditActionRoleUpdate             = "role.update"
+	AuditActionRoleDelete             = "role.delete"
+	AuditActionAPIKeyCreate           = "api_key.create"
+	AuditActionAPIKeyRevoke           = "api_key.revoke"
+	AuditActionDepartmentCreate       = "department.create"
+	AuditActionDepartmentUpdate       = "department.update"
+	AuditActionDepartmentDelete       = "department.delete"
+	AuditActionApprovalChainCreate    = "approval_chain.create"
+	AuditActionApprovalChainUpdate    = "approval_chain.update"
+	AuditActionApprovalChainDelete    = "approval_chain.delete"
+	AuditActionDelegationCreate       = "approval_delegation.create"
+	AuditActionDelegationRevoke       = "approval_delegation.revoke"
+	AuditActionWorkScheduleCreate     = "work_schedule.create"
+	AuditActionWorkScheduleUpdate     = "work_schedule.update"
+	AuditActionWorkScheduleDelete     = "work_schedule.delete"
+	AuditActionAttendanceScan         = "attendance.scan"
+	AuditActionLeaveCreate            = "leave_request.create"
+	AuditActionLeaveUpdate            = "leave_request.update"
+	AuditActionLeaveResubmit          = "leave_request.resubmit"
+	AuditActionLeaveCancel            = "leave_request.cancel"
+	AuditActionLeaveAttachmentUpload  = "leave_request.attachment_upload"
+	AuditActionLeaveDecision          = "leave_request.decision"
+	AuditActionLeaveStatusUpdate      = "leave_request.status_update"
+	AuditActionLeaveWithdrawalResolve = "leave_request.withdrawal_resolve"
+)
+
+// AuditLog adalah satu entri audit perubahan data: siapa (actor) melakukan apa (action) terhadap
+// objek mana (target), beserta perbedaan nilai sebelum dan sesudahnya. Koleksi audit_logs hanya
+// ditambah, tidak pernah diubah atau dihapus oleh aplikasi.
+type AuditLog struct {
+	ID         primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	ActorID    *primitive.ObjectID `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	ActorEmail string              `json:"actor_email,omitempty" bson:"actor_email,omitempty"`
+	ActorRole  string              `json:"actor_role,omitempty" bson:"actor_role,omitempty"`
+	APIKeyID   *primitive.ObjectID `json:"api_key_id,omitempty" bson:"api_key_id,omitempty"` // diisi jika request memakai API key
+	Action     string              `json:"action" bson:"action"`                             // contoh: "user.update", "leave_request.status_update"
+	TargetType string              `json:"target_type,omitempty" bson:"target_type,omitempty"`
+	TargetID   string              `json:"target_id,omitempty" bson:"target_id,omitempty"`
+	Changes    []AuditChange       `json:"changes,omitempty" bson:"changes,omitempty"`
+	Method     string              `json:"method" bson:"method"`
+	Path       string              `json:"path" bson:"path"` // pola rute, contoh: /api/v1/users/:id
+	StatusCode int                 `json:"status_code" bson:"status_code"`
+	IPAddress  string              `json:"ip_address,omitempty" bson:"ip_address,omitempty"`
+	UserAgent  string              `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// AuditChange adalah perubahan satu field. Before kosong untuk data baru, After kosong untuk data
+// yang dihapus.
+type AuditChange struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After  interface{} `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// AuditLogFilter adalah filter pencarian audit log. Field kosong tidak dipakai sebagai filter.
+type AuditLogFilter struct {
+	ActorID    *primitive.ObjectID
+	Action     string
+	TargetType string
+	TargetID   string
+	From       *time.Time
+	To         *time.Time
+}
@@ -6,15 +6,32 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Kebijakan ketika persetujuan cuti membuat jumlah karyawan yang hadir di bawah MinStaffing.
+const (
+	StaffingPolicyWarn  = "warn"  // persetujuan tetap diproses, respons berisi peringatan
+	StaffingPolicyBlock = "block" // persetujuan ditolak dengan 409
+)
+
 type Department struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Name      string             `bson:"name" json:"name"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	Name           string              `bson:"name" json:"name"`
+	HeadID         *primitive.ObjectID `bson:"head_id,omitempty" json:"head_id,omitempty"`                                      // kepala departemen
+	MinStaffing    *int                `bson:"min_staffing,omitempty" json:"min_staffing,omitempty" validate:"omitempty,min=0"` // jumlah minimum karyawan yang hadir per hari
+	StaffingPolicy string              `bson:"staffing_policy,omitempty" json:"staffing_policy,omitempty" validate:"omitempty,oneof=warn block"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time           `bson:"updated_at" json:"updated_at"`
 }
 
-
 type DepartmentCount struct {
 	Department string `bson:"_id" json:"department"`
 	Count      int64  `bson:"count" json:"count"`
-}
\ No newline at end of file
+}
+
+// StaffingConflict menjelaskan tanggal di mana jumlah karyawan yang hadir akan di bawah batas minimum.
+type StaffingConflict struct {
+	Date        string `json:"date"`
+	Headcount   int    `json:"headcount"`
+	OnLeave     int    `json:"on_leave"`
+	Available   int    `json:"available"`
+	MinStaffing int    `json:"min_staffing"`
+}
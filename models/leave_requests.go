@@ -6,6 +6,22 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Durasi pengajuan. Pengajuan lama yang tidak punya field ini dianggap "full_day".
+const (
+	LeaveDurationFullDay = "full_day"
+	LeaveDurationHalfDay = "half_day"
+	LeaveDurationHourly  = "hourly"
+)
+
+// Periode untuk pengajuan setengah hari.
+const (
+	HalfDayPeriodAM = "AM"
+	HalfDayPeriodPM = "PM"
+)
+
+// AnnualLeaveDaysLimit adalah saldo cuti tahunan (dalam hari) untuk setiap karyawan.
+const AnnualLeaveDaysLimit = 12.0
+
 type LeaveRequest struct {
 	ID            primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
 	UserID        primitive.ObjectID `json:"user_id" bson:"user_id,omitempty"`
@@ -16,29 +32,199 @@ type LeaveRequest struct {
 	Note          string             `json:"note" bson:"note,omitempty"`
 	RequestType   string             `json:"request_type" bson:"request_type"` // "Cuti", "Sakit", "Izin"
 	AttachmentURL string             `json:"attachment_url,omitempty" bson:"attachment_url,omitempty"`
+	Duration      string             `json:"duration,omitempty" bson:"duration,omitempty"`               // "full_day", "half_day", "hourly"
+	HalfDayPeriod string             `json:"half_day_period,omitempty" bson:"half_day_period,omitempty"` // "AM" atau "PM", hanya untuk half_day
+	StartTime     string             `json:"start_time,omitempty" bson:"start_time,omitempty"`           // HH:MM, hanya untuk hourly
+	EndTime       string             `json:"end_time,omitempty" bson:"end_time,omitempty"`               // HH:MM, hanya untuk hourly
+	LeaveDays     float64            `json:"leave_days,omitempty" bson:"leave_days,omitempty"`           // Jumlah hari yang dipotong dari saldo cuti
+
+	// Pembatalan (pending) dan penarikan (approved) oleh karyawan
+	CancelReason          string     `json:"cancel_reason,omitempty" bson:"cancel_reason,omitempty"`
+	CancelledAt           *time.Time `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
+	WithdrawalRequested   bool       `json:"withdrawal_requested,omitempty" bson:"withdrawal_requested,omitempty"`
+	WithdrawalReason      string     `json:"withdrawal_reason,omitempty" bson:"withdrawal_reason,omitempty"`
+	WithdrawalRequestedAt *time.Time `json:"withdrawal_requested_at,omitempty" bson:"withdrawal_requested_at,omitempty"`
+	WithdrawnAt           *time.Time `json:"withdrawn_at,omitempty" bson:"withdrawn_at,omitempty"`
+
+	// Rantai persetujuan. CurrentStep adalah indeks (0-based) langkah yang sedang menunggu keputusan.
+	ApprovalChainID *primitive.ObjectID `json:"approval_chain_id,omitempty" bson:"approval_chain_id,omitempty"`
+	Approvals       []ApprovalStep      `json:"approvals,omitempty" bson:"approvals,omitempty"`
+	CurrentStep     int                 `json:"current_step" bson:"current_step"`
+
+	// Riwayat revisi (edit saat pending dan pengajuan ulang setelah ditolak).
+	Revisions       []LeaveRequestRevision `json:"revisions,omitempty" bson:"revisions,omitempty"`
+	ResubmittedFrom *primitive.ObjectID    `json:"resubmitted_from,omitempty" bson:"resubmitted_from,omitempty"` // pengajuan ditolak yang diajukan ulang
+	ResubmittedAs   *primitive.ObjectID    `json:"resubmitted_as,omitempty" bson:"resubmitted_as,omitempty"`     // pengajuan baru hasil pengajuan ulang
+
+	// Log tindakan otomatis oleh sistem (pengingat, eskalasi, persetujuan otomatis).
+	AutoActions []LeaveAutoAction `json:"auto_actions,omitempty" bson:"auto_actions,omitempty"`
+
 	CreatedAt     time.Time          `json:"created_at" bson:"created_at,omitempty"`
 	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at,omitempty"`
 }
 
+// LeaveRequestRevision adalah salinan isi pengajuan sebelum diubah, beserta daftar field yang berubah.
+// LeaveRequestID menunjuk pengajuan pemilik salinan tersebut, karena riwayat ikut dibawa saat pengajuan ulang.
+type LeaveRequestRevision struct {
+	Revision       int                `json:"revision" bson:"revision"`
+	LeaveRequestID primitive.ObjectID `json:"leave_request_id" bson:"leave_request_id"`
+	Kind           string             `json:"kind" bson:"kind"` // "edit" atau "resubmit"
+	Status         string             `json:"status" bson:"status"`
+	StartDate      string             `json:"start_date" bson:"start_date"`
+	EndDate        string             `json:"end_date" bson:"end_date"`
+	Reason         string             `json:"reason" bson:"reason"`
+	Duration       string             `json:"duration,omitempty" bson:"duration,omitempty"`
+	HalfDayPeriod  string             `json:"half_day_period,omitempty" bson:"half_day_period,omitempty"`
+	StartTime      string             `json:"start_time,omitempty" bson:"start_time,omitempty"`
+	EndTime        string             `json:"end_time,omitempty" bson:"end_time,omitempty"`
+	LeaveDays      float64            `json:"leave_days,omitempty" bson:"leave_days,omitempty"`
+	AttachmentURL  string             `json:"attachment_url,omitempty" bson:"attachment_url,omitempty"`
+	Note           string             `json:"note,omitempty" bson:"note,omitempty"` // catatan approver saat itu (misal: alasan penolakan)
+	ChangedFields  []string           `json:"changed_fields" bson:"changed_fields"`
+	RevisedBy      primitive.ObjectID `json:"revised_by" bson:"revised_by"`
+	RevisedAt      time.Time          `json:"revised_at" bson:"revised_at"`
+}
+
+// Jenis revisi pengajuan.
+const (
+	LeaveRevisionEdit     = "edit"
+	LeaveRevisionResubmit = "resubmit"
+)
+
+// NewLeaveRequestRevision membuat salinan prev dan mencatat field yang berbeda pada next.
+func NewLeaveRequestRevision(kind string, prev, next *LeaveRequest, revisedBy primitive.ObjectID) LeaveRequestRevision {
+	var changed []string
+	compare := func(field, before, after string) {
+		if before != after {
+			changed = append(changed, field)
+		}
+	}
+	compare("start_date", prev.StartDate, next.StartDate)
+	compare("end_date", prev.EndDate, next.EndDate)
+	compare("reason", prev.Reason, next.Reason)
+	compare("duration", prev.Duration, next.Duration)
+	compare("half_day_period", prev.HalfDayPeriod, next.HalfDayPeriod)
+	compare("start_time", prev.StartTime, next.StartTime)
+	compare("end_time", prev.EndTime, next.EndTime)
+	compare("attachment_url", prev.AttachmentURL, next.AttachmentURL)
+	if changed == nil {
+		changed = []string{}
+	}
+
+	return LeaveRequestRevision{
+		Revision:       len(prev.Revisions) + 1,
+		LeaveRequestID: prev.ID,
+		Kind:           kind,
+		Status:         prev.Status,
+		StartDate:      prev.StartDate,
+		EndDate:        prev.EndDate,
+		Reason:         prev.Reason,
+		Duration:       prev.Duration,
+		HalfDayPeriod:  prev.HalfDayPeriod,
+		StartTime:      prev.StartTime,
+		EndTime:        prev.EndTime,
+		LeaveDays:      prev.LeaveDays,
+		AttachmentURL:  prev.AttachmentURL,
+		Note:           prev.Note,
+		ChangedFields:  changed,
+		RevisedBy:      revisedBy,
+		RevisedAt:      time.Now(),
+	}
+}
+
+// LeaveAutoAction mencatat satu tindakan otomatis cron job terhadap pengajuan yang lama menunggu.
+type LeaveAutoAction struct {
+	Action      string    `json:"action" bson:"action"` // "reminder", "escalate", "auto_approve", "auto_approve_blocked"
+	StepLevel   int       `json:"step_level" bson:"step_level"`
+	StepName    string    `json:"step_name" bson:"step_name"`
+	PendingDays int       `json:"pending_days" bson:"pending_days"`
+	Recipients  []string  `json:"recipients,omitempty" bson:"recipients,omitempty"` // email penerima notifikasi
+	Detail      string    `json:"detail,omitempty" bson:"detail,omitempty"`
+	PerformedAt time.Time `json:"performed_at" bson:"performed_at"`
+}
+
+// Jenis tindakan otomatis.
+const (
+	LeaveAutoActionReminder           = "reminder"
+	LeaveAutoActionEscalate           = "escalate"
+	LeaveAutoActionAutoApprove        = "auto_approve"
+	LeaveAutoActionAutoApproveBlocked = "auto_approve_blocked" // dibatalkan karena batas minimum staf (kebijakan block) atau tidak ada approver selain pemohon
+)
+
+// PendingSince mengembalikan waktu mulai langkah aktif menunggu keputusan: keputusan langkah
+// sebelumnya, edit terakhir (yang memulai ulang rantai), atau waktu pengajuan dibuat.
+func (r *LeaveRequest) PendingSince() time.Time {
+	since := r.CreatedAt
+	if r.CurrentStep > 0 && r.CurrentStep <= len(r.Approvals) {
+		if prev := r.Approvals[r.CurrentStep-1].DecidedAt; prev != nil && prev.After(since) {
+			since = *prev
+		}
+	}
+	for _, revision := range r.Revisions {
+		if revision.Kind == LeaveRevisionEdit && revision.LeaveRequestID == r.ID && revision.RevisedAt.After(since) {
+			since = revision.RevisedAt
+		}
+	}
+	return since
+}
+
+// LastAutoAction mengembalikan tindakan otomatis terakhir dengan jenis tertentu pada langkah
+// tertentu yang terjadi setelah after, atau nil jika belum ada.
+func (r *LeaveRequest) LastAutoAction(action string, stepLevel int, after time.Time) *LeaveAutoAction {
+	for i := len(r.AutoActions) - 1; i >= 0; i-- {
+		entry := &r.AutoActions[i]
+		if entry.PerformedAt.Before(after) {
+			break
+		}
+		if entry.Action == action && entry.StepLevel == stepLevel {
+			return entry
+		}
+	}
+	return nil
+}
+
+// IsPartialDay mengembalikan true jika pengajuan hanya mencakup sebagian hari kerja
+// (setengah hari atau per jam), sehingga karyawan tetap diharapkan hadir.
+func (r *LeaveRequest) IsPartialDay() bool {
+	return r.Duration == LeaveDurationHalfDay || r.Duration == LeaveDurationHourly
+}
+
+// CurrentApproval mengembalikan langkah persetujuan yang sedang menunggu keputusan,
+// atau nil jika pengajuan tidak memiliki rantai persetujuan (data lama).
+func (r *LeaveRequest) CurrentApproval() *ApprovalStep {
+	if r.CurrentStep < 0 || r.CurrentStep >= len(r.Approvals) {
+		return nil
+	}
+	return &r.Approvals[r.CurrentStep]
+}
+
 // BARU: Tambahkan struct ini ke file models/leave_request.go Anda
 type LeaveRequestWithUser struct {
 	LeaveRequest `bson:",inline"` 
 	UserName     string           `json:"user_name" bson:"user_name"`    // <--- UBAH DARI "user_info.name" MENJADI "user_name"
 	UserEmail    string           `json:"user_email" bson:"user_email"`  // <--- UBAH DARI "user_info.email" MENJADI "user_email"
 	UserPhoto    string           `json:"user_photo,omitempty" bson:"user_photo,omitempty"` // <--- UBAH DARI "user_info.photo" MENJADI "user_photo"
+	UserDepartment string         `json:"user_department,omitempty" bson:"user_department,omitempty"`
 }
 
 type LeaveSummaryResponse struct {
-	CurrentMonthLeaveCount int64 `json:"current_month_leave_count"`
-	AnnualLeaveCount       int64 `json:"annual_leave_count"`
+	CurrentMonthLeaveCount int64   `json:"current_month_leave_count"`
+	AnnualLeaveCount       int64   `json:"annual_leave_count"`
+	AnnualLeaveDaysUsed    float64 `json:"annual_leave_days_used"`
+	AnnualLeaveDaysLimit   float64 `json:"annual_leave_days_limit"`
+	RemainingLeaveDays     float64 `json:"remaining_leave_days"`
 }
 
 type LeaveRequestCreatePayload struct {
-	UserID      string `json:"user_id" validate:"required"`
-	StartDate   string `json:"start_date" validate:"required,datetime=2006-01-02"`
-	EndDate     string `json:"end_date" validate:"required,datetime=2006-01-02,gtefield=StartDate"`
-	RequestType string `json:"request_type" validate:"required,oneof=Cuti Sakit"`
-	Reason      string `json:"reason" validate:"required,min=10,max=500"`
+	UserID        string `json:"user_id" validate:"required"`
+	StartDate     string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate       string `json:"end_date" validate:"required,datetime=2006-01-02,gtefield=StartDate"`
+	RequestType   string `json:"request_type" validate:"required,oneof=Cuti Sakit"`
+	Reason        string `json:"reason" validate:"required,min=10,max=500"`
+	Duration      string `json:"duration,omitempty" validate:"omitempty,oneof=full_day half_day hourly"`
+	HalfDayPeriod string `json:"half_day_period,omitempty" validate:"omitempty,oneof=AM PM"`
+	StartTime     string `json:"start_time,omitempty" validate:"omitempty,datetime=15:04"`
+	EndTime       string `json:"end_time,omitempty" validate:"omitempty,datetime=15:04"`
 }
 
 
@@ -46,4 +232,38 @@ type LeaveRequestCreatePayload struct {
 type LeaveRequestUpdatePayload struct {
 	Status string `json:"status" validate:"required,oneof=pending approved rejected"`
 	Note   string `json:"note,omitempty"`
-}
\ No newline at end of file
+}
+
+type LeaveRequestCancelPayload struct {
+	Reason string `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+type LeaveWithdrawalDecisionPayload struct {
+	Decision string `json:"decision" validate:"required,oneof=approved rejected"`
+	Note     string `json:"note,omitempty" validate:"omitempty,max=500"`
+}
+
+// LeaveCalendarEntry adalah satu pengajuan yang tampil pada satu hari di kalender cuti.
+type LeaveCalendarEntry struct {
+	LeaveRequestID primitive.ObjectID `json:"leave_request_id"`
+	UserID         primitive.ObjectID `json:"user_id"`
+	UserName       string             `json:"user_name"`
+	RequestType    string             `json:"request_type"`
+	Status         string             `json:"status"`
+	Duration       string             `json:"duration,omitempty"`
+	HalfDayPeriod  string             `json:"half_day_period,omitempty"`
+}
+
+// LeaveCalendarDay merangkum cuti approved dan pending pada satu tanggal untuk satu departemen.
+// Cuti setengah hari / per jam ditampilkan tetapi tidak mengurangi jumlah karyawan yang hadir.
+type LeaveCalendarDay struct {
+	Date                   string               `json:"date"`
+	Approved               []LeaveCalendarEntry `json:"approved"`
+	Pending                []LeaveCalendarEntry `json:"pending"`
+	Headcount              int                  `json:"headcount"`
+	OnLeave                int                  `json:"on_leave"`
+	PendingOnLeave         int                  `json:"pending_on_leave"`
+	MinStaffing            int                  `json:"min_staffing"`
+	BelowMinimum           bool                 `json:"below_minimum"`
+	BelowMinimumIfApproved bool                 `json:"below_minimum_if_approved"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OIDCLoginState menyimpan state login SSO yang sedang berjalan, dari redirect ke identity provider
+// sampai callback. Hanya hash state yang disimpan; nonce dan code verifier PKCE dipakai saat
+// menukar authorization code dan dihapus setelah dipakai sekali.
+type OIDCLoginState struct {
+	ID           primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	StateHash    string             `json:"-" bson:"state_hash"`
+	Nonce        string             `json:"-" bson:"nonce"`
+	CodeVerifier string             `json:"-" bson:"code_verifier"`
+	ExpiresAt    time.Time          `json:"expires_at" bson:"expires_at"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type OIDCCallbackPayload struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
@@ -7,12 +7,12 @@ import (
 )
 
 type QRCode struct {
-	ID        primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
-	Code      string               `json:"code" bson:"code,omitempty"`
-	Date      string               `json:"date" bson:"date,omitempty"` 
-	ExpiresAt time.Time            `json:"expires_at" bson:"expires_at,omitempty"`
-	CreatedAt time.Time            `json:"created_at" bson:"created_at,omitempty"`
-	UpdatedAt time.Time            `json:"updated_at" bson:"updated_at,omitempty"` 
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Code      string             `json:"code" bson:"code,omitempty"`
+	Date      string             `json:"date" bson:"date,omitempty"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at,omitempty"`
 }
 
 type QRCodeGeneratePayload struct {
@@ -21,5 +21,7 @@ type QRCodeGeneratePayload struct {
 
 type QRCodeScanPayload struct {
 	QRCodeValue string `json:"qr_code_value" validate:"required"`
-	UserID      string `json:"user_id" validate:"required"`
+	// UserID wajib diisi pemegang izin attendance.scan (mis. API key badge reader). User lain hanya
+	// boleh mengosongkannya atau mengisi ID-nya sendiri.
+	UserID string `json:"user_id,omitempty"`
 }
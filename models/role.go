@@ -0,0 +1,156 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Daftar izin (permission) yang dikenal aplikasi. Role hanya boleh berisi izin dari daftar ini.
+// Konvensi penamaan: <sumber>.<aksi>[.all], ".all" berarti berlaku untuk data semua user.
+const (
+	PermissionUsersReadAll         = "users.read.all"
+	PermissionUsersWrite           = "users.write"
+	PermissionUsersDelete          = "users.delete"
+	PermissionUsersSecurity        = "users.security"
+	PermissionDashboardRead        = "dashboard.read"
+	PermissionDepartmentsWrite     = "departments.write"
+	PermissionApprovalChainsRead   = "approval_chains.read"
+	PermissionApprovalChainsWrite  = "approval_chains.write"
+	PermissionAttendanceReadAll    = "attendance.read.all"
+	PermissionAttendanceQRGenerate = "attendance.qr.generate"
+	PermissionAttendanceScan       = "attendance.scan"
+	PermissionLeaveReadAll         = "leave.read.all"
+	PermissionLeaveApprove         = "leave.approve"
+	PermissionDelegationsManageAll = "delegations.manage.all"
+	PermissionWorkSchedulesReadAll = "work_schedules.read.all"
+	PermissionWorkSchedulesWrite   = "work_schedules.write"
+	PermissionTeamRead             = "team.read"
+	PermissionRolesRead            = "roles.read"
+	PermissionRolesWrite           = "roles.write"
+	PermissionAPIKeysRead          = "api_keys.read"
+	PermissionAPIKeysWrite         = "api_keys.write"
+	PermissionAuditLogsRead        = "audit_logs.read"
+
+	// PermissionAll memberi seluruh izin, termasuk izin yang ditambahkan di versi berikutnya.
+	// Hanya dipakai role sistem admin.
+	PermissionAll = "*"
+)
+
+// PermissionInfo menjelaskan satu izin untuk ditampilkan di layar pengaturan role.
+type PermissionInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Permissions adalah katalog izin yang dikenal aplikasi.
+var Permissions = []PermissionInfo{
+	{PermissionUsersReadAll, "Melihat profil, foto, dan riwayat keamanan semua user"},
+	{PermissionUsersWrite, "Mengubah data semua user, termasuk gaji, atasan, dan role"},
+	{PermissionUsersDelete, "Menghapus user"},
+	{PermissionUsersSecurity, "Mencabut sesi, membuka kunci akun, dan mereset 2FA user"},
+	{PermissionDashboardRead, "Melihat statistik dan feed aktivitas dashboard"},
+	{PermissionDepartmentsWrite, "Membuat, mengubah, dan menghapus departemen"},
+	{PermissionApprovalChainsRead, "Melihat rantai persetujuan"},
+	{PermissionApprovalChainsWrite, "Membuat, mengubah, dan menghapus rantai persetujuan"},
+	{PermissionAttendanceReadAll, "Melihat kehadiran semua karyawan"},
+	{PermissionAttendanceQRGenerate, "Membuat QR code absensi"},
+	{PermissionAttendanceScan, "Mencatat check-in karyawan lain lewat scan QR, mis. API key badge reader"},
+	{PermissionLeaveReadAll, "Melihat semua pengajuan cuti/izin dan kalender cuti"},
+	{PermissionLeaveApprove, "Mengubah status pengajuan dan memutuskan pembatalan cuti di luar rantai persetujuan"},
+	{PermissionDelegationsManageAll, "Mencabut delegasi persetujuan milik user lain"},
+	{PermissionWorkSchedulesReadAll, "Melihat semua aturan jadwal kerja"},
+	{PermissionWorkSchedulesWrite, "Membuat, mengubah, dan menghapus jadwal kerja"},
+	{PermissionTeamRead, "Melihat data tim yang dipimpin (anggota, kehadiran, cuti)"},
+	{PermissionRolesRead, "Melihat role dan izinnya"},
+	{PermissionRolesWrite, "Membuat, mengubah, dan menghapus role"},
+	{PermissionAPIKeysRead, "Melihat daftar API key integrasi"},
+	{PermissionAPIKeysWrite, "Membuat dan mencabut API key integrasi"},
+	{PermissionAuditLogsRead, "Melihat audit log perubahan data oleh semua user"},
+}
+
+// IsKnownPermission mengembalikan true jika name ada di katalog izin.
+func IsKnownPermission(name string) bool {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Role sistem yang selalu tersedia. User lama menyimpan role sebagai nama, sehingga role
+// direferensikan lewat Name (bukan ID) dan nama role tidak bisa diubah.
+const (
+	RoleAdmin    = "admin"
+	RoleManager  = "manager"
+	RoleKaryawan = "karyawan"
+)
+
+// Role memetakan nama role ke daftar izin. Role sistem tidak bisa dihapus.
+type Role struct {
+	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	Permissions []string           `json:"permissions" bson:"permissions"`
+	IsSystem    bool               `json:"is_system" bson:"is_system"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// HasPermission mengembalikan true jika role memiliki izin tersebut.
+func (r *Role) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission || p == PermissionAll {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRoles dibuat saat aplikasi start jika belum ada. Role yang sudah ada tidak ditimpa,
+// sehingga perubahan izin oleh admin tetap dipertahankan.
+var DefaultRoles = []Role{
+	{
+		Name:        RoleAdmin,
+		Description: "Akses penuh ke seluruh fitur",
+		Permissions: []string{PermissionAll},
+		IsSystem:    true,
+	},
+	{
+		Name:        RoleManager,
+		Description: "Atasan yang memimpin tim",
+		Permissions: []string{PermissionTeamRead},
+		IsSystem:    true,
+	},
+	{
+		Name:        RoleKaryawan,
+		Description: "Karyawan tanpa akses administratif",
+		Permissions: []string{},
+		IsSystem:    true,
+	},
+	{
+		Name:        "hr_viewer",
+		Description: "Staf HR yang dapat melihat semua data tanpa mengubah apa pun",
+		Permissions: []string{
+			PermissionUsersReadAll,
+			PermissionDashboardRead,
+			PermissionApprovalChainsRead,
+			PermissionAttendanceReadAll,
+			PermissionLeaveReadAll,
+			PermissionWorkSchedulesReadAll,
+			PermissionRolesRead,
+		},
+	},
+}
+
+type CreateRolePayload struct {
+	Name        string   `json:"name" validate:"required,min=3,max=50"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+type UpdateRolePayload struct {
+	Description *string  `json:"description" validate:"omitempty,max=255"`
+	Permissions []string `json:"permissions"`
+}
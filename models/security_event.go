@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Jenis kejadian keamanan akun.
+const (
+	SecurityEventLoginSuccess    = "login_success"
+	SecurityEventLoginFailed     = "login_failed"
+	SecurityEventAccountLocked   = "account_locked"
+	SecurityEventAccountUnlocked = "account_unlocked"
+	SecurityEventSessionRevoked  = "session_revoked" // satu sesi diakhiri oleh user atau admin
+
+	SecurityEventTwoFactorEnabled     = "two_factor_enabled"
+	SecurityEventTwoFactorDisabled    = "two_factor_disabled"
+	SecurityEventTwoFactorReset       = "two_factor_reset" // dinonaktifkan oleh admin
+	SecurityEventRecoveryCodesRenewed = "recovery_codes_renewed"
+	SecurityEventRecoveryCodeUsed     = "recovery_code_used"
+)
+
+// Alasan kegagalan login pada SecurityEvent.Reason.
+const (
+	LoginFailureInvalidPassword  = "invalid_password"
+	LoginFailureUnknownEmail     = "unknown_email"
+	LoginFailureAccountLocked    = "account_locked"
+	LoginFailureIPThrottled      = "ip_throttled"
+	LoginFailureInvalidTwoFactor = "invalid_two_factor_code"
+	LoginFailureSSOUnknownEmail  = "sso_unknown_email" // login SSO berhasil di IdP tetapi email tidak terdaftar
+	LoginFailureSSOUnverified    = "sso_email_unverified"
+	LoginFailureAccountInactive  = "account_inactive" // status kepegawaian tidak aktif
+)
+
+// SecurityEvent adalah satu entri riwayat keamanan akun (login berhasil/gagal, penguncian, dsb).
+// UserID kosong untuk percobaan login dengan email yang tidak terdaftar.
+type SecurityEvent struct {
+	ID        primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID    *primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Type      string              `json:"type" bson:"type"`
+	Email     string              `json:"email,omitempty" bson:"email,omitempty"` // email yang dipakai saat login
+	Reason    string              `json:"reason,omitempty" bson:"reason,omitempty"`
+	IPAddress string              `json:"ip_address,omitempty" bson:"ip_address,omitempty"`
+	UserAgent string              `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	ActorID   *primitive.ObjectID `json:"actor_id,omitempty" bson:"actor_id,omitempty"` // admin yang melakukan tindakan, jika ada
+	Detail    string              `json:"detail,omitempty" bson:"detail,omitempty"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken disimpan di server dalam bentuk hash. Setiap login membuka satu sesi (SessionID);
+// setiap refresh mengganti token lama dengan token baru pada sesi yang sama (rotasi).
+// Token lama yang dipakai ulang dianggap bocor dan seluruh sesinya dicabut.
+type RefreshToken struct {
+	ID         primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	SessionID  string              `json:"session_id" bson:"session_id"`
+	TokenHash  string              `json:"-" bson:"token_hash"` // SHA-256 dari token, token asli hanya dikirim ke client
+	ExpiresAt  time.Time           `json:"expires_at" bson:"expires_at"`
+	RevokedAt  *time.Time          `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	ReplacedBy *primitive.ObjectID `json:"replaced_by,omitempty" bson:"replaced_by,omitempty"`
+	UserAgent  string              `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	IPAddress  string              `json:"ip_address,omitempty" bson:"ip_address,omitempty"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// Session adalah satu sesi login (satu perangkat). Access token dan refresh token membawa SessionID;
+// AuthMiddleware menolak token dari sesi yang sudah diakhiri atau kedaluwarsa. ExpiresAt mengikuti
+// refresh token terakhir pada sesi ini dan diperpanjang setiap refresh.
+type Session struct {
+	ID         primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	SessionID  string             `json:"-" bson:"session_id"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Device     string             `json:"device" bson:"device"` // ringkasan user agent, misal: "Chrome di Windows"
+	UserAgent  string             `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	IPAddress  string             `json:"ip_address,omitempty" bson:"ip_address,omitempty"` // IP saat login
+	LastSeenIP string             `json:"last_seen_ip,omitempty" bson:"last_seen_ip,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	LastSeenAt time.Time          `json:"last_seen_at" bson:"last_seen_at"`
+	ExpiresAt  time.Time          `json:"expires_at" bson:"expires_at"`
+	RevokedAt  *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	Current    bool               `json:"current" bson:"-"` // true untuk sesi yang dipakai request ini
+}
+
+// IsActive mengembalikan true jika sesi belum diakhiri dan belum kedaluwarsa.
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RevokedToken adalah entri daftar pencabutan access token yang diperiksa AuthMiddleware.
+// Entri per token mengisi TokenID; entri per user mengisi RevokedBefore sehingga semua access token
+// user tersebut yang terbit sebelum waktu itu ditolak. Entri dihapus otomatis (TTL) setelah ExpiresAt,
+// karena access token yang sudah kedaluwarsa memang tidak lagi valid.
+type RevokedToken struct {
+	ID            primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	TokenID       string             `json:"token_id,omitempty" bson:"token_id,omitempty"`
+	UserID        primitive.ObjectID `json:"user_id" bson:"user_id"`
+	RevokedBefore *time.Time         `json:"revoked_before,omitempty" bson:"revoked_before,omitempty"`
+	ExpiresAt     time.Time          `json:"expires_at" bson:"expires_at"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TokenPair adalah access token berumur pendek dan refresh token yang dikirim ke client.
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	AccessExpiresAt  time.Time `json:"token_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// PasswordResetToken adalah token sekali pakai untuk reset password mandiri. Hanya hash token yang
+// disimpan; token asli dikirim ke user melalui notifier.
+type PasswordResetToken struct {
+	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	TokenHash   string             `json:"-" bson:"token_hash"`
+	ExpiresAt   time.Time          `json:"expires_at" bson:"expires_at"`
+	UsedAt      *time.Time         `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	RequestedIP string             `json:"requested_ip,omitempty" bson:"requested_ip,omitempty"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type PasswordResetRequestPayload struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type PasswordResetConfirmPayload struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"` // aturan dari kebijakan password
+}
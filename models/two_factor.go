@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TwoFactorChallenge dibuat saat password benar tetapi user memakai 2FA. Token tantangan
+// (hanya hash-nya yang disimpan) ditukar dengan access token setelah kode TOTP/pemulihan valid.
+type TwoFactorChallenge struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+	Attempts  int                `json:"attempts" bson:"attempts"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	UsedAt    *time.Time         `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type TwoFactorCodePayload struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// TwoFactorVerifyPayload dipakai pada langkah kedua login. Isi salah satu: Code atau RecoveryCode.
+type TwoFactorVerifyPayload struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code,omitempty" validate:"omitempty,len=6,numeric"`
+	RecoveryCode   string `json:"recovery_code,omitempty" validate:"omitempty,max=32"`
+}
+
+type TwoFactorDisablePayload struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"` // PNG dalam bentuk data URI base64
+}
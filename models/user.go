@@ -6,35 +6,90 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Status kepegawaian. User tanpa status (data lama) dianggap aktif.
+const (
+	EmploymentStatusActive    = "active"
+	EmploymentStatusProbation = "probation" // masa percobaan, tetap dihitung sebagai karyawan aktif
+	EmploymentStatusInactive  = "inactive"  // sudah keluar atau diberhentikan
+)
+
 type User struct {
-	ID           primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Name         string             `json:"name" bson:"name,omitempty"`
-	Email        string             `json:"email" bson:"email,omitempty"`
-	Password     string             `json:"password" bson:"password,omitempty"`
-	Role         string             `json:"role" bson:"role,omitempty"`
-	Position     string             `json:"position" bson:"position,omitempty"`
-	Department   string             `json:"department" bson:"department,omitempty"`
-	BaseSalary   float64            `json:"base_salary" bson:"base_salary,omitempty"`
-	Address      string             `json:"address" bson:"address,omitempty"`
-	Photo        string             `json:"photo" bson:"photo,omitempty"` // bisa dihapus kalau tidak digunakan lagi
-	PhotoID      primitive.ObjectID `json:"photo_id,omitempty" bson:"photo_id,omitempty"`
-	PhotoMime    string             `json:"photo_mime,omitempty" bson:"photo_mime,omitempty"`
-	IsFirstLogin bool               `json:"is_first_login" bson:"isFirstLogin,omitempty"`
-	CreatedAt    time.Time          `json:"created_at" bson:"created_at,omitempty"`
-	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at,omitempty"`
+	ID               primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name             string              `json:"name" bson:"name,omitempty"`
+	Email            string              `json:"email" bson:"email,omitempty"`
+	Password         string              `json:"password" bson:"password,omitempty"`
+	Role             string              `json:"role" bson:"role,omitempty"`
+	Position         string              `json:"position" bson:"position,omitempty"`
+	Department       string              `json:"department" bson:"department,omitempty"`
+	BaseSalary       float64             `json:"base_salary" bson:"base_salary,omitempty"`
+	Address          string              `json:"address" bson:"address,omitempty"`
+	Photo            string              `json:"photo" bson:"photo,omitempty"` // bisa dihapus kalau tidak digunakan lagi
+	PhotoID          primitive.ObjectID  `json:"photo_id,omitempty" bson:"photo_id,omitempty"`
+	PhotoMime        string              `json:"photo_mime,omitempty" bson:"photo_mime,omitempty"`
+	IsFirstLogin     bool                `json:"is_first_login" bson:"isFirstLogin,omitempty"`
+	ManagerID        *primitive.ObjectID `json:"manager_id,omitempty" bson:"manager_id,omitempty"` // atasan langsung
+	EmploymentStatus string              `json:"employment_status,omitempty" bson:"employment_status,omitempty"`
+
+	// Proteksi brute-force: jumlah login gagal berturut-turut dan penguncian sementara.
+	FailedLoginCount  int        `json:"failed_login_count,omitempty" bson:"failed_login_count,omitempty"`
+	LastFailedLoginAt *time.Time `json:"last_failed_login_at,omitempty" bson:"last_failed_login_at,omitempty"`
+	LockedUntil       *time.Time `json:"locked_until,omitempty" bson:"locked_until,omitempty"`
+
+	// Autentikasi dua faktor (TOTP). Secret dan hash kode pemulihan tidak pernah dikirim ke client.
+	TwoFactorEnabled       bool       `json:"two_factor_enabled" bson:"two_factor_enabled,omitempty"`
+	TwoFactorEnabledAt     *time.Time `json:"two_factor_enabled_at,omitempty" bson:"two_factor_enabled_at,omitempty"`
+	TwoFactorSecret        string     `json:"-" bson:"two_factor_secret,omitempty"`
+	TwoFactorPendingSecret string     `json:"-" bson:"two_factor_pending_secret,omitempty"` // secret yang sedang didaftarkan, belum dikonfirmasi
+	TwoFactorLastStep      int64      `json:"-" bson:"two_factor_last_step,omitempty"`      // periode TOTP terakhir yang dipakai (anti replay)
+	RecoveryCodeHashes     []string   `json:"-" bson:"recovery_code_hashes,omitempty"`
+
+	// Riwayat password: hash password sebelumnya (terbaru lebih dulu) untuk mencegah pemakaian ulang,
+	// dan waktu password terakhir diganti untuk masa kedaluwarsa password.
+	PasswordHistory   []string   `json:"-" bson:"password_history,omitempty"`
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty" bson:"password_changed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at,omitempty"`
 }
 
+// TeamMember adalah tampilan data karyawan untuk manager: tanpa password dan gaji.
+type TeamMember struct {
+	ID         primitive.ObjectID  `json:"id"`
+	Name       string              `json:"name"`
+	Email      string              `json:"email"`
+	Role       string              `json:"role"`
+	Position   string              `json:"position"`
+	Department string              `json:"department"`
+	Photo      string              `json:"photo,omitempty"`
+	ManagerID  *primitive.ObjectID `json:"manager_id,omitempty"`
+}
+
+// ToTeamMember mengubah User menjadi TeamMember.
+func (u *User) ToTeamMember() TeamMember {
+	return TeamMember{
+		ID:         u.ID,
+		Name:       u.Name,
+		Email:      u.Email,
+		Role:       u.Role,
+		Position:   u.Position,
+		Department: u.Department,
+		Photo:      u.Photo,
+		ManagerID:  u.ManagerID,
+	}
+}
 
 type UserRegisterPayload struct {
-	Name       string  `json:"name" validate:"required,min=3,max=100"`
-	Email      string  `json:"email" validate:"required,email"` 
-	Password   string  `json:"password" validate:"required,min=8,max=50,hasuppercase"`
-	Role       string  `json:"role" validate:"required,oneof=admin karyawan"`
-	Position   string  `json:"position"`
-	Department string  `json:"department"`
-	BaseSalary float64 `json:"base_salary" validate:"min=0"`
-	Address    string  `json:"address" validate:"omitempty,min=5,max=255"`
-	Photo      string  `json:"photo" validate:"omitempty,url"`
+	Name             string  `json:"name" validate:"required,min=3,max=100"`
+	Email            string  `json:"email" validate:"required,email"`
+	Password         string  `json:"password" validate:"required"` // aturan dari kebijakan password (GET /auth/password-policy)
+	Role             string  `json:"role" validate:"required"`     // nama role dari koleksi roles
+	Position         string  `json:"position"`
+	Department       string  `json:"department"`
+	BaseSalary       float64 `json:"base_salary" validate:"min=0"`
+	Address          string  `json:"address" validate:"omitempty,min=5,max=255"`
+	Photo            string  `json:"photo" validate:"omitempty,url"`
+	ManagerID        string  `json:"manager_id,omitempty"`
+	EmploymentStatus string  `json:"employment_status,omitempty" validate:"omitempty,oneof=active probation inactive"` // default: active
 }
 
 type UserLoginPayload struct {
@@ -43,13 +98,16 @@ type UserLoginPayload struct {
 }
 
 type UserUpdatePayload struct {
-	Name       string  `json:"name,omitempty"`
-	Email      string  `json:"email,omitempty" validate:"omitempty,email"`
-	Position   string  `json:"position,omitempty"`
-	Department string  `json:"department,omitempty"`
-	BaseSalary float64 `json:"base_salary,omitempty" validate:"omitempty,min=0"`
-	Address    string  `json:"address,omitempty" validate:"omitempty,min=5,max=255"`
-	Photo      string  `json:"photo,omitempty" validate:"omitempty,url"`
+	Name             string  `json:"name,omitempty"`
+	Email            string  `json:"email,omitempty" validate:"omitempty,email"`
+	Position         string  `json:"position,omitempty"`
+	Department       string  `json:"department,omitempty"`
+	BaseSalary       float64 `json:"base_salary,omitempty" validate:"omitempty,min=0"`
+	Address          string  `json:"address,omitempty" validate:"omitempty,min=5,max=255"`
+	Photo            string  `json:"photo,omitempty" validate:"omitempty,url"`
+	ManagerID        string  `json:"manager_id,omitempty"`
+	Role             string  `json:"role,omitempty"`
+	EmploymentStatus string  `json:"employment_status,omitempty" validate:"omitempty,oneof=active probation inactive"`
 }
 
 type Claims struct {
@@ -57,22 +115,72 @@ type Claims struct {
 	Email        string             `json:"email"`
 	Role         string             `json:"role"`
 	IsFirstLogin bool               `json:"is_first_login"`
+	TokenID      string             `json:"token_id"`   // ID unik access token (jti), dipakai untuk pencabutan
+	SessionID    string             `json:"session_id"` // sesi refresh token tempat access token diterbitkan
+	IssuedAt     time.Time          `json:"issued_at"`
+	ExpiresAt    time.Time          `json:"expires_at"`
+	// TwoFactorSetupRequired diisi jika role user wajib 2FA tetapi user belum mendaftarkannya;
+	// token seperti ini hanya boleh dipakai untuk mendaftarkan 2FA.
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty"`
+	// PasswordExpired diisi jika password user sudah melewati umur maksimum kebijakan password;
+	// seperti IsFirstLogin, token ini hanya boleh dipakai untuk mengganti password.
+	PasswordExpired bool `json:"password_expired,omitempty"`
+	// APIKeyID dan Permissions diisi jika request memakai API key, bukan login user. Izin API key
+	// dibatasi pada Permissions, bukan izin role; UserID berisi admin pembuat key.
+	APIKeyID    *primitive.ObjectID `json:"api_key_id,omitempty"`
+	Permissions []string            `json:"permissions,omitempty"`
+}
+
+// HasAPIKeyPermission memeriksa izin API key pada claims. Izin di APIKeyExcludedPermissions selalu
+// ditolak, termasuk untuk key yang dibuat sebelum izin tersebut dikecualikan.
+func (c *Claims) HasAPIKeyPermission(permission string) bool {
+	if APIKeyExcludedPermissions[permission] {
+		return false
+	}
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
 }
+
 type ChangePasswordPayload struct {
 	OldPassword string `json:"old_password" validate:"required"`
-	NewPassword string `json:"new_password" validate:"required,min=8,max=50,hasuppercase"`
+	NewPassword string `json:"new_password" validate:"required"` // aturan dari kebijakan password
 }
 
+// TeamDashboardStats adalah ringkasan tim untuk dashboard manager.
+type TeamDashboardStats struct {
+	TotalAnggota          int64            `json:"total_anggota"`
+	KehadiranHariIni      map[string]int64 `json:"kehadiran_hari_ini"`
+	BelumAbsen            int64            `json:"belum_absen"`
+	CutiHariIni           int64            `json:"cuti_hari_ini"`
+	PengajuanMenungguSaya int64            `json:"pengajuan_menunggu_saya"`
+}
 
 type DashboardStats struct {
-	TotalKaryawan         int64             `json:"total_karyawan"`
-	KaryawanAktif         int64             `json:"karyawan_aktif"`
-	KaryawanCuti          int64             `json:"karyawan_cuti"`
-	PendingLeaveRequestsCount int64         `json:"pending_leave_requests_count"` 
-	PosisiBaru            int64             `json:"posisi_baru"`
-	TotalDepartemen       int64             `json:"total_departemen"` 
-	DistribusiDepartemen  []DepartmentCount `json:"distribusi_departemen"`
-	AktivitasTerbaru      []string          `json:"aktivitas_terbaru"`
+	TotalKaryawan             int64             `json:"total_karyawan"`
+	KaryawanAktif             int64             `json:"karyawan_aktif"` // status kepegawaian selain inactive, tanpa akun admin
+	KaryawanCuti              int64             `json:"karyawan_cuti"`  // cuti penuh hari ini (pengajuan penuh, atau AM dan PM sekaligus)
+	PendingLeaveRequestsCount int64             `json:"pending_leave_requests_count"`
+	KaryawanBaru              int64             `json:"karyawan_baru"` // bergabung dalam 30 hari terakhir
+	PosisiBaru                int64             `json:"posisi_baru"`   // posisi yang pertama kali diisi dalam 30 hari terakhir
+	TotalDepartemen           int64             `json:"total_departemen"`
+	KehadiranHariIni          map[string]int64  `json:"kehadiran_hari_ini"` // jumlah absensi hari ini per status (Hadir, Terlambat, Alpha, ...)
+	Tren                      DashboardTrend    `json:"tren"`
+	DistribusiDepartemen      []DepartmentCount `json:"distribusi_departemen"`
+	AktivitasTerbaru          []Activity        `json:"aktivitas_terbaru"`
 }
 
-
+// DashboardTrend berisi selisih statistik dashboard terhadap periode sebelumnya. Statistik harian
+// dibandingkan dengan hari yang sama minggu lalu agar pola hari kerja tidak memengaruhi tren;
+// KaryawanBaru dan PosisiBaru dibandingkan dengan 30 hari sebelumnya.
+type DashboardTrend struct {
+	KaryawanCuti int64 `json:"karyawan_cuti"`
+	KaryawanBaru int64 `json:"karyawan_baru"`
+	PosisiBaru   int64 `json:"posisi_baru"`
+	Hadir        int64 `json:"hadir"`
+	Terlambat    int64 `json:"terlambat"`
+	Alpha        int64 `json:"alpha"`
+}
@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Message adalah satu notifikasi untuk satu atau lebih penerima (alamat email).
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Notifier mengirim notifikasi ke pengguna. Implementasi bisa berupa log, email, dan sebagainya.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type logNotifier struct{}
+
+// NewLogNotifier membuat Notifier yang hanya menulis penerima dan subjek notifikasi ke log aplikasi.
+// Isi pesan tidak pernah ditulis karena bisa berisi rahasia seperti token reset password; gunakan
+// driver file untuk membaca isi notifikasi di lingkungan pengembangan.
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) Send(ctx context.Context, msg Message) error {
+	log.Printf("NOTIFIKASI ke [%s]: %s (isi pesan tidak dicatat)", strings.Join(msg.To, ", "), msg.Subject)
+	return nil
+}
+
+// Driver notifier yang didukung (NOTIFIER_DRIVER).
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+// Config berisi pengaturan untuk memilih dan membuat Notifier. Driver wajib diisi: tidak ada
+// default agar server produksi tidak diam-diam berjalan tanpa pengiriman notifikasi.
+type Config struct {
+	Driver       string
+	FilePath     string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+}
+
+// New membuat Notifier sesuai driver pada cfg.
+func New(cfg Config) (Notifier, error) {
+	switch cfg.Driver {
+	case "":
+		return nil, fmt.Errorf("NOTIFIER_DRIVER wajib diisi (log, file, atau smtp)")
+	case DriverLog:
+		return NewLogNotifier(), nil
+	case DriverFile:
+		if cfg.FilePath == "" {
+			return nil, fmt.Errorf("NOTIFIER_FILE_PATH wajib diisi untuk driver file")
+		}
+		return NewFileNotifier(cfg.FilePath), nil
+	case DriverSMTP:
+		if cfg.SMTPHost == "" || cfg.SMTPFrom == "" {
+			return nil, fmt.Errorf("SMTP_HOST dan SMTP_FROM wajib diisi untuk driver smtp")
+		}
+		return NewSMTPNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom), nil
+	default:
+		return nil, fmt.Errorf("driver notifier tidak dikenal: %q (gunakan log, file, atau smtp)", cfg.Driver)
+	}
+}
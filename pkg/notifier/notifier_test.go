@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const secretBody = "Gunakan token reset berikut: rahasia-123"
+
+func TestFileNotifierAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	n, err := New(Config{Driver: DriverFile, FilePath: path})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	messages := []Message{
+		{To: []string{"a@example.com"}, Subject: "Reset password", Body: secretBody},
+		{To: []string{"b@example.com", "c@example.com"}, Subject: "Pengingat", Body: "Pengajuan menunggu"},
+	}
+	for _, msg := range messages {
+		if err := n.Send(context.Background(), msg); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("membuka file notifikasi: %v", err)
+	}
+	defer f.Close()
+
+	var got []Message
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line struct {
+			Message
+			SentAt string `json:"sent_at"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("baris bukan JSON valid: %v", err)
+		}
+		if line.SentAt == "" {
+			t.Errorf("sent_at kosong pada baris %q", scanner.Text())
+		}
+		got = append(got, line.Message)
+	}
+	if len(got) != len(messages) {
+		t.Fatalf("jumlah baris = %d, want %d", len(got), len(messages))
+	}
+	for i := range messages {
+		if got[i].Subject != messages[i].Subject || got[i].Body != messages[i].Body || strings.Join(got[i].To, ",") != strings.Join(messages[i].To, ",") {
+			t.Errorf("baris %d = %+v, want %+v", i, got[i], messages[i])
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("permission file = %o, want 600", perm)
+	}
+}
+
+func TestLogNotifierDoesNotLogBody(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	n, err := New(Config{Driver: DriverLog})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := n.Send(context.Background(), Message{To: []string{"a@example.com"}, Subject: "Reset password", Body: secretBody}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "rahasia-123") {
+		t.Errorf("log berisi isi pesan: %q", out)
+	}
+	if !strings.Contains(out, "a@example.com") || !strings.Contains(out, "Reset password") {
+		t.Errorf("log tidak berisi penerima dan subjek: %q", out)
+	}
+}
+
+func TestNewRequiresDriver(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"driver kosong", Config{}},
+		{"driver tidak dikenal", Config{Driver: "sms"}},
+		{"file tanpa path", Config{Driver: DriverFile}},
+		{"smtp tanpa host", Config{Driver: DriverSMTP, SMTPFrom: "noreply@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Errorf("New(%+v) tidak mengembalikan error", tt.cfg)
+			}
+		})
+	}
+}
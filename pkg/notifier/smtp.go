@@ -0,0 +1,49 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+type smtpNotifier struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPNotifier membuat Notifier yang mengirim notifikasi sebagai email teks biasa.
+// Jika username kosong, email dikirim tanpa autentikasi SMTP.
+func NewSMTPNotifier(host, port, username, password, from string) Notifier {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &smtpNotifier{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (n *smtpNotifier) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return nil
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", n.from)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", msg.Subject)
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=\"UTF-8\"\r\n\r\n")
+	body.WriteString(msg.Body)
+	body.WriteString("\r\n")
+
+	if err := smtp.SendMail(n.addr, n.auth, n.from, msg.To, []byte(body.String())); err != nil {
+		return fmt.Errorf("gagal mengirim email: %w", err)
+	}
+	return nil
+}
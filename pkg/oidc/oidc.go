@@ -0,0 +1,452 @@
+// Package oidc mengimplementasikan login OpenID Connect (authorization code flow dengan PKCE)
+// untuk client confidential: discovery, penukaran code, dan verifikasi ID token (RS256/384/512,
+// ES256/384) terhadap JWKS milik identity provider.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config berisi pengaturan client OIDC yang terdaftar di identity provider.
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// IDToken adalah klaim ID token yang sudah diverifikasi.
+type IDToken struct {
+	Issuer        string `json:"iss"`
+	Subject       string `json:"sub"`
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// ErrInvalidToken menandai ID token yang tidak lolos verifikasi.
+var ErrInvalidToken = errors.New("ID token tidak valid")
+
+// clockSkew adalah toleransi perbedaan jam dengan identity provider.
+const clockSkew = time.Minute
+
+// jwksMinRefreshInterval membatasi pengambilan ulang JWKS saat menemukan kid yang tidak dikenal.
+const jwksMinRefreshInterval = time.Minute
+
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider adalah client untuk satu identity provider. Metadata discovery dan JWKS diambil saat
+// pertama kali dibutuhkan sehingga aplikasi tetap bisa start walaupun IdP sedang tidak tersedia.
+type Provider struct {
+	cfg        Config
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	metadata      *providerMetadata
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+func NewProvider(cfg Config) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	cfg.IssuerURL = strings.TrimRight(cfg.IssuerURL, "/")
+	return &Provider{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// CodeChallenge menghasilkan code_challenge PKCE (metode S256) dari code verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL membuat URL halaman login identity provider.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.ClientID)
+	params.Set("redirect_uri", p.cfg.RedirectURL)
+	params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", CodeChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return md.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange menukar authorization code dengan token di token endpoint, lalu memverifikasi ID token
+// (tanda tangan, issuer, audience, masa berlaku, dan nonce).
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*IDToken, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat request token: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menghubungi token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("gagal membaca respons token: %w", err)
+	}
+	var tokenResp struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("respons token tidak valid (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || tokenResp.Error != "" {
+		return nil, fmt.Errorf("token endpoint menolak code (status %d): %s %s", resp.StatusCode, tokenResp.Error, tokenResp.ErrorDescription)
+	}
+	if tokenResp.IDToken == "" {
+		return nil, fmt.Errorf("respons token tidak berisi id_token")
+	}
+
+	return p.verifyIDToken(ctx, md, tokenResp.IDToken, nonce)
+}
+
+func (p *Provider) discover(ctx context.Context) (*providerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var md providerMetadata
+	if err := p.getJSON(ctx, p.cfg.IssuerURL+"/.well-known/openid-configuration", &md); err != nil {
+		return nil, fmt.Errorf("gagal mengambil konfigurasi OIDC: %w", err)
+	}
+	if strings.TrimRight(md.Issuer, "/") != p.cfg.IssuerURL {
+		return nil, fmt.Errorf("issuer discovery %q tidak sama dengan OIDC_ISSUER_URL %q", md.Issuer, p.cfg.IssuerURL)
+	}
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return nil, fmt.Errorf("konfigurasi OIDC tidak lengkap")
+	}
+	p.metadata = &md
+	return p.metadata, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type idTokenClaims struct {
+	IDToken
+	Audience audience `json:"aud"`
+	Azp      string   `json:"azp"`
+	Expiry   int64    `json:"exp"`
+	IssuedAt int64    `json:"iat"`
+}
+
+// audience menerima klaim aud berupa string tunggal maupun array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, md *providerMetadata, rawToken, nonce string) (*IDToken, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: format JWT salah", ErrInvalidToken)
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header tidak valid", ErrInvalidToken)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("%w: header tidak valid", ErrInvalidToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: tanda tangan tidak valid", ErrInvalidToken)
+	}
+
+	key, err := p.publicKey(ctx, md, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload tidak valid", ErrInvalidToken)
+	}
+	var claims idTokenClaims
+	if err := json.Unmarshal(payloadJSON, &claims); err != nil {
+		return nil, fmt.Errorf("%w: payload tidak valid", ErrInvalidToken)
+	}
+
+	now := time.Now()
+	switch {
+	case claims.Issuer != md.Issuer:
+		return nil, fmt.Errorf("%w: issuer %q tidak dikenal", ErrInvalidToken, claims.Issuer)
+	case !claims.Audience.contains(p.cfg.ClientID):
+		return nil, fmt.Errorf("%w: audience tidak sesuai", ErrInvalidToken)
+	case len(claims.Audience) > 1 && claims.Azp != p.cfg.ClientID:
+		return nil, fmt.Errorf("%w: azp tidak sesuai", ErrInvalidToken)
+	case claims.Expiry == 0 || now.After(time.Unix(claims.Expiry, 0).Add(clockSkew)):
+		return nil, fmt.Errorf("%w: token sudah kedaluwarsa", ErrInvalidToken)
+	case claims.IssuedAt != 0 && time.Unix(claims.IssuedAt, 0).After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: iat di masa depan", ErrInvalidToken)
+	case claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce tidak sesuai", ErrInvalidToken)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: klaim sub kosong", ErrInvalidToken)
+	}
+
+	return &claims.IDToken, nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	var h hash.Hash
+	var cryptoHash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		h, cryptoHash = sha256.New(), crypto.SHA256
+	case "RS384", "ES384":
+		h, cryptoHash = sha512.New384(), crypto.SHA384
+	case "RS512":
+		h, cryptoHash = sha512.New(), crypto.SHA512
+	default:
+		return fmt.Errorf("algoritma %q tidak didukung", alg)
+	}
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algoritma %q tidak cocok dengan kunci RSA", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, cryptoHash, digest, signature); err != nil {
+			return fmt.Errorf("tanda tangan tidak cocok")
+		}
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("algoritma %q tidak cocok dengan kunci EC", alg)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("panjang tanda tangan EC salah")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return fmt.Errorf("tanda tangan tidak cocok")
+		}
+	default:
+		return fmt.Errorf("jenis kunci tidak didukung")
+	}
+	return nil
+}
+
+// publicKey mencari kunci berdasarkan kid. JWKS diambil ulang jika kid belum dikenal, misalnya
+// setelah IdP merotasi kuncinya.
+func (p *Provider) publicKey(ctx context.Context, md *providerMetadata, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if !p.keysFetchedAt.IsZero() && time.Since(p.keysFetchedAt) < jwksMinRefreshInterval {
+		return nil, fmt.Errorf("%w: kunci %q tidak dikenal", ErrInvalidToken, kid)
+	}
+
+	keys, err := p.fetchKeys(ctx, md.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: kunci %q tidak dikenal", ErrInvalidToken, kid)
+}
+
+// lookupKey mencari kunci berdasarkan kid; token tanpa kid hanya diterima jika JWKS berisi satu kunci.
+func (p *Provider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (p *Provider) fetchKeys(ctx context.Context, jwksURI string) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("gagal mengambil JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue // kunci dengan jenis yang tidak didukung diabaikan
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("kurva %q tidak didukung", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("titik kunci EC tidak valid")
+		}
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("jenis kunci %q tidak didukung", k.Kty)
+	}
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d dari %s", resp.StatusCode, endpoint)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
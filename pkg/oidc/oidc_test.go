@@ -0,0 +1,188 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"Sistem-Manajemen-Karyawan/pkg/oidc"
+	"Sistem-Manajemen-Karyawan/pkg/oidc/oidctest"
+)
+
+const (
+	testClientID     = "sistem-karyawan"
+	testClientSecret = "rahasia client"
+	testRedirectURL  = "https://karyawan.example.com/sso/callback"
+)
+
+func newTestIdP(t *testing.T) (*oidctest.IdP, *oidc.Provider) {
+	t.Helper()
+	idp, err := oidctest.NewIdP(testClientID, testClientSecret, testRedirectURL)
+	if err != nil {
+		t.Fatalf("NewIdP: %v", err)
+	}
+	t.Cleanup(idp.Close)
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:    idp.Issuer() + "/",
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+	})
+	return idp, provider
+}
+
+// login menjalankan authorization code flow sampai Exchange dengan nonce dan code verifier yang
+// dipakai saat membuat URL login.
+func login(t *testing.T, idp *oidctest.IdP, provider *oidc.Provider, claims oidctest.Claims) (*oidc.IDToken, error) {
+	t.Helper()
+	ctx := context.Background()
+	const state, nonce, verifier = "state-123", "nonce-123", "verifier-123-abcdefghijklmnopqrstuvwxyz"
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, idp.Issuer()+"/authorize?") {
+		t.Fatalf("authURL = %q, want authorization endpoint IdP", authURL)
+	}
+	code, gotState, err := idp.Authorize(authURL, claims)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if gotState != state {
+		t.Fatalf("state = %q, want %q", gotState, state)
+	}
+	return provider.Exchange(ctx, code, verifier, nonce)
+}
+
+func TestExchangeReturnsVerifiedClaims(t *testing.T) {
+	idp, provider := newTestIdP(t)
+
+	token, err := login(t, idp, provider, oidctest.Claims{
+		"sub":            "user-42",
+		"email":          "budi@example.com",
+		"email_verified": true,
+		"name":           "Budi",
+	})
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if token.Subject != "user-42" || token.Email != "budi@example.com" || token.Name != "Budi" {
+		t.Errorf("token = %+v", token)
+	}
+	if token.EmailVerified == nil || !*token.EmailVerified {
+		t.Errorf("EmailVerified = %v, want true", token.EmailVerified)
+	}
+	if token.Issuer != idp.Issuer() {
+		t.Errorf("Issuer = %q, want %q", token.Issuer, idp.Issuer())
+	}
+}
+
+func TestExchangeRejectsInvalidIDToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		claims oidctest.Claims
+		issue  func(idp *oidctest.IdP) func(oidctest.Claims) string
+	}{
+		{name: "nonce berbeda", claims: oidctest.Claims{"sub": "u", "nonce": "nonce-lain"}},
+		{name: "audience lain", claims: oidctest.Claims{"sub": "u", "aud": "client-lain"}},
+		{name: "azp tidak sesuai", claims: oidctest.Claims{"sub": "u", "aud": []string{testClientID, "client-lain"}, "azp": "client-lain"}},
+		{name: "issuer lain", claims: oidctest.Claims{"sub": "u", "iss": "https://idp-lain.example.com"}},
+		{name: "kedaluwarsa", claims: oidctest.Claims{"sub": "u", "exp": time.Now().Add(-time.Hour).Unix()}},
+		{name: "iat di masa depan", claims: oidctest.Claims{"sub": "u", "iat": time.Now().Add(time.Hour).Unix()}},
+		{name: "sub kosong", claims: oidctest.Claims{"email": "budi@example.com"}},
+		{
+			name:   "tanda tangan kunci lain",
+			claims: oidctest.Claims{"sub": "u"},
+			issue: func(idp *oidctest.IdP) func(oidctest.Claims) string {
+				return func(c oidctest.Claims) string { return idp.SignIDTokenWithKey(c, otherKey, idp.KeyID) }
+			},
+		},
+		{
+			name:   "token diubah",
+			claims: oidctest.Claims{"sub": "u"},
+			issue: func(idp *oidctest.IdP) func(oidctest.Claims) string {
+				return func(c oidctest.Claims) string {
+					parts := strings.Split(idp.SignIDToken(c), ".")
+					forged := strings.Split(idp.SignIDToken(oidctest.Claims{"sub": "admin", "nonce": c["nonce"]}), ".")
+					return parts[0] + "." + forged[1] + "." + parts[2]
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp, provider := newTestIdP(t)
+			if tt.issue != nil {
+				idp.IssueIDToken = tt.issue(idp)
+			}
+			_, err := login(t, idp, provider, tt.claims)
+			if !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Errorf("err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	idp, provider := newTestIdP(t)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier-asli")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, _, err := idp.Authorize(authURL, oidctest.Claims{"sub": "u"})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if _, err := provider.Exchange(ctx, code, "verifier-lain", "nonce"); err == nil {
+		t.Fatal("Exchange dengan code verifier lain tidak mengembalikan error")
+	}
+}
+
+func TestExchangeRejectsReusedCode(t *testing.T) {
+	idp, provider := newTestIdP(t)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, _, err := idp.Authorize(authURL, oidctest.Claims{"sub": "u"})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if _, err := provider.Exchange(ctx, code, "verifier", "nonce"); err != nil {
+		t.Fatalf("Exchange pertama: %v", err)
+	}
+	if _, err := provider.Exchange(ctx, code, "verifier", "nonce"); err == nil {
+		t.Fatal("code yang sama dapat ditukar dua kali")
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	idp, err := oidctest.NewIdP(testClientID, testClientSecret, testRedirectURL)
+	if err != nil {
+		t.Fatalf("NewIdP: %v", err)
+	}
+	defer idp.Close()
+
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:   strings.Replace(idp.Issuer(), "127.0.0.1", "localhost", 1),
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+	})
+	if _, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier"); err == nil {
+		t.Fatal("issuer discovery yang berbeda diterima")
+	}
+}
@@ -0,0 +1,232 @@
+// Package oidctest menyediakan identity provider OpenID Connect palsu untuk pengujian: discovery,
+// JWKS, halaman otorisasi (tanpa UI), dan token endpoint dengan ID token bertanda tangan RS256.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Claims adalah klaim tambahan ID token. Klaim standar (iss, aud, exp, iat, nonce) diisi otomatis
+// jika tidak ada.
+type Claims map[string]interface{}
+
+// IdP adalah identity provider palsu yang berjalan di httptest.Server.
+type IdP struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	KeyID        string
+	// IssueIDToken, jika diisi, dipakai token endpoint untuk membuat ID token sebagai pengganti
+	// SignIDToken, mis. untuk menguji token yang rusak.
+	IssueIDToken func(claims Claims) string
+
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+type grant struct {
+	codeChallenge string
+	claims        Claims
+}
+
+// NewIdP menjalankan identity provider palsu untuk satu client. Panggil Close setelah selesai.
+func NewIdP(clientID, clientSecret, redirectURL string) (*IdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat kunci RSA: %w", err)
+	}
+	idp := &IdP{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		KeyID:        "test-key",
+		key:          key,
+		grants:       make(map[string]grant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.handleDiscovery)
+	mux.HandleFunc("/jwks", idp.handleJWKS)
+	mux.HandleFunc("/token", idp.handleToken)
+	idp.Server = httptest.NewServer(mux)
+	return idp, nil
+}
+
+// Issuer mengembalikan issuer URL identity provider.
+func (i *IdP) Issuer() string {
+	return i.Server.URL
+}
+
+// Close menghentikan server identity provider.
+func (i *IdP) Close() {
+	i.Server.Close()
+}
+
+// Authorize mensimulasikan user yang berhasil login di halaman otorisasi: memeriksa parameter
+// authURL, lalu mengembalikan code dan state yang dikirim IdP ke redirect URL. Nonce dari authURL
+// dimasukkan ke ID token kecuali claims sudah berisi nonce.
+func (i *IdP) Authorize(authURL string, claims Claims) (code, state string, err error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	q := u.Query()
+	switch {
+	case q.Get("response_type") != "code":
+		return "", "", fmt.Errorf("response_type %q tidak didukung", q.Get("response_type"))
+	case q.Get("client_id") != i.ClientID:
+		return "", "", fmt.Errorf("client_id %q tidak dikenal", q.Get("client_id"))
+	case q.Get("redirect_uri") != i.RedirectURL:
+		return "", "", fmt.Errorf("redirect_uri %q tidak terdaftar", q.Get("redirect_uri"))
+	case q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "":
+		return "", "", fmt.Errorf("PKCE S256 wajib")
+	case q.Get("state") == "":
+		return "", "", fmt.Errorf("state kosong")
+	}
+
+	merged := Claims{"nonce": q.Get("nonce")}
+	for k, v := range claims {
+		merged[k] = v
+	}
+
+	code = randomString()
+	i.mu.Lock()
+	i.grants[code] = grant{codeChallenge: q.Get("code_challenge"), claims: merged}
+	i.mu.Unlock()
+	return code, q.Get("state"), nil
+}
+
+// SignIDToken membuat ID token RS256 dengan klaim standar yang diisi otomatis.
+func (i *IdP) SignIDToken(claims Claims) string {
+	return i.SignIDTokenWithKey(claims, i.key, i.KeyID)
+}
+
+// SignIDTokenWithKey sama dengan SignIDToken, tetapi memakai kunci lain (mis. kunci yang tidak ada
+// di JWKS) untuk menguji penolakan tanda tangan.
+func (i *IdP) SignIDTokenWithKey(claims Claims, key *rsa.PrivateKey, kid string) string {
+	now := time.Now()
+	payload := Claims{
+		"iss": i.Issuer(),
+		"aud": i.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		payload[k] = v
+	}
+	return i.sign(payload, key, kid)
+}
+
+func (i *IdP) sign(payload Claims, key *rsa.PrivateKey, kid string) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	body, _ := json.Marshal(payload)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (i *IdP) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 i.Issuer(),
+		"authorization_endpoint": i.Issuer() + "/authorize",
+		"token_endpoint":         i.Issuer() + "/token",
+		"jwks_uri":               i.Issuer() + "/jwks",
+	})
+}
+
+func (i *IdP) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := i.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": i.KeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *IdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	}
+	if !ok || clientID != i.ClientID || clientSecret != i.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	if r.PostForm.Get("redirect_uri") != i.RedirectURL {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri tidak sesuai"})
+		return
+	}
+
+	// Code hanya dapat ditukar sekali.
+	i.mu.Lock()
+	g, found := i.grants[r.PostForm.Get("code")]
+	delete(i.grants, r.PostForm.Get("code"))
+	i.mu.Unlock()
+	if !found {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code tidak dikenal"})
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != g.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code_verifier tidak sesuai"})
+		return
+	}
+
+	issue := i.SignIDToken
+	if i.IssueIDToken != nil {
+		issue = i.IssueIDToken
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     issue(g.claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -1,9 +1,12 @@
 package paseto
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	"Sistem-Manajemen-Karyawan/models"
@@ -14,40 +17,124 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-type PasetoMaker struct {
-	paseto       *paseto.V2
-	symmetricKey []byte
+// Masa berlaku token. Access token dibuat singkat karena hanya bisa dicabut lewat daftar pencabutan;
+// refresh token disimpan di server dan dirotasi setiap kali dipakai.
+const (
+	AccessTokenDuration  = 15 * time.Minute
+	RefreshTokenDuration = 7 * 24 * time.Hour
+)
+
+// Keyring berisi kunci simetris PASETO v2.local yang dikenali aplikasi, diindeks berdasarkan key ID.
+// Token baru selalu dibuat dengan kunci primary; token lama tetap bisa divalidasi selama kuncinya
+// masih ada di Keys. Key ID disimpan di footer token (tidak terenkripsi, tetapi ikut diautentikasi).
+type Keyring struct {
+	PrimaryKeyID string
+	Keys         map[string][]byte
 }
 
-func NewPasetoMaker() (*PasetoMaker, error) {
-	secretBase64 := os.Getenv("PASETO_SECRET")
-	if secretBase64 == "" {
-		return nil, fmt.Errorf("PASETO_SECRET tidak ditemukan di environment variables")
+// ParseKeyring membaca daftar kunci berformat "kid1:base64,kid2:base64". Kunci boleh di-encode
+// dengan base64 standar maupun base64 URL.
+func ParseKeyring(primaryKeyID, keys string) (*Keyring, error) {
+	keyring := &Keyring{PrimaryKeyID: primaryKeyID, Keys: make(map[string][]byte)}
+	for _, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, encoded, found := strings.Cut(entry, ":")
+		kid = strings.TrimSpace(kid)
+		if !found || kid == "" {
+			return nil, fmt.Errorf("format kunci PASETO tidak valid: %q (gunakan kid:base64)", entry)
+		}
+		if _, exists := keyring.Keys[kid]; exists {
+			return nil, fmt.Errorf("key ID PASETO %q terdaftar lebih dari sekali", kid)
+		}
+		key, err := DecodeKey(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("kunci PASETO %q: %w", kid, err)
+		}
+		keyring.Keys[kid] = key
 	}
+	if err := keyring.validate(); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
 
-	// SESUDAH
-	decodedKey, err := base64.StdEncoding.DecodeString(secretBase64)
+// DecodeKey men-decode kunci base64 (standar atau URL) dan memastikan panjangnya 32 byte.
+func DecodeKey(encoded string) ([]byte, error) {
+	var key []byte
+	var err error
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if key, err = enc.DecodeString(encoded); err == nil {
+			break
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("gagal decode PASETO_SECRET dari base64: %w", err)
+		return nil, fmt.Errorf("gagal decode kunci dari base64: %w", err)
 	}
+	if len(key) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("kunci rahasia harus tepat %d bytes setelah di-decode, bukan %d", chacha20poly1305.KeySize, len(key))
+	}
+	return key, nil
+}
 
-	if len(decodedKey) != chacha20poly1305.KeySize {
-		return nil, fmt.Errorf("kunci rahasia harus tepat %d bytes setelah di-decode, bukan %d", chacha20poly1305.KeySize, len(decodedKey))
+func (k *Keyring) validate() error {
+	if len(k.Keys) == 0 {
+		return fmt.Errorf("tidak ada kunci PASETO yang dikonfigurasi")
+	}
+	if _, ok := k.Keys[k.PrimaryKeyID]; !ok {
+		return fmt.Errorf("kunci primary PASETO %q tidak ada di daftar kunci", k.PrimaryKeyID)
+	}
+	return nil
+}
+
+// tokenFooter disimpan di footer token agar kunci yang tepat bisa dipilih saat validasi.
+type tokenFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// PasetoMaker dibuat sekali saat aplikasi start dan aman dipakai bersamaan oleh banyak request.
+type PasetoMaker struct {
+	paseto  *paseto.V2
+	keyring *Keyring
+}
+
+func NewPasetoMaker(keyring *Keyring) (*PasetoMaker, error) {
+	if keyring == nil {
+		return nil, fmt.Errorf("keyring PASETO belum dikonfigurasi")
+	}
+	if err := keyring.validate(); err != nil {
+		return nil, err
 	}
 
 	maker := &PasetoMaker{
-		paseto:       paseto.NewV2(),
-		symmetricKey: decodedKey,
+		paseto:  paseto.NewV2(),
+		keyring: keyring,
 	}
 
 	return maker, nil
 }
 
-func (maker *PasetoMaker) GenerateToken(user *models.User) (string, error) {
+// TokenOptions berisi penanda yang membatasi pemakaian access token.
+type TokenOptions struct {
+	TwoFactorSetupRequired bool // token hanya boleh dipakai untuk mendaftarkan 2FA
+	PasswordExpired        bool // token hanya boleh dipakai untuk mengganti password yang kedaluwarsa
+}
+
+// GenerateToken membuat access token untuk sesi sessionID (models.Session yang tersimpan di database)
+// dan mengembalikan klaim yang ditanamkan.
+func (maker *PasetoMaker) GenerateToken(user *models.User, sessionID string, opts TokenOptions) (string, *models.Claims, error) {
 	now := time.Now()
-	exp := now.Add(24 * time.Hour)
+	exp := now.Add(AccessTokenDuration)
+
+	tokenID, err := NewRandomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
 
 	token := paseto.JSONToken{
+		Jti:        tokenID,
 		IssuedAt:   now,
 		Expiration: exp,
 		NotBefore:  now,
@@ -57,19 +144,43 @@ func (maker *PasetoMaker) GenerateToken(user *models.User) (string, error) {
 	token.Set("email", user.Email)
 	token.Set("role", user.Role)
 	token.Set("is_first_login", fmt.Sprintf("%v", user.IsFirstLogin))
+	token.Set("session_id", sessionID)
+	if opts.TwoFactorSetupRequired {
+		token.Set("two_factor_setup_required", "true")
+	}
+	if opts.PasswordExpired {
+		token.Set("password_expired", "true")
+	}
+
+	kid := maker.keyring.PrimaryKeyID
+	encrypted, err := maker.paseto.Encrypt(maker.keyring.Keys[kid], token, tokenFooter{KeyID: kid})
+	if err != nil {
+		return "", nil, err
+	}
 
-	return maker.paseto.Encrypt(maker.symmetricKey, token, nil)
+	claims := &models.Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Role:         user.Role,
+		IsFirstLogin: user.IsFirstLogin,
+		TokenID:      tokenID,
+		SessionID:    sessionID,
+		IssuedAt:     now,
+		ExpiresAt:    exp,
+
+		TwoFactorSetupRequired: opts.TwoFactorSetupRequired,
+		PasswordExpired:        opts.PasswordExpired,
+	}
+	return encrypted, claims, nil
 }
 
 func (maker *PasetoMaker) ValidateToken(tokenString string) (*models.Claims, error) {
 	var token paseto.JSONToken
-
-	err := maker.paseto.Decrypt(tokenString, maker.symmetricKey, &token, nil)
-	if err != nil {
-		return nil, fmt.Errorf("gagal decrypt token: %w", err)
+	if err := maker.decrypt(tokenString, &token); err != nil {
+		return nil, err
 	}
 
-	err = token.Validate()
+	err := token.Validate()
 	if err != nil {
 		return nil, fmt.Errorf("validasi token gagal: %w", err)
 	}
@@ -85,6 +196,51 @@ func (maker *PasetoMaker) ValidateToken(tokenString string) (*models.Claims, err
 	claims.Email = token.Get("email")
 	claims.Role = token.Get("role")
 	claims.IsFirstLogin = (token.Get("is_first_login") == "true")
+	claims.TokenID = token.Jti
+	claims.SessionID = token.Get("session_id")
+	claims.IssuedAt = token.IssuedAt
+	claims.ExpiresAt = token.Expiration
+	claims.TwoFactorSetupRequired = (token.Get("two_factor_setup_required") == "true")
+	claims.PasswordExpired = (token.Get("password_expired") == "true")
 
 	return claims, nil
 }
+
+// decrypt memilih kunci berdasarkan key ID di footer. Token tanpa key ID (dibuat sebelum keyring
+// dipakai) dicoba dengan semua kunci yang dikenal.
+func (maker *PasetoMaker) decrypt(tokenString string, token *paseto.JSONToken) error {
+	var footer tokenFooter
+	if err := paseto.ParseFooter(tokenString, &footer); err == nil && footer.KeyID != "" {
+		key, ok := maker.keyring.Keys[footer.KeyID]
+		if !ok {
+			return fmt.Errorf("key ID token %q tidak dikenal", footer.KeyID)
+		}
+		if err := maker.paseto.Decrypt(tokenString, key, token, nil); err != nil {
+			return fmt.Errorf("gagal decrypt token: %w", err)
+		}
+		return nil
+	}
+
+	var lastErr error
+	for _, key := range maker.keyring.Keys {
+		if lastErr = maker.paseto.Decrypt(tokenString, key, token, nil); lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("gagal decrypt token: %w", lastErr)
+}
+
+// NewRandomToken menghasilkan string acak (base64 URL) dari n byte acak.
+func NewRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("gagal membuat token acak: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken menghasilkan hash SHA-256 (hex) dari token opaque seperti refresh token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,202 @@
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// bcryptMaxLength adalah batas panjang input bcrypt; karakter setelahnya diabaikan.
+const bcryptMaxLength = 72
+
+// commonPasswords adalah daftar bawaan password yang paling sering dipakai dan selalu ditolak.
+// Daftar tambahan bisa dimuat dari file (PASSWORD_BANNED_LIST_FILE).
+var commonPasswords = []string{
+	"password", "password1", "password123", "passw0rd", "p@ssw0rd", "p@ssword",
+	"12345678", "123456789", "1234567890", "87654321", "11111111", "00000000",
+	"qwerty123", "qwertyuiop", "1q2w3e4r", "1qaz2wsx", "abc12345", "abcd1234",
+	"iloveyou", "sunshine", "princess", "football", "baseball", "welcome1", "welcome123",
+	"admin123", "administrator", "letmein1", "changeme", "trustno1", "superman", "monkey123",
+	"bismillah", "indonesia", "jakarta123", "sayang123", "rahasia", "rahasia123", "karyawan123",
+}
+
+// Policy adalah kebijakan password yang berlaku untuk password baru.
+type Policy struct {
+	MinLength        int  `json:"min_length"`
+	MaxLength        int  `json:"max_length"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	// HistorySize adalah jumlah password terakhir (termasuk password saat ini) yang tidak boleh
+	// dipakai lagi. 0 menonaktifkan pemeriksaan riwayat.
+	HistorySize int `json:"history_size"`
+	// MaxAgeDays adalah umur maksimum password (hari) sebelum user wajib menggantinya.
+	// 0 berarti password tidak pernah kedaluwarsa.
+	MaxAgeDays int `json:"max_age_days"`
+
+	banned map[string]bool
+}
+
+// DefaultPolicy mengembalikan kebijakan bawaan: 8-50 karakter dengan minimal satu huruf kapital,
+// tidak boleh memakai 5 password terakhir, dan tanpa masa kedaluwarsa.
+func DefaultPolicy() *Policy {
+	p := &Policy{
+		MinLength:        8,
+		MaxLength:        50,
+		RequireUppercase: true,
+		HistorySize:      5,
+	}
+	p.AddBannedPasswords(commonPasswords)
+	return p
+}
+
+// AddBannedPasswords menambah password yang ditolak. Pencocokan tidak membedakan huruf besar/kecil.
+func (p *Policy) AddBannedPasswords(passwords []string) {
+	if p.banned == nil {
+		p.banned = make(map[string]bool, len(passwords))
+	}
+	for _, pw := range passwords {
+		if pw = strings.ToLower(strings.TrimSpace(pw)); pw != "" {
+			p.banned[pw] = true
+		}
+	}
+}
+
+// LoadBannedPasswords membaca daftar password terlarang dari file teks, satu password per baris.
+// Baris kosong dan baris yang diawali '#' diabaikan.
+func LoadBannedPasswords(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuka daftar password terlarang: %w", err)
+	}
+	defer file.Close()
+
+	var passwords []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords = append(passwords, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("gagal membaca daftar password terlarang: %w", err)
+	}
+	return passwords, nil
+}
+
+// Check memastikan pengaturan kebijakan masuk akal.
+func (p *Policy) Check() error {
+	if p.MinLength < 1 {
+		return fmt.Errorf("panjang minimum password harus >= 1")
+	}
+	if p.MaxLength < p.MinLength {
+		return fmt.Errorf("panjang maksimum password (%d) lebih kecil dari panjang minimum (%d)", p.MaxLength, p.MinLength)
+	}
+	if p.MaxLength > bcryptMaxLength {
+		return fmt.Errorf("panjang maksimum password tidak boleh lebih dari %d karakter", bcryptMaxLength)
+	}
+	if p.HistorySize < 0 || p.MaxAgeDays < 0 {
+		return fmt.Errorf("jumlah riwayat dan umur maksimum password tidak boleh negatif")
+	}
+	return nil
+}
+
+// Validate memeriksa password baru terhadap kebijakan dan mengembalikan daftar pelanggaran.
+// Hasil kosong berarti password memenuhi kebijakan.
+func (p *Policy) Validate(pw string) []string {
+	var violations []string
+
+	length := len([]rune(pw))
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("Password minimal %d karakter.", p.MinLength))
+	}
+	if length > p.MaxLength || len(pw) > bcryptMaxLength {
+		violations = append(violations, fmt.Sprintf("Password maksimal %d karakter.", p.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUppercase && !hasUpper {
+		violations = append(violations, "Password harus mengandung setidaknya satu huruf kapital.")
+	}
+	if p.RequireLowercase && !hasLower {
+		violations = append(violations, "Password harus mengandung setidaknya satu huruf kecil.")
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "Password harus mengandung setidaknya satu angka.")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "Password harus mengandung setidaknya satu simbol.")
+	}
+
+	if p.banned[strings.ToLower(pw)] {
+		violations = append(violations, "Password terlalu umum dan mudah ditebak.")
+	}
+	return violations
+}
+
+// IsReused mengembalikan true jika password sama dengan password saat ini atau salah satu dari
+// HistorySize-1 password sebelumnya.
+func (p *Policy) IsReused(pw, currentHash string, history []string) bool {
+	if p.HistorySize == 0 {
+		return false
+	}
+	if currentHash != "" && CheckPasswordHash(pw, currentHash) {
+		return true
+	}
+	for i, hash := range history {
+		if i >= p.HistorySize-1 {
+			break
+		}
+		if CheckPasswordHash(pw, hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextHistory mengembalikan riwayat hash password setelah password saat ini diganti, terbaru lebih
+// dulu, dipotong sesuai HistorySize.
+func (p *Policy) NextHistory(currentHash string, history []string) []string {
+	keep := p.HistorySize - 1
+	if keep <= 0 {
+		return []string{}
+	}
+	next := make([]string, 0, keep)
+	if currentHash != "" {
+		next = append(next, currentHash)
+	}
+	for _, hash := range history {
+		if len(next) >= keep {
+			break
+		}
+		next = append(next, hash)
+	}
+	return next
+}
+
+// IsExpired mengembalikan true jika password yang terakhir diganti pada changedAt sudah melewati
+// MaxAgeDays. Waktu nol (tidak diketahui) dianggap belum kedaluwarsa.
+func (p *Policy) IsExpired(changedAt, now time.Time) bool {
+	if p.MaxAgeDays <= 0 || changedAt.IsZero() {
+		return false
+	}
+	return now.After(changedAt.AddDate(0, 0, p.MaxAgeDays))
+}
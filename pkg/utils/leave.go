@@ -0,0 +1,183 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+// CalculateLeaveDays menghitung berapa hari yang dipotong dari saldo cuti untuk sebuah pengajuan.
+// Pengajuan penuh dihitung per tanggal, setengah hari dihitung 0.5, dan per jam dihitung sebagai
+// pecahan dari lama jam kerja pada schedule (dibulatkan dua angka di belakang koma). schedule
+// wajib diisi untuk pengajuan per jam, dan jam cuti harus berada di dalam jam kerja tersebut.
+func CalculateLeaveDays(duration, startDate, endDate, startTime, endTime string, schedule *models.WorkSchedule) (float64, error) {
+	switch duration {
+	case models.LeaveDurationHalfDay:
+		return 0.5, nil
+	case models.LeaveDurationHourly:
+		start, err := time.Parse("15:04", startTime)
+		if err != nil {
+			return 0, fmt.Errorf("format jam mulai tidak valid: %s", startTime)
+		}
+		end, err := time.Parse("15:04", endTime)
+		if err != nil {
+			return 0, fmt.Errorf("format jam selesai tidak valid: %s", endTime)
+		}
+		if !end.After(start) {
+			return 0, fmt.Errorf("jam selesai harus setelah jam mulai")
+		}
+		if schedule == nil {
+			return 0, fmt.Errorf("jadwal kerja dibutuhkan untuk menghitung cuti per jam")
+		}
+		workStart, workEnd, err := scheduleBounds(schedule)
+		if err != nil {
+			return 0, err
+		}
+		if start.Before(workStart) || end.After(workEnd) {
+			return 0, fmt.Errorf("cuti per jam harus berada di dalam jam kerja %s-%s", schedule.StartTime, schedule.EndTime)
+		}
+		hours, workHours := end.Sub(start).Hours(), workEnd.Sub(workStart).Hours()
+		if hours >= workHours {
+			return 0, fmt.Errorf("cuti per jam harus kurang dari %.4g jam kerja, gunakan cuti satu hari penuh", workHours)
+		}
+		return math.Round(hours/workHours*100) / 100, nil
+	default:
+		start, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			return 0, fmt.Errorf("format tanggal mulai tidak valid: %s", startDate)
+		}
+		end, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
+			return 0, fmt.Errorf("format tanggal selesai tidak valid: %s", endDate)
+		}
+		return end.Sub(start).Hours()/24 + 1, nil
+	}
+}
+
+// scheduleBounds mengembalikan jam masuk dan jam pulang jadwal.
+func scheduleBounds(schedule *models.WorkSchedule) (start, end time.Time, err error) {
+	start, err = time.Parse("15:04", schedule.StartTime)
+	if err != nil {
+		return start, end, fmt.Errorf("format jam mulai jadwal tidak valid: %s", schedule.StartTime)
+	}
+	end, err = time.Parse("15:04", schedule.EndTime)
+	if err != nil {
+		return start, end, fmt.Errorf("format jam selesai jadwal tidak valid: %s", schedule.EndTime)
+	}
+	if !end.After(start) {
+		return start, end, fmt.Errorf("jam selesai jadwal harus setelah jam mulai")
+	}
+	return start, end, nil
+}
+
+// leaveTimeRange mengembalikan rentang jam yang dicakup pengajuan sebagian hari pada schedule.
+// Cuti pagi (AM) mencakup jam masuk sampai tengah jadwal, cuti siang (PM) tengah jadwal sampai jam pulang.
+func leaveTimeRange(schedule *models.WorkSchedule, leave *models.LeaveRequest) (from, to time.Time, err error) {
+	start, end, err := scheduleBounds(schedule)
+	if err != nil {
+		return from, to, err
+	}
+	switch leave.Duration {
+	case models.LeaveDurationHalfDay:
+		midpoint := start.Add(end.Sub(start) / 2)
+		if leave.HalfDayPeriod == models.HalfDayPeriodAM {
+			return start, midpoint, nil
+		}
+		return midpoint, end, nil
+	case models.LeaveDurationHourly:
+		from, err = time.Parse("15:04", leave.StartTime)
+		if err != nil {
+			return from, to, fmt.Errorf("format jam mulai cuti tidak valid: %s", leave.StartTime)
+		}
+		to, err = time.Parse("15:04", leave.EndTime)
+		if err != nil {
+			return from, to, fmt.Errorf("format jam selesai cuti tidak valid: %s", leave.EndTime)
+		}
+		return from, to, nil
+	default:
+		return start, end, nil
+	}
+}
+
+// LeavesOverlap mengembalikan true jika dua pengajuan pada tanggal yang sama beririsan. Cuti penuh
+// selalu beririsan, sedangkan cuti pagi (AM) dan cuti siang (PM) tidak. schedule hanya dibutuhkan
+// jika keduanya sebagian hari dan salah satunya per jam.
+func LeavesOverlap(schedule *models.WorkSchedule, a, b *models.LeaveRequest) (bool, error) {
+	if !a.IsPartialDay() || !b.IsPartialDay() {
+		return true, nil
+	}
+	if a.Duration == models.LeaveDurationHalfDay && b.Duration == models.LeaveDurationHalfDay {
+		return a.HalfDayPeriod == b.HalfDayPeriod, nil
+	}
+	if schedule == nil {
+		return false, fmt.Errorf("jadwal kerja dibutuhkan untuk membandingkan cuti per jam")
+	}
+	aFrom, aTo, err := leaveTimeRange(schedule, a)
+	if err != nil {
+		return false, err
+	}
+	bFrom, bTo, err := leaveTimeRange(schedule, b)
+	if err != nil {
+		return false, err
+	}
+	return aFrom.Before(bTo) && bFrom.Before(aTo), nil
+}
+
+// AdjustScheduleForLeaves mengembalikan salinan jadwal dengan jam masuk/pulang yang digeser
+// sesuai semua cuti setengah hari atau per jam yang sudah disetujui pada tanggal tersebut.
+// Cuti pagi (AM) menggeser jam masuk ke tengah jadwal, cuti siang (PM) memajukan jam pulang.
+// Cuti per jam yang menempel di awal/akhir jam kerja tersisa menggeser jam masuk/pulang ke batas
+// cuti tersebut. covered bernilai true jika tidak ada jam kerja yang tersisa, misalnya karena ada
+// cuti penuh atau cuti AM dan PM pada tanggal yang sama.
+func AdjustScheduleForLeaves(schedule *models.WorkSchedule, leaves []models.LeaveRequest) (adjusted *models.WorkSchedule, covered bool, err error) {
+	copied := *schedule
+	adjusted = &copied
+
+	partial := make([]models.LeaveRequest, 0, len(leaves))
+	for i := range leaves {
+		if !leaves[i].IsPartialDay() {
+			return adjusted, true, nil
+		}
+		partial = append(partial, leaves[i])
+	}
+	if len(partial) == 0 {
+		return adjusted, false, nil
+	}
+
+	workStart, workEnd, err := scheduleBounds(schedule)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Setengah hari lebih dulu, lalu per jam urut jam mulai, agar cuti per jam yang bersambung
+	// (mis. 08:00-10:00 lalu 10:00-12:00) ikut menggeser jam masuk.
+	sort.SliceStable(partial, func(i, j int) bool {
+		iHalf, jHalf := partial[i].Duration == models.LeaveDurationHalfDay, partial[j].Duration == models.LeaveDurationHalfDay
+		if iHalf != jHalf {
+			return iHalf
+		}
+		return partial[i].StartTime < partial[j].StartTime
+	})
+
+	for i := range partial {
+		from, to, err := leaveTimeRange(schedule, &partial[i])
+		if err != nil {
+			return nil, false, err
+		}
+		if !from.After(workStart) && to.After(workStart) {
+			workStart = to
+		} else if !to.Before(workEnd) && from.Before(workEnd) {
+			workEnd = from
+		}
+	}
+
+	if !workStart.Before(workEnd) {
+		return adjusted, true, nil
+	}
+	adjusted.StartTime = workStart.Format("15:04")
+	adjusted.EndTime = workEnd.Format("15:04")
+	return adjusted, false, nil
+}
@@ -0,0 +1,116 @@
+package util
+
+import (
+	"testing"
+
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+func TestCalculateLeaveDaysHourlyUsesSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule *models.WorkSchedule
+		start    string
+		end      string
+		want     float64
+		wantErr  bool
+	}{
+		{"jadwal 8 jam", &models.WorkSchedule{StartTime: "08:00", EndTime: "16:00"}, "08:00", "10:00", 0.25, false},
+		{"jadwal 9 jam", &models.WorkSchedule{StartTime: "08:00", EndTime: "17:00"}, "08:00", "11:00", 0.33, false},
+		{"jadwal 5 jam", &models.WorkSchedule{StartTime: "08:00", EndTime: "13:00"}, "11:00", "13:00", 0.4, false},
+		{"seluruh jam kerja", &models.WorkSchedule{StartTime: "08:00", EndTime: "13:00"}, "08:00", "13:00", 0, true},
+		{"di luar jam kerja", &models.WorkSchedule{StartTime: "08:00", EndTime: "16:00"}, "15:00", "17:00", 0, true},
+		{"tanpa jadwal", nil, "08:00", "10:00", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CalculateLeaveDays(models.LeaveDurationHourly, "2025-03-03", "2025-03-03", tt.start, tt.end, tt.schedule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CalculateLeaveDays = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeavesOverlap(t *testing.T) {
+	schedule := &models.WorkSchedule{StartTime: "08:00", EndTime: "16:00"}
+	full := &models.LeaveRequest{Duration: models.LeaveDurationFullDay}
+	legacy := &models.LeaveRequest{}
+	am := &models.LeaveRequest{Duration: models.LeaveDurationHalfDay, HalfDayPeriod: models.HalfDayPeriodAM}
+	pm := &models.LeaveRequest{Duration: models.LeaveDurationHalfDay, HalfDayPeriod: models.HalfDayPeriodPM}
+	morningHour := &models.LeaveRequest{Duration: models.LeaveDurationHourly, StartTime: "08:00", EndTime: "09:00"}
+	afternoonHour := &models.LeaveRequest{Duration: models.LeaveDurationHourly, StartTime: "13:00", EndTime: "14:00"}
+
+	tests := []struct {
+		name string
+		a, b *models.LeaveRequest
+		want bool
+	}{
+		{"penuh dan AM", full, am, true},
+		{"data lama tanpa durasi", legacy, pm, true},
+		{"AM dan PM", am, pm, false},
+		{"AM dan AM", am, am, true},
+		{"PM dan jam pagi", pm, morningHour, false},
+		{"AM dan jam pagi", am, morningHour, true},
+		{"PM dan jam siang", pm, afternoonHour, true},
+		{"dua jam berbeda", morningHour, afternoonHour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LeavesOverlap(schedule, tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("LeavesOverlap: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LeavesOverlap = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdjustScheduleForLeaves(t *testing.T) {
+	schedule := &models.WorkSchedule{StartTime: "08:00", EndTime: "16:00"}
+	hourly := func(start, end string) models.LeaveRequest {
+		return models.LeaveRequest{Duration: models.LeaveDurationHourly, StartTime: start, EndTime: end}
+	}
+	half := func(period string) models.LeaveRequest {
+		return models.LeaveRequest{Duration: models.LeaveDurationHalfDay, HalfDayPeriod: period}
+	}
+
+	tests := []struct {
+		name        string
+		leaves      []models.LeaveRequest
+		start, end  string
+		wantCovered bool
+	}{
+		{"tanpa cuti", nil, "08:00", "16:00", false},
+		{"AM", []models.LeaveRequest{half(models.HalfDayPeriodAM)}, "12:00", "16:00", false},
+		{"PM", []models.LeaveRequest{half(models.HalfDayPeriodPM)}, "08:00", "12:00", false},
+		{"AM dan PM", []models.LeaveRequest{half(models.HalfDayPeriodPM), half(models.HalfDayPeriodAM)}, "", "", true},
+		{"cuti penuh", []models.LeaveRequest{{Duration: models.LeaveDurationFullDay}}, "", "", true},
+		{"jam bersambung di awal", []models.LeaveRequest{hourly("10:00", "11:00"), hourly("08:00", "10:00")}, "11:00", "16:00", false},
+		{"AM lalu jam siang", []models.LeaveRequest{hourly("12:00", "13:00"), half(models.HalfDayPeriodAM)}, "13:00", "16:00", false},
+		{"jam di akhir", []models.LeaveRequest{hourly("15:00", "16:00")}, "08:00", "15:00", false},
+		{"jam di tengah", []models.LeaveRequest{hourly("10:00", "11:00")}, "08:00", "16:00", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adjusted, covered, err := AdjustScheduleForLeaves(schedule, tt.leaves)
+			if err != nil {
+				t.Fatalf("AdjustScheduleForLeaves: %v", err)
+			}
+			if covered != tt.wantCovered {
+				t.Fatalf("covered = %v, want %v", covered, tt.wantCovered)
+			}
+			if !covered && (adjusted.StartTime != tt.start || adjusted.EndTime != tt.end) {
+				t.Errorf("jadwal = %s-%s, want %s-%s", adjusted.StartTime, adjusted.EndTime, tt.start, tt.end)
+			}
+		})
+	}
+	if schedule.StartTime != "08:00" || schedule.EndTime != "16:00" {
+		t.Errorf("jadwal asli ikut berubah: %s-%s", schedule.StartTime, schedule.EndTime)
+	}
+}
@@ -0,0 +1,51 @@
+package util
+
+import "strings"
+
+// DescribeUserAgent meringkas header User-Agent menjadi nama browser/klien dan sistem operasi
+// untuk ditampilkan di daftar sesi, misal: "Chrome di Windows".
+func DescribeUserAgent(userAgent string) string {
+	if strings.TrimSpace(userAgent) == "" {
+		return "Perangkat tidak dikenal"
+	}
+	ua := strings.ToLower(userAgent)
+
+	client := "Aplikasi lain"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		client = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		client = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		client = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		client = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		client = "Safari"
+	case strings.Contains(ua, "okhttp"), strings.Contains(ua, "dart"):
+		client = "Aplikasi mobile"
+	case strings.Contains(ua, "postman"):
+		client = "Postman"
+	case strings.Contains(ua, "curl/"):
+		client = "curl"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "android"):
+		platform = "Android"
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		platform = "iOS"
+	case strings.Contains(ua, "windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "mac os x") || strings.Contains(ua, "macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "linux"):
+		platform = "Linux"
+	}
+
+	if platform == "" {
+		return client
+	}
+	return client + " di " + platform
+}
@@ -51,6 +51,10 @@ func ValidateStruct(s interface{}) []*ErrorResponse {
 
 			case "url":
 				element.Msg = fmt.Sprintf("Kolom '%s' harus berupa format URL yang valid.", element.Field)
+			case "len":
+				element.Msg = fmt.Sprintf("Kolom '%s' harus tepat %s karakter.", element.Field, err.Param())
+			case "numeric":
+				element.Msg = fmt.Sprintf("Kolom '%s' hanya boleh berisi angka.", element.Field)
 			case "oneof":
 				element.Msg = fmt.Sprintf("Kolom '%s' harus salah satu dari: %s.", element.Field, err.Param())
 			default:
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+type ActivityRepository interface {
+	Create(ctx context.Context, activity *models.Activity) (*mongo.InsertOneResult, error)
+	Find(ctx context.Context, activityType string, page, limit int64) ([]models.Activity, int64, error)
+}
+
+type activityRepository struct {
+	collection *mongo.Collection
+}
+
+func NewActivityRepository() ActivityRepository {
+	return &activityRepository{
+		collection: config.GetCollection(config.ActivityCollection),
+	}
+}
+
+func (r *activityRepository) Create(ctx context.Context, activity *models.Activity) (*mongo.InsertOneResult, error) {
+	activity.ID = primitive.NewObjectID()
+	activity.CreatedAt = time.Now()
+	activity.UserName = ""
+
+	result, err := r.collection.InsertOne(ctx, activity)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan aktivitas: %w", err)
+	}
+	return result, nil
+}
+
+// Find mengambil aktivitas terbaru lebih dulu, opsional difilter berdasarkan jenis, lengkap dengan
+// nama karyawan yang bersangkutan. Aktivitas milik user yang sudah dihapus tetap ditampilkan tanpa nama.
+func (r *activityRepository) Find(ctx context.Context, activityType string, page, limit int64) ([]models.Activity, int64, error) {
+	filter := bson.D{}
+	if activityType != "" {
+		filter = append(filter, bson.E{Key: "type", Value: activityType})
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gagal menghitung aktivitas: %w", err)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$skip", Value: (page - 1) * limit}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: config.UserCollection},
+			{Key: "localField", Value: "user_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "userDetails"},
+		}}},
+		{{Key: "$set", Value: bson.D{
+			{Key: "user_name", Value: bson.D{{Key: "$arrayElemAt", Value: bson.A{"$userDetails.name", 0}}}},
+		}}},
+		{{Key: "$project", Value: bson.D{{Key: "userDetails", Value: 0}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gagal mengambil aktivitas: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	activities := []models.Activity{}
+	if err = cursor.All(ctx, &activities); err != nil {
+		return nil, 0, fmt.Errorf("gagal decode aktivitas: %w", err)
+	}
+	return activities, total, nil
+}
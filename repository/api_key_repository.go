@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+// apiKeyLastUsedInterval membatasi penulisan last_used_at agar tidak terjadi update setiap request.
+const apiKeyLastUsedInterval = time.Minute
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) (*mongo.InsertOneResult, error)
+	FindAll(ctx context.Context, includeRevoked bool) ([]models.APIKey, error)
+	FindActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Revoke(ctx context.Context, id, revokedBy primitive.ObjectID) (*mongo.UpdateResult, error)
+	TouchLastUsed(ctx context.Context, id primitive.ObjectID, ip string) error
+}
+
+type apiKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyRepository() APIKeyRepository {
+	return &apiKeyRepository{
+		collection: config.GetCollection(config.APIKeyCollection),
+	}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) (*mongo.InsertOneResult, error) {
+	key.ID = primitive.NewObjectID()
+	key.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat API key: %w", err)
+	}
+	return result, nil
+}
+
+func (r *apiKeyRepository) FindAll(ctx context.Context, includeRevoked bool) ([]models.APIKey, error) {
+	filter := bson.M{}
+	if !includeRevoked {
+		filter["revoked_at"] = bson.M{"$exists": false}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil API key: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	keys := []models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("gagal decode API key: %w", err)
+	}
+	return keys, nil
+}
+
+// FindActiveByHash mencari API key yang belum dicabut dan belum kedaluwarsa.
+func (r *apiKeyRepository) FindActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	filter := bson.M{
+		"key_hash":   keyHash,
+		"revoked_at": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+
+	var key models.APIKey
+	err := r.collection.FindOne(ctx, filter).Decode(&key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal mencari API key: %w", err)
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id, revokedBy primitive.ObjectID) (*mongo.UpdateResult, error) {
+	filter := bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now(), "revoked_by": revokedBy}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mencabut API key: %w", err)
+	}
+	return result, nil
+}
+
+// TouchLastUsed mencatat waktu dan IP pemakaian terakhir, paling sering sekali per apiKeyLastUsedInterval.
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, ip string) error {
+	now := time.Now()
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"last_used_at": bson.M{"$exists": false}},
+			bson.M{"last_used_at": bson.M{"$lt": now.Add(-apiKeyLastUsedInterval)}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"last_used_at": now, "last_used_ip": ip}}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("gagal mencatat pemakaian API key: %w", err)
+	}
+	return nil
+}
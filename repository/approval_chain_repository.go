@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+type ApprovalChainRepository interface {
+	Create(ctx context.Context, chain *models.ApprovalChain) (*mongo.InsertOneResult, error)
+	FindAll(ctx context.Context) ([]models.ApprovalChain, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.ApprovalChain, error)
+	Update(ctx context.Context, id primitive.ObjectID, updateData bson.M) (*mongo.UpdateResult, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (*mongo.DeleteResult, error)
+	FindBestMatch(ctx context.Context, requestType string, department string) (*models.ApprovalChain, error)
+}
+
+type approvalChainRepository struct {
+	collection *mongo.Collection
+}
+
+func NewApprovalChainRepository() ApprovalChainRepository {
+	return &approvalChainRepository{
+		collection: config.GetCollection(config.ApprovalChainCollection),
+	}
+}
+
+func (r *approvalChainRepository) Create(ctx context.Context, chain *models.ApprovalChain) (*mongo.InsertOneResult, error) {
+	chain.ID = primitive.NewObjectID()
+	chain.CreatedAt = time.Now()
+	chain.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, chain)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat rantai persetujuan: %w", err)
+	}
+	return result, nil
+}
+
+func (r *approvalChainRepository) FindAll(ctx context.Context) ([]models.ApprovalChain, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil rantai persetujuan: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var chains []models.ApprovalChain
+	if err = cursor.All(ctx, &chains); err != nil {
+		return nil, fmt.Errorf("gagal decode rantai persetujuan: %w", err)
+	}
+	if len(chains) == 0 {
+		return []models.ApprovalChain{}, nil
+	}
+	return chains, nil
+}
+
+func (r *approvalChainRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.ApprovalChain, error) {
+	var chain models.ApprovalChain
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&chain)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal menemukan rantai persetujuan berdasarkan ID: %w", err)
+	}
+	return &chain, nil
+}
+
+func (r *approvalChainRepository) Update(ctx context.Context, id primitive.ObjectID, updateData bson.M) (*mongo.UpdateResult, error) {
+	updateData["updated_at"] = time.Now()
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": updateData})
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengupdate rantai persetujuan: %w", err)
+	}
+	return result, nil
+}
+
+func (r *approvalChainRepository) Delete(ctx context.Context, id primitive.ObjectID) (*mongo.DeleteResult, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, fmt.Errorf("gagal menghapus rantai persetujuan: %w", err)
+	}
+	return result, nil
+}
+
+// FindBestMatch mencari rantai persetujuan paling spesifik untuk jenis pengajuan dan departemen.
+// Urutan prioritas: jenis+departemen, departemen saja, jenis saja, lalu rantai umum (keduanya kosong).
+// Mengembalikan nil jika tidak ada rantai yang cocok.
+func (r *approvalChainRepository) FindBestMatch(ctx context.Context, requestType string, department string) (*models.ApprovalChain, error) {
+	filter := bson.M{
+		"$and": []bson.M{
+			{"$or": []bson.M{
+				{"request_type": requestType},
+				{"request_type": ""},
+				{"request_type": bson.M{"$exists": false}},
+			}},
+			{"$or": []bson.M{
+				{"department": department},
+				{"department": ""},
+				{"department": bson.M{"$exists": false}},
+			}},
+		},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mencari rantai persetujuan yang cocok: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []models.ApprovalChain
+	if err = cursor.All(ctx, &candidates); err != nil {
+		return nil, fmt.Errorf("gagal decode rantai persetujuan yang cocok: %w", err)
+	}
+
+	var best *models.ApprovalChain
+	bestScore := -1
+	for i := range candidates {
+		score := 0
+		if candidates[i].Department != "" {
+			score += 2
+		}
+		if candidates[i].RequestType != "" {
+			score++
+		}
+		if score > bestScore {
+			best = &candidates[i]
+			bestScore = score
+		}
+	}
+	return best, nil
+}
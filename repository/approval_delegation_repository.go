@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+type ApprovalDelegationRepository interface {
+	Create(ctx context.Context, delegation *models.ApprovalDelegation) (*mongo.InsertOneResult, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.ApprovalDelegation, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.ApprovalDelegation, error)
+	FindOverlapping(ctx context.Context, delegatorID primitive.ObjectID, startDate, endDate string) (*models.ApprovalDelegation, error)
+	FindActiveForDelegate(ctx context.Context, delegateID primitive.ObjectID, date string) ([]models.ApprovalDelegation, error)
+	Revoke(ctx context.Context, id primitive.ObjectID) (*mongo.UpdateResult, error)
+}
+
+type approvalDelegationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewApprovalDelegationRepository() ApprovalDelegationRepository {
+	return &approvalDelegationRepository{
+		collection: config.GetCollection(config.ApprovalDelegationCollection),
+	}
+}
+
+func (r *approvalDelegationRepository) Create(ctx context.Context, delegation *models.ApprovalDelegation) (*mongo.InsertOneResult, error) {
+	delegation.ID = primitive.NewObjectID()
+	delegation.CreatedAt = time.Now()
+	delegation.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, delegation)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat delegasi persetujuan: %w", err)
+	}
+	return result, nil
+}
+
+func (r *approvalDelegationRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.ApprovalDelegation, error) {
+	var delegation models.ApprovalDelegation
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&delegation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal menemukan delegasi persetujuan: %w", err)
+	}
+	return &delegation, nil
+}
+
+// FindByUser mengambil delegasi yang diberikan maupun diterima oleh user.
+func (r *approvalDelegationRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.ApprovalDelegation, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"delegator_id": userID},
+		bson.M{"delegate_id": userID},
+	}}
+	opts := options.Find().SetSort(bson.D{{Key: "start_date", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil delegasi persetujuan: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var delegations []models.ApprovalDelegation
+	if err = cursor.All(ctx, &delegations); err != nil {
+		return nil, fmt.Errorf("gagal decode delegasi persetujuan: %w", err)
+	}
+	if len(delegations) == 0 {
+		return []models.ApprovalDelegation{}, nil
+	}
+	return delegations, nil
+}
+
+// FindOverlapping mencari delegasi aktif milik delegator yang beririsan dengan rentang tanggal.
+func (r *approvalDelegationRepository) FindOverlapping(ctx context.Context, delegatorID primitive.ObjectID, startDate, endDate string) (*models.ApprovalDelegation, error) {
+	filter := bson.M{
+		"delegator_id": delegatorID,
+		"revoked_at":   bson.M{"$exists": false},
+		"start_date":   bson.M{"$lte": endDate},
+		"end_date":     bson.M{"$gte": startDate},
+	}
+
+	var delegation models.ApprovalDelegation
+	err := r.collection.FindOne(ctx, filter).Decode(&delegation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal memeriksa delegasi yang beririsan: %w", err)
+	}
+	return &delegation, nil
+}
+
+// FindActiveForDelegate mengambil delegasi yang berlaku untuk delegate pada tanggal tertentu.
+func (r *approvalDelegationRepository) FindActiveForDelegate(ctx context.Context, delegateID primitive.ObjectID, date string) ([]models.ApprovalDelegation, error) {
+	filter := bson.M{
+		"delegate_id": delegateID,
+		"revoked_at":  bson.M{"$exists": false},
+		"start_date":  bson.M{"$lte": date},
+		"end_date":    bson.M{"$gte": date},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil delegasi aktif: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var delegations []models.ApprovalDelegation
+	if err = cursor.All(ctx, &delegations); err != nil {
+		return nil, fmt.Errorf("gagal decode delegasi aktif: %w", err)
+	}
+	return delegations, nil
+}
+
+func (r *approvalDelegationRepository) Revoke(ctx context.Context, id primitive.ObjectID) (*mongo.UpdateResult, error) {
+	now := time.Now()
+	filter := bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": now, "updated_at": now}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mencabut delegasi persetujuan: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+// attendanceStatuses memetakan status absensi ke field AttendanceStatusCounts.
+var attendanceStatuses = []struct {
+	Status string
+	Field  string
+}{
+	{"Hadir", "hadir"},
+	{"Terlambat", "terlambat"},
+	{"Sakit", "sakit"},
+	{"Cuti", "cuti"},
+	{"Izin", "izin"},
+	{"Alpha", "alpha"},
+}
+
+// statusCountAccumulators adalah accumulator $group yang menghitung record absensi per status.
+func statusCountAccumulators() bson.D {
+	fields := bson.D{}
+	for _, s := range attendanceStatuses {
+		fields = append(fields, bson.E{Key: s.Field, Value: bson.D{{Key: "$sum", Value: bson.D{
+			{Key: "$cond", Value: bson.A{bson.D{{Key: "$eq", Value: bson.A{"$status", s.Status}}}, 1, 0}},
+		}}}})
+	}
+	return append(fields,
+		bson.E{Key: "total_records", Value: bson.D{{Key: "$sum", Value: 1}}},
+		bson.E{Key: "total_late_minutes", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$late_minutes", 0}}}}}},
+	)
+}
+
+// statusCountTotals adalah accumulator $group yang menjumlahkan hasil statusCountAccumulators.
+func statusCountTotals() bson.D {
+	fields := bson.D{}
+	for _, s := range attendanceStatuses {
+		fields = append(fields, bson.E{Key: s.Field, Value: bson.D{{Key: "$sum", Value: "$" + s.Field}}})
+	}
+	return append(fields,
+		bson.E{Key: "total_records", Value: bson.D{{Key: "$sum", Value: "$total_records"}}},
+		bson.E{Key: "total_late_minutes", Value: bson.D{{Key: "$sum", Value: "$total_late_minutes"}}},
+	)
+}
+
+func dateRangeMatch(startDate, endDate string) bson.D {
+	return bson.D{{Key: "$match", Value: bson.D{{Key: "date", Value: bson.D{
+		{Key: "$gte", Value: startDate},
+		{Key: "$lte", Value: endDate},
+	}}}}}
+}
+
+// userLookupStages menggabungkan data user berdasarkan localField dan, jika department diisi,
+// hanya menyisakan user di departemen tersebut. Record milik user yang sudah dihapus dibuang.
+func userLookupStages(localField, department string) mongo.Pipeline {
+	stages := mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: config.UserCollection},
+			{Key: "localField", Value: localField},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "userDetails"},
+		}}},
+		{{Key: "$unwind", Value: "$userDetails"}},
+	}
+	if department != "" {
+		stages = append(stages, bson.D{{Key: "$match", Value: bson.D{{Key: "userDetails.department", Value: department}}}})
+	}
+	return stages
+}
+
+// userNameStages menyalin nama dan departemen dari userDetails lalu membuang userDetails.
+var userNameStages = mongo.Pipeline{
+	{{Key: "$set", Value: bson.D{
+		{Key: "user_name", Value: "$userDetails.name"},
+		{Key: "user_department", Value: "$userDetails.department"},
+	}}},
+	{{Key: "$project", Value: bson.D{{Key: "userDetails", Value: 0}}}},
+}
+
+// SummarizeByEmployee menghitung absensi per status dan total menit keterlambatan setiap karyawan.
+func (r *attendanceRepository) SummarizeByEmployee(ctx context.Context, startDate, endDate, department string) ([]models.EmployeeAttendanceSummary, error) {
+	pipeline := mongo.Pipeline{
+		dateRangeMatch(startDate, endDate),
+		{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: "$user_id"}}, statusCountAccumulators()...)}},
+	}
+	pipeline = append(pipeline, userLookupStages("_id", department)...)
+	pipeline = append(pipeline, userNameStages...)
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: bson.D{{Key: "user_name", Value: 1}}}})
+
+	summaries := []models.EmployeeAttendanceSummary{}
+	if err := r.aggregateAll(ctx, pipeline, &summaries); err != nil {
+		return nil, fmt.Errorf("gagal menghitung ringkasan absensi karyawan: %w", err)
+	}
+	for i := range summaries {
+		summaries[i].ComputeAttendanceRate()
+	}
+	return summaries, nil
+}
+
+// SummarizeByDepartment menghitung absensi per status dan total menit keterlambatan setiap departemen.
+func (r *attendanceRepository) SummarizeByDepartment(ctx context.Context, startDate, endDate string) ([]models.DepartmentAttendanceSummary, error) {
+	// Dikelompokkan per user lebih dulu agar data user cukup digabungkan sekali per karyawan.
+	pipeline := mongo.Pipeline{
+		dateRangeMatch(startDate, endDate),
+		{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: "$user_id"}}, statusCountAccumulators()...)}},
+	}
+	pipeline = append(pipeline, userLookupStages("_id", "")...)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: append(bson.D{
+			{Key: "_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$userDetails.department", ""}}}},
+			{Key: "employees", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}, statusCountTotals()...)}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	)
+
+	summaries := []models.DepartmentAttendanceSummary{}
+	if err := r.aggregateAll(ctx, pipeline, &summaries); err != nil {
+		return nil, fmt.Errorf("gagal menghitung ringkasan absensi departemen: %w", err)
+	}
+	for i := range summaries {
+		summaries[i].ComputeAttendanceRate()
+	}
+	return summaries, nil
+}
+
+// DailyTrend menghitung absensi per status untuk setiap tanggal yang memiliki record absensi.
+func (r *attendanceRepository) DailyTrend(ctx context.Context, startDate, endDate, department string) ([]models.DailyAttendanceTrend, error) {
+	pipeline := mongo.Pipeline{dateRangeMatch(startDate, endDate)}
+	if department != "" {
+		pipeline = append(pipeline, userLookupStages("user_id", department)...)
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: "$date"}}, statusCountAccumulators()...)}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	)
+
+	trend := []models.DailyAttendanceTrend{}
+	if err := r.aggregateAll(ctx, pipeline, &trend); err != nil {
+		return nil, fmt.Errorf("gagal menghitung tren absensi harian: %w", err)
+	}
+	for i := range trend {
+		trend[i].ComputeAttendanceRate()
+	}
+	return trend, nil
+}
+
+// TopLateness mengurutkan karyawan berdasarkan total menit keterlambatan, lalu jumlah hari terlambat.
+func (r *attendanceRepository) TopLateness(ctx context.Context, startDate, endDate, department string, limit int64) ([]models.LatenessRanking, error) {
+	pipeline := mongo.Pipeline{
+		dateRangeMatch(startDate, endDate),
+		{{Key: "$match", Value: bson.D{{Key: "status", Value: "Terlambat"}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$user_id"},
+			{Key: "late_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "total_late_minutes", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$late_minutes", 0}}}}}},
+		}}},
+	}
+	pipeline = append(pipeline, userLookupStages("_id", department)...)
+	pipeline = append(pipeline, userNameStages...)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "total_late_minutes", Value: -1}, {Key: "late_count", Value: -1}, {Key: "user_name", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+
+	rankings := []models.LatenessRanking{}
+	if err := r.aggregateAll(ctx, pipeline, &rankings); err != nil {
+		return nil, fmt.Errorf("gagal menghitung peringkat keterlambatan: %w", err)
+	}
+	return rankings, nil
+}
+
+func (r *attendanceRepository) aggregateAll(ctx context.Context, pipeline mongo.Pipeline, results interface{}) error {
+	cursor, err := r.attendanceCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, results)
+}
@@ -8,6 +8,7 @@ import (
 
 	"Sistem-Manajemen-Karyawan/config"
 	"Sistem-Manajemen-Karyawan/models"
+	util "Sistem-Manajemen-Karyawan/pkg/utils"
 
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -32,6 +33,18 @@ type AttendanceRepository interface {
 	FindAttendanceByUserID(ctx context.Context, userID primitive.ObjectID) ([]models.Attendance, error)
     UpdateAttendance(ctx context.Context, id primitive.ObjectID, payload *models.AttendanceUpdatePayload) (*mongo.UpdateResult, error)
 	GetAllAttendancesWithUserDetails(ctx context.Context, filter bson.M, page, limit int64) ([]models.AttendanceWithUser, int64, error)
+	LinkAttendanceToLeaveRequest(ctx context.Context, attendanceID primitive.ObjectID, leaveRequestID primitive.ObjectID, previous *models.AttendanceLeaveSnapshot) (*mongo.UpdateResult, error)
+	RevertLeaveAttendances(ctx context.Context, leaveRequestID primitive.ObjectID) (removed int64, restored int64, err error)
+	CountByStatusForUsersAndDate(ctx context.Context, userIDs []primitive.ObjectID, date string) (map[string]int64, error)
+	CountByStatusForDate(ctx context.Context, date string) (map[string]int64, error)
+	ApplyLeaveDecision(ctx context.Context, request *models.LeaveRequest, status string, note string) error
+
+	// Analitik absensi, lihat attendance_analytics.go. Rentang tanggal inklusif (YYYY-MM-DD);
+	// department kosong berarti semua departemen.
+	SummarizeByEmployee(ctx context.Context, startDate, endDate, department string) ([]models.EmployeeAttendanceSummary, error)
+	SummarizeByDepartment(ctx context.Context, startDate, endDate string) ([]models.DepartmentAttendanceSummary, error)
+	DailyTrend(ctx context.Context, startDate, endDate, department string) ([]models.DailyAttendanceTrend, error)
+	TopLateness(ctx context.Context, startDate, endDate, department string, limit int64) ([]models.LatenessRanking, error)
 MarkAbsentEmployeesAsAlpha(
         ctx context.Context,
         userRepo *UserRepository, // <--- TAMBAHKAN *
@@ -138,6 +151,7 @@ func (r *attendanceRepository) GetAllAttendancesWithUserDetails(ctx context.Cont
 			{Key: "check_out", Value: 1},
 			{Key: "status", Value: 1},
 			{Key: "note", Value: 1},
+			{Key: "late_minutes", Value: 1},
 			{Key: "user_name", Value: "$userDetails.name"},
 			{Key: "user_email", Value: "$userDetails.email"},
 			{Key: "user_photo", Value: "$userDetails.photo"},        
@@ -245,6 +259,7 @@ func (r *attendanceRepository) GetTodayAttendanceWithUserDetails(ctx context.Con
             {Key: "check_out", Value: 1},
             {Key: "status", Value: 1},
             {Key: "note", Value: 1},
+            {Key: "late_minutes", Value: 1},
             {Key: "user_name", Value: "$userDetails.name"},
             {Key: "user_email", Value: "$userDetails.email"},
             {Key: "user_photo", Value: "$userDetails.photo"},
@@ -301,6 +316,10 @@ func (r *attendanceRepository) UpdateAttendance(ctx context.Context, id primitiv
 	}
 	if payload.Status != "" { // Pastikan ini juga diupdate
 		update["$set"].(bson.M)["status"] = payload.Status
+		// Menit keterlambatan hanya berlaku untuk status Terlambat.
+		if payload.Status != "Terlambat" {
+			update["$unset"] = bson.M{"late_minutes": ""}
+		}
 	}
 	if payload.Note != "" { // Pastikan ini juga diupdate
 		update["$set"].(bson.M)["note"] = payload.Note
@@ -314,6 +333,62 @@ func (r *attendanceRepository) UpdateAttendance(ctx context.Context, id primitiv
 	return res, nil
 }
 
+// LinkAttendanceToLeaveRequest menandai record absensi yang diubah oleh persetujuan cuti beserta
+// isi record sebelum diubah, agar dapat dikembalikan oleh RevertLeaveAttendances.
+func (r *attendanceRepository) LinkAttendanceToLeaveRequest(ctx context.Context, attendanceID primitive.ObjectID, leaveRequestID primitive.ObjectID, previous *models.AttendanceLeaveSnapshot) (*mongo.UpdateResult, error) {
+	update := bson.M{
+		"$set": bson.M{
+			"leave_request_id": leaveRequestID,
+			"leave_previous":   previous,
+			"updated_at":       time.Now(),
+		},
+	}
+	res, err := r.attendanceCollection.UpdateByID(ctx, attendanceID, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menautkan absensi ke pengajuan cuti: %w", err)
+	}
+	return res, nil
+}
+
+// RevertLeaveAttendances membatalkan perubahan absensi dari persetujuan sebuah pengajuan cuti saat
+// pengajuan ditarik: record yang diubah dikembalikan ke isi sebelumnya (restored), sedangkan record
+// yang dibuat oleh persetujuan dihapus (removed). Record lama yang tertaut tanpa LeavePrevious tetapi
+// memiliki check-in dianggap sudah ada sebelumnya, sehingga hanya dilepas tautannya.
+func (r *attendanceRepository) RevertLeaveAttendances(ctx context.Context, leaveRequestID primitive.ObjectID) (int64, int64, error) {
+	restoreResult, err := r.attendanceCollection.UpdateMany(ctx,
+		bson.M{"leave_request_id": leaveRequestID, "leave_previous": bson.M{"$exists": true}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.D{
+				{Key: "status", Value: "$leave_previous.status"},
+				{Key: "note", Value: "$leave_previous.note"},
+				{Key: "late_minutes", Value: "$leave_previous.late_minutes"},
+				{Key: "updated_at", Value: time.Now()},
+			}}},
+			{{Key: "$unset", Value: bson.A{"leave_request_id", "leave_previous"}}},
+		},
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("gagal mengembalikan absensi dari pengajuan cuti: %w", err)
+	}
+
+	deleteResult, err := r.attendanceCollection.DeleteMany(ctx, bson.M{
+		"leave_request_id": leaveRequestID,
+		"check_in":         bson.M{"$exists": false},
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("gagal menghapus absensi dari pengajuan cuti: %w", err)
+	}
+
+	_, err = r.attendanceCollection.UpdateMany(ctx,
+		bson.M{"leave_request_id": leaveRequestID},
+		bson.M{"$unset": bson.M{"leave_request_id": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("gagal melepas tautan absensi dari pengajuan cuti: %w", err)
+	}
+	return deleteResult.DeletedCount, restoreResult.ModifiedCount, nil
+}
+
 // file: repository/attendance_repository.go
 // (Tambahkan di bagian bawah file)
 
@@ -321,6 +396,132 @@ func (r *attendanceRepository) UpdateAttendance(ctx context.Context, id primitiv
 // Ia membutuhkan repository lain sebagai argumen untuk melakukan tugasnya.
 // file: repository/attendance_repository.go
 
+// ApplyLeaveDecision menulis record absensi untuk setiap tanggal pengajuan setelah keputusan akhir
+// (approved/rejected). Error pada tanggal mana pun langsung dikembalikan agar pemanggil dapat
+// membatalkan transaksi; jalankan di dalam config.WithTransaction.
+func (r *attendanceRepository) ApplyLeaveDecision(ctx context.Context, request *models.LeaveRequest, status string, note string) error {
+	// Cuti setengah hari / per jam tidak membuat record absensi: karyawan tetap check-in,
+	// dan jam masuk/pulangnya digeser saat scan (lihat AttendanceHandler.ScanQRCode).
+	if request.IsPartialDay() {
+		return nil
+	}
+
+	startDate, err := time.Parse("2006-01-02", request.StartDate)
+	if err != nil {
+		return fmt.Errorf("gagal parse start_date %s: %w", request.StartDate, err)
+	}
+	endDate, err := time.Parse("2006-01-02", request.EndDate)
+	if err != nil {
+		return fmt.Errorf("gagal parse end_date %s: %w", request.EndDate, err)
+	}
+
+	for d := startDate; !d.After(endDate); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+
+		existing, err := r.FindAttendanceByUserAndDate(ctx, request.UserID, date)
+		if err != nil {
+			return fmt.Errorf("gagal mencari absensi tanggal %s: %w", date, err)
+		}
+
+		switch status {
+		case "approved":
+			attendanceNote := fmt.Sprintf("Disetujui: %s. Catatan admin: %s", request.Reason, note)
+			if existing == nil {
+				attendance := &models.Attendance{
+					ID:             primitive.NewObjectID(),
+					UserID:         request.UserID,
+					Date:           date,
+					Status:         request.RequestType,
+					Note:           attendanceNote,
+					LeaveRequestID: &request.ID,
+					CreatedAt:      time.Now(),
+					UpdatedAt:      time.Now(),
+				}
+				if _, err := r.CreateAttendance(ctx, attendance); err != nil {
+					return fmt.Errorf("gagal menyimpan absensi tanggal %s: %w", date, err)
+				}
+				continue
+			}
+
+			previous := &models.AttendanceLeaveSnapshot{Status: existing.Status, Note: existing.Note, LateMinutes: existing.LateMinutes}
+			payload := models.AttendanceUpdatePayload{Status: request.RequestType, Note: attendanceNote}
+			if _, err := r.UpdateAttendance(ctx, existing.ID, &payload); err != nil {
+				return fmt.Errorf("gagal memperbarui absensi tanggal %s: %w", date, err)
+			}
+			if _, err := r.LinkAttendanceToLeaveRequest(ctx, existing.ID, request.ID, previous); err != nil {
+				return fmt.Errorf("gagal menautkan absensi tanggal %s ke pengajuan: %w", date, err)
+			}
+
+		case "rejected":
+			attendanceNote := fmt.Sprintf("Pengajuan ditolak: %s. Catatan admin: %s", request.Reason, note)
+			if existing == nil {
+				attendance := &models.Attendance{
+					ID:        primitive.NewObjectID(),
+					UserID:    request.UserID,
+					Date:      date,
+					Status:    "Tidak Absen",
+					Note:      attendanceNote,
+					CreatedAt: time.Now(),
+					UpdatedAt: time.Now(),
+				}
+				if _, err := r.CreateAttendance(ctx, attendance); err != nil {
+					return fmt.Errorf("gagal membuat absensi default tanggal %s: %w", date, err)
+				}
+				continue
+			}
+
+			// Hanya record Sakit/Cuti tanpa check-in/check-out yang dikembalikan ke "Tidak Absen".
+			if (existing.Status == "Sakit" || existing.Status == "Cuti") && existing.CheckIn == "" && existing.CheckOut == "" {
+				payload := models.AttendanceUpdatePayload{Status: "Tidak Absen", Note: attendanceNote}
+				if _, err := r.UpdateAttendance(ctx, existing.ID, &payload); err != nil {
+					return fmt.Errorf("gagal memperbarui absensi tanggal %s: %w", date, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CountByStatusForUsersAndDate mengelompokkan absensi sekumpulan user pada tanggal tertentu berdasarkan status.
+func (r *attendanceRepository) CountByStatusForUsersAndDate(ctx context.Context, userIDs []primitive.ObjectID, date string) (map[string]int64, error) {
+	return r.countByStatus(ctx, bson.M{"user_id": bson.M{"$in": userIDs}, "date": date})
+}
+
+// CountByStatusForDate mengelompokkan seluruh absensi pada tanggal tertentu berdasarkan status.
+func (r *attendanceRepository) CountByStatusForDate(ctx context.Context, date string) (map[string]int64, error) {
+	return r.countByStatus(ctx, bson.M{"date": date})
+}
+
+func (r *attendanceRepository) countByStatus(ctx context.Context, match bson.M) (map[string]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$status"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+
+	cursor, err := r.attendanceCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menghitung absensi per status: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Status string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("gagal decode hasil absensi per status: %w", err)
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
 func (r *attendanceRepository) MarkAbsentEmployeesAsAlpha(
     ctx context.Context,
     userRepo *UserRepository,
@@ -371,8 +572,10 @@ func (r *attendanceRepository) MarkAbsentEmployeesAsAlpha(
             continue
         }
 
-        leave, _ := leaveRequestRepo.FindApprovedRequestByUserAndDate(ctx, user.ID, today)
-        if leave != nil {
+        // Cuti setengah hari / per jam tetap mewajibkan kehadiran, jadi hanya dilewati jika cuti
+        // menutup seluruh jam kerja (cuti penuh, atau cuti AM dan PM di hari yang sama).
+        leaves, _ := leaveRequestRepo.FindApprovedRequestsByUserAndDate(ctx, user.ID, today)
+        if _, covered, err := util.AdjustScheduleForLeaves(schedule, leaves); err != nil || covered {
             continue
         }
 
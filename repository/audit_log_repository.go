@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+// AuditLogRepository sengaja hanya menyediakan Create dan pencarian: audit log bersifat append-only.
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *models.AuditLog) (*mongo.InsertOneResult, error)
+	Find(ctx context.Context, filter models.AuditLogFilter, page, limit int64) ([]models.AuditLog, int64, error)
+}
+
+type auditLogRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditLogRepository() AuditLogRepository {
+	// Nilai before/after pada perubahan bisa berupa dokumen bersarang; didecode sebagai map agar
+	// tampil sebagai objek JSON biasa, bukan daftar key/value.
+	collection, err := config.GetCollection(config.AuditLogCollection).Clone(
+		options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true}),
+	)
+	if err != nil {
+		log.Fatalf("Gagal menyiapkan koleksi audit log: %v", err)
+	}
+	return &auditLogRepository{
+		collection: collection,
+	}
+}
+
+func (r *auditLogRepository) Create(ctx context.Context, entry *models.AuditLog) (*mongo.InsertOneResult, error) {
+	entry.ID = primitive.NewObjectID()
+	entry.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan audit log: %w", err)
+	}
+	return result, nil
+}
+
+// Find mengambil audit log yang cocok dengan filter, terbaru lebih dulu.
+func (r *auditLogRepository) Find(ctx context.Context, filter models.AuditLogFilter, page, limit int64) ([]models.AuditLog, int64, error) {
+	query := bson.M{}
+	if filter.ActorID != nil {
+		query["actor_id"] = *filter.ActorID
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.TargetType != "" {
+		query["target_type"] = filter.TargetType
+	}
+	if filter.TargetID != "" {
+		query["target_id"] = filter.TargetID
+	}
+	if filter.From != nil || filter.To != nil {
+		createdAt := bson.M{}
+		if filter.From != nil {
+			createdAt["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			createdAt["$lt"] = *filter.To
+		}
+		query["created_at"] = createdAt
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gagal menghitung audit log: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gagal mengambil audit log: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.AuditLog{}
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, 0, fmt.Errorf("gagal decode audit log: %w", err)
+	}
+	return entries, total, nil
+}
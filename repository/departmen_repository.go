@@ -19,10 +19,11 @@ type DepartmentRepository interface {
 	CreateDepartment(ctx context.Context, department *models.Department) (*mongo.InsertOneResult, error)
 	GetAllDepartments(ctx context.Context) ([]models.Department, error)
 	GetDepartmentByID(ctx context.Context, id primitive.ObjectID) (*models.Department, error)
-	UpdateDepartment(ctx context.Context, id primitive.ObjectID, updateData bson.M) (*mongo.UpdateResult, error)
+	UpdateDepartment(ctx context.Context, id primitive.ObjectID, updateData bson.M, unsetFields []string) (*mongo.UpdateResult, error)
 	DeleteDepartment(ctx context.Context, id primitive.ObjectID) (*mongo.DeleteResult, error)
 	FindDepartmentByName(ctx context.Context, name string) (*models.Department, error)
 	CountDocuments(ctx context.Context, filter bson.M) (int64, error) // <--- BARU: Tambahkan method ini ke interface
+	FindDepartmentsByHead(ctx context.Context, headID primitive.ObjectID) ([]models.Department, error)
 }
 
 type departmentRepository struct {
@@ -77,10 +78,18 @@ func (r *departmentRepository) GetDepartmentByID(ctx context.Context, id primiti
 	return &department, nil
 }
 
-func (r *departmentRepository) UpdateDepartment(ctx context.Context, id primitive.ObjectID, updateData bson.M) (*mongo.UpdateResult, error) {
+// UpdateDepartment mengisi field pada updateData dan menghapus field pada unsetFields (mis. head_id).
+func (r *departmentRepository) UpdateDepartment(ctx context.Context, id primitive.ObjectID, updateData bson.M, unsetFields []string) (*mongo.UpdateResult, error) {
 	updateData["updated_at"] = time.Now()
 	filter := bson.M{"_id": id}
 	update := bson.M{"$set": updateData}
+	if len(unsetFields) > 0 {
+		unset := bson.M{}
+		for _, field := range unsetFields {
+			unset[field] = ""
+		}
+		update["$unset"] = unset
+	}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -119,4 +128,18 @@ func (r *departmentRepository) CountDocuments(ctx context.Context, filter bson.M
 		return 0, fmt.Errorf("gagal menghitung dokumen dari koleksi departemen: %w", err)
 	}
 	return count, nil
-}
\ No newline at end of file
+}
+// FindDepartmentsByHead mengembalikan semua departemen yang dikepalai oleh user tertentu.
+func (r *departmentRepository) FindDepartmentsByHead(ctx context.Context, headID primitive.ObjectID) ([]models.Department, error) {
+	var departments []models.Department
+	cursor, err := r.collection.Find(ctx, bson.M{"head_id": headID})
+	if err != nil {
+		return nil, fmt.Errorf("gagal menemukan departemen berdasarkan kepala departemen: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &departments); err != nil {
+		return nil, fmt.Errorf("gagal mendecode departemen: %w", err)
+	}
+	return departments, nil
+}
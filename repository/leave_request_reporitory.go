@@ -3,7 +3,6 @@ package repository
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"Sistem-Manajemen-Karyawan/config"
@@ -17,17 +16,32 @@ import (
 
 // Perbarui interface LeaveRequestRepository
 type LeaveRequestRepository interface {
-	Create(req *models.LeaveRequest) (*mongo.InsertOneResult, error)
-	FindAll() ([]models.LeaveRequestWithUser, error) 
+	Create(ctx context.Context, req *models.LeaveRequest) (*mongo.InsertOneResult, error)
+	FindAllWithUserDetails(ctx context.Context, filter bson.M, sort bson.D, page, limit int64) ([]models.LeaveRequestWithUser, int64, error)
 	FindByID(id primitive.ObjectID) (*models.LeaveRequest, error)
 	UpdateStatus(id primitive.ObjectID, status string, note string) (*mongo.UpdateResult, error)
 	UpdateAttachmentURL(id primitive.ObjectID, fileURL string) (*mongo.UpdateResult, error)
 	CountPendingRequests(ctx context.Context) (int64, error)
 	FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]models.LeaveRequest, error)
-	FindByUserAndDateAndType(ctx context.Context, userID primitive.ObjectID, date string, requestType string) (*models.LeaveRequest, error)
+	FindByUserAndDateAndType(ctx context.Context, userID primitive.ObjectID, date string, requestType string, excludeID primitive.ObjectID) ([]models.LeaveRequest, error)
 	CountByUserIDMonthAndType(ctx context.Context, userID primitive.ObjectID, year int, month time.Month, requestType string) (int64, error)
-	 FindApprovedRequestByUserAndDate(ctx context.Context, userID primitive.ObjectID, date string) (*models.LeaveRequest, error)
+	 FindApprovedRequestsByUserAndDate(ctx context.Context, userID primitive.ObjectID, date string) ([]models.LeaveRequest, error)
 	 CountByUserIDYearAndType(ctx context.Context, userID primitive.ObjectID, year int, requestType string) (int64, error)
+	SumLeaveDaysByUserIDYearAndType(ctx context.Context, userID primitive.ObjectID, year int, requestType string) (float64, error)
+	CancelPendingRequest(ctx context.Context, id primitive.ObjectID, reason string) (*mongo.UpdateResult, error)
+	RequestWithdrawal(ctx context.Context, id primitive.ObjectID, reason string) (*mongo.UpdateResult, error)
+	ResolveWithdrawal(ctx context.Context, id primitive.ObjectID, approved bool, note string) (*mongo.UpdateResult, error)
+	RecordApprovalDecision(ctx context.Context, id primitive.ObjectID, stepIndex int, approvals []models.ApprovalStep, status string, note string) (*mongo.UpdateResult, error)
+	FindPendingForApprover(ctx context.Context, approverIDs []primitive.ObjectID, roles []string, includeAdminSteps bool) ([]models.LeaveRequestWithUser, error)
+	FindByUserIDs(ctx context.Context, userIDs []primitive.ObjectID, status string) ([]models.LeaveRequestWithUser, error)
+	CountApprovedOnDateForUsers(ctx context.Context, userIDs []primitive.ObjectID, date string) (int64, error)
+	CountApprovedOnDate(ctx context.Context, date string) (int64, error)
+	UpdatePendingRequest(ctx context.Context, id primitive.ObjectID, updateData bson.M, revision models.LeaveRequestRevision) (*mongo.UpdateResult, error)
+	MarkResubmitted(ctx context.Context, id primitive.ObjectID, newRequestID primitive.ObjectID) (*mongo.UpdateResult, error)
+	FindInDateRangeForUsers(ctx context.Context, userIDs []primitive.ObjectID, startDate, endDate string, statuses []string) ([]models.LeaveRequestWithUser, error)
+	FindPendingCreatedBefore(ctx context.Context, before time.Time) ([]models.LeaveRequest, error)
+	AppendAutoAction(ctx context.Context, id primitive.ObjectID, action models.LeaveAutoAction) (*mongo.UpdateResult, error)
+	EscalateApprovalStep(ctx context.Context, id primitive.ObjectID, stepIndex int, step models.ApprovalStep, action models.LeaveAutoAction) (*mongo.UpdateResult, error)
 }
 
 type leaveRequestRepository struct {
@@ -40,23 +54,52 @@ func NewLeaveRequestRepository() LeaveRequestRepository {
 	}
 }
 
-func (r *leaveRequestRepository) Create(req *models.LeaveRequest) (*mongo.InsertOneResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (r *leaveRequestRepository) Create(ctx context.Context, req *models.LeaveRequest) (*mongo.InsertOneResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	return r.collection.InsertOne(ctx, req)
 }
 
-func (r *leaveRequestRepository) FindAll() ([]models.LeaveRequestWithUser, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// FindAllWithUserDetails mengambil pengajuan sesuai filter dengan sorting dan pagination,
+// lalu menggabungkan detail user hanya untuk halaman yang diminta.
+func (r *leaveRequestRepository) FindAllWithUserDetails(ctx context.Context, filter bson.M, sort bson.D, page, limit int64) ([]models.LeaveRequestWithUser, int64, error) {
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gagal menghitung total pengajuan: %w", err)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: sort}},
+		{{Key: "$skip", Value: (page - 1) * limit}},
+		{{Key: "$limit", Value: limit}},
+	}
+	pipeline = append(pipeline, userDetailStages()...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gagal melakukan agregasi untuk pengajuan dengan detail user: %w", err)
+	}
+	defer cursor.Close(ctx)
 
 	var requests []models.LeaveRequestWithUser
+	if err = cursor.All(ctx, &requests); err != nil {
+		return nil, 0, fmt.Errorf("gagal mendecode pengajuan dengan detail user: %w", err)
+	}
+	if len(requests) == 0 {
+		return []models.LeaveRequestWithUser{}, total, nil
+	}
+	return requests, total, nil
+}
 
-	pipeline := mongo.Pipeline{
+// userDetailStages menggabungkan data user (nama, email, foto) ke setiap pengajuan.
+// Semua field pengajuan tetap dibawa, sehingga field baru tidak perlu didaftarkan satu per satu.
+func userDetailStages() mongo.Pipeline {
+	return mongo.Pipeline{
 		bson.D{{
 			Key: "$lookup",
 			Value: bson.D{
-				{Key: "from", Value: "users"},
+				{Key: "from", Value: config.UserCollection},
 				{Key: "localField", Value: "user_id"},
 				{Key: "foreignField", Value: "_id"},
 				{Key: "as", Value: "user_info"},
@@ -70,43 +113,16 @@ func (r *leaveRequestRepository) FindAll() ([]models.LeaveRequestWithUser, error
 			},
 		}},
 		bson.D{{
-			Key: "$project",
+			Key: "$addFields",
 			Value: bson.D{
-				{Key: "_id", Value: 1},
-				{Key: "user_id", Value: 1},
-				{Key: "start_date", Value: 1},
-				{Key: "end_date", Value: 1},
-				{Key: "reason", Value: 1},
-				{Key: "status", Value: 1},
-				{Key: "note", Value: 1},
-				{Key: "request_type", Value: 1},
-				{Key: "attachment_url", Value: 1},
-				{Key: "created_at", Value: 1},
-				{Key: "updated_at", Value: 1},
 				{Key: "user_name", Value: "$user_info.name"},
 				{Key: "user_email", Value: "$user_info.email"},
 				{Key: "user_photo", Value: "$user_info.photo"},
+				{Key: "user_department", Value: "$user_info.department"},
 			},
 		}},
+		bson.D{{Key: "$project", Value: bson.D{{Key: "user_info", Value: 0}}}},
 	}
-
-	cursor, err := r.collection.Aggregate(ctx, pipeline)
-	if err != nil {
-		return nil, fmt.Errorf("gagal melakukan agregasi untuk pengajuan dengan detail user: %w", err)
-	}
-	defer cursor.Close(ctx)
-
-	if err = cursor.All(ctx, &requests); err != nil {
-		return nil, fmt.Errorf("gagal mendecode pengajuan dengan detail user: %w", err)
-	}
-
-	if len(requests) > 0 {
-		log.Printf("DEBUG: Hasil FindAll LeaveRequests (item pertama): %+v\n", requests[0])
-	} else {
-		log.Println("DEBUG: FindAll LeaveRequests mengembalikan data kosong.")
-	}
-
-	return requests, nil
 }
 
 func (r *leaveRequestRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]models.LeaveRequest, error) {
@@ -145,7 +161,9 @@ func (r *leaveRequestRepository) FindByID(id primitive.ObjectID) (*models.LeaveR
 	return &request, nil
 }
 
-func (r *leaveRequestRepository) FindByUserAndDateAndType(ctx context.Context, userID primitive.ObjectID, date string, requestType string) (*models.LeaveRequest, error) {
+// FindByUserAndDateAndType mengambil semua pengajuan pending/approved yang mencakup tanggal tertentu.
+// excludeID (boleh NilObjectID) dikecualikan, misalnya pengajuan yang sedang diedit.
+func (r *leaveRequestRepository) FindByUserAndDateAndType(ctx context.Context, userID primitive.ObjectID, date string, requestType string, excludeID primitive.ObjectID) ([]models.LeaveRequest, error) {
 	filter := bson.M{
 		"user_id":      userID,
 		"request_type": requestType,
@@ -162,16 +180,21 @@ func (r *leaveRequestRepository) FindByUserAndDateAndType(ctx context.Context, u
 		},
 	}
 
-	var result models.LeaveRequest
-	err := r.collection.FindOne(ctx, filter).Decode(&result)
+	if !excludeID.IsZero() {
+		filter["_id"] = bson.M{"$ne": excludeID}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			// Tidak ditemukan dokumen adalah bukan error, kembalikan nil
-			return nil, nil
-		}
 		return nil, fmt.Errorf("gagal mencari pengajuan berdasarkan user, tanggal, dan jenis: %w", err)
 	}
-	return &result, nil
+	defer cursor.Close(ctx)
+
+	requests := []models.LeaveRequest{}
+	if err = cursor.All(ctx, &requests); err != nil {
+		return nil, fmt.Errorf("gagal decode pengajuan berdasarkan user, tanggal, dan jenis: %w", err)
+	}
+	return requests, nil
 }
 
 
@@ -258,9 +281,9 @@ func (r *leaveRequestRepository) CountByUserIDYearAndType(ctx context.Context, u
 	return count, nil
 }
 
-func (r *leaveRequestRepository) FindApprovedRequestByUserAndDate(ctx context.Context, userID primitive.ObjectID, date string) (*models.LeaveRequest, error) {
-	var request models.LeaveRequest
-
+// FindApprovedRequestsByUserAndDate mengambil semua pengajuan approved milik user yang mencakup
+// tanggal tersebut, misalnya cuti pagi (AM) dan cuti siang (PM) pada hari yang sama.
+func (r *leaveRequestRepository) FindApprovedRequestsByUserAndDate(ctx context.Context, userID primitive.ObjectID, date string) ([]models.LeaveRequest, error) {
 	filter := bson.M{
 		"user_id":    userID,
 		"status":     "approved",
@@ -268,13 +291,389 @@ func (r *leaveRequestRepository) FindApprovedRequestByUserAndDate(ctx context.Co
 		"end_date":   bson.M{"$gte": date},
 	}
 
-	err := r.collection.FindOne(ctx, filter).Decode(&request)
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil // Tidak ditemukan bukan error
-		}
 		return nil, fmt.Errorf("gagal mencari pengajuan yang disetujui: %w", err)
 	}
-	return &request, nil
+	defer cursor.Close(ctx)
+
+	requests := []models.LeaveRequest{}
+	if err = cursor.All(ctx, &requests); err != nil {
+		return nil, fmt.Errorf("gagal decode pengajuan yang disetujui: %w", err)
+	}
+	return requests, nil
+}
+
+// SumLeaveDaysByUserIDYearAndType menjumlahkan leave_days (pending/approved) untuk user dan tahun tertentu.
+// Pengajuan lama yang belum memiliki leave_days dihitung sebagai 1 hari.
+func (r *leaveRequestRepository) SumLeaveDaysByUserIDYearAndType(ctx context.Context, userID primitive.ObjectID, year int, requestType string) (float64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"user_id":      userID,
+			"request_type": requestType,
+			"status":       bson.M{"$in": []string{"pending", "approved"}},
+			"start_date": bson.M{
+				"$gte": fmt.Sprintf("%04d-01-01", year),
+				"$lte": fmt.Sprintf("%04d-12-31", year),
+			},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$leave_days", 1}}}}}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menjumlahkan hari pengajuan %s tahunan: %w", requestType, err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Total float64 `bson:"total"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return 0, fmt.Errorf("gagal decode jumlah hari pengajuan %s: %w", requestType, err)
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return results[0].Total, nil
+}
+
+// CancelPendingRequest membatalkan pengajuan yang masih pending. Filter status memastikan
+// pengajuan yang sudah diputuskan admin tidak ikut terbatalkan.
+func (r *leaveRequestRepository) CancelPendingRequest(ctx context.Context, id primitive.ObjectID, reason string) (*mongo.UpdateResult, error) {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":        "cancelled",
+			"cancel_reason": reason,
+			"cancelled_at":  now,
+			"updated_at":    now,
+		},
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": "pending"}, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membatalkan pengajuan: %w", err)
+	}
+	return result, nil
 }
 
+// RequestWithdrawal menandai pengajuan approved sebagai menunggu konfirmasi penarikan dari admin.
+func (r *leaveRequestRepository) RequestWithdrawal(ctx context.Context, id primitive.ObjectID, reason string) (*mongo.UpdateResult, error) {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"withdrawal_requested":    true,
+			"withdrawal_reason":       reason,
+			"withdrawal_requested_at": now,
+			"updated_at":              now,
+		},
+	}
+	filter := bson.M{"_id": id, "status": "approved", "withdrawal_requested": bson.M{"$ne": true}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengajukan penarikan: %w", err)
+	}
+	return result, nil
+}
+
+// ResolveWithdrawal menyelesaikan permintaan penarikan. Jika disetujui, status menjadi "withdrawn";
+// jika ditolak, pengajuan tetap approved dan tanda penarikan dihapus.
+func (r *leaveRequestRepository) ResolveWithdrawal(ctx context.Context, id primitive.ObjectID, approved bool, note string) (*mongo.UpdateResult, error) {
+	now := time.Now()
+	set := bson.M{
+		"withdrawal_requested": false,
+		"note":                 note,
+		"updated_at":           now,
+	}
+	if approved {
+		set["status"] = "withdrawn"
+		set["withdrawn_at"] = now
+	}
+	filter := bson.M{"_id": id, "status": "approved", "withdrawal_requested": true}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return nil, fmt.Errorf("gagal memproses penarikan pengajuan: %w", err)
+	}
+	return result, nil
+}
+
+// RecordApprovalDecision menyimpan daftar langkah persetujuan (yang sudah berisi keputusan pada stepIndex)
+// dan memajukan rantai. Filter current_step + status pending mencegah dua approver memutuskan
+// langkah yang sama bersamaan.
+func (r *leaveRequestRepository) RecordApprovalDecision(ctx context.Context, id primitive.ObjectID, stepIndex int, approvals []models.ApprovalStep, status string, note string) (*mongo.UpdateResult, error) {
+	set := bson.M{
+		"approvals":  approvals,
+		"status":     status,
+		"updated_at": time.Now(),
+	}
+	if status == "pending" {
+		set["current_step"] = stepIndex + 1
+	} else {
+		set["note"] = note
+	}
+
+	filter := bson.M{"_id": id, "status": "pending", "current_step": stepIndex}
+	if stepIndex == 0 {
+		// Data lama belum punya field current_step.
+		filter["current_step"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan keputusan persetujuan: %w", err)
+	}
+	return result, nil
+}
+
+// FindPendingForApprover mengambil pengajuan pending yang langkah aktifnya menunggu keputusan
+// dari salah satu user atau role yang diberikan (termasuk yang diwakili melalui delegasi).
+// includeAdminSteps diisi true untuk pemegang izin leave.approve: langkah pengganti admin dan
+// pengajuan lama tanpa rantai persetujuan ikut ditampilkan.
+func (r *leaveRequestRepository) FindPendingForApprover(ctx context.Context, approverIDs []primitive.ObjectID, roles []string, includeAdminSteps bool) ([]models.LeaveRequestWithUser, error) {
+	approverMatch := []bson.M{
+		{
+			"current_approval.approver_type": bson.M{"$in": bson.A{models.ApproverTypeUser, models.ApproverTypeManager, models.ApproverTypeDepartmentHead}},
+			"current_approval.approver_id":   bson.M{"$in": approverIDs},
+		},
+		{"current_approval.approver_type": models.ApproverTypeRole, "current_approval.approver_role": bson.M{"$in": roles}},
+	}
+	if includeAdminSteps {
+		approverMatch = append(approverMatch,
+			bson.M{"current_approval.approver_type": models.ApproverTypeRole, "current_approval.approver_role": models.RoleAdmin},
+			bson.M{"current_approval": bson.M{"$exists": false}},
+		)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": "pending"}}},
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "current_approval", Value: bson.D{{Key: "$arrayElemAt", Value: bson.A{
+				bson.D{{Key: "$ifNull", Value: bson.A{"$approvals", bson.A{}}}},
+				bson.D{{Key: "$ifNull", Value: bson.A{"$current_step", 0}}},
+			}}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"$or": approverMatch}}},
+		{{Key: "$project", Value: bson.D{{Key: "current_approval", Value: 0}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}}}},
+	}
+	pipeline = append(pipeline, userDetailStages()...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil pengajuan yang menunggu persetujuan: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var requests []models.LeaveRequestWithUser
+	if err = cursor.All(ctx, &requests); err != nil {
+		return nil, fmt.Errorf("gagal decode pengajuan yang menunggu persetujuan: %w", err)
+	}
+	if len(requests) == 0 {
+		return []models.LeaveRequestWithUser{}, nil
+	}
+	return requests, nil
+}
+
+// FindByUserIDs mengambil pengajuan milik sekumpulan user (misal: anggota tim manager),
+// opsional difilter berdasarkan status.
+func (r *leaveRequestRepository) FindByUserIDs(ctx context.Context, userIDs []primitive.ObjectID, status string) ([]models.LeaveRequestWithUser, error) {
+	match := bson.M{"user_id": bson.M{"$in": userIDs}}
+	if status != "" {
+		match["status"] = status
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+	}
+	pipeline = append(pipeline, userDetailStages()...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil pengajuan tim: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var requests []models.LeaveRequestWithUser
+	if err = cursor.All(ctx, &requests); err != nil {
+		return nil, fmt.Errorf("gagal decode pengajuan tim: %w", err)
+	}
+	if len(requests) == 0 {
+		return []models.LeaveRequestWithUser{}, nil
+	}
+	return requests, nil
+}
+
+// CountApprovedOnDateForUsers menghitung user yang cuti penuh pada tanggal tertentu
+// (lihat countApprovedOnDate).
+func (r *leaveRequestRepository) CountApprovedOnDateForUsers(ctx context.Context, userIDs []primitive.ObjectID, date string) (int64, error) {
+	return r.countApprovedOnDate(ctx, bson.M{"user_id": bson.M{"$in": userIDs}}, date)
+}
+
+// CountApprovedOnDate menghitung seluruh user yang cuti penuh pada tanggal tertentu
+// (lihat countApprovedOnDate).
+func (r *leaveRequestRepository) CountApprovedOnDate(ctx context.Context, date string) (int64, error) {
+	return r.countApprovedOnDate(ctx, bson.M{}, date)
+}
+
+// countApprovedOnDate menghitung user yang tidak masuk kerja karena pengajuan approved pada tanggal
+// tertentu: memiliki pengajuan penuh (termasuk data lama tanpa durasi), atau cuti pagi (AM) dan
+// cuti siang (PM) sekaligus. Cuti per jam tidak dihitung karena karyawan tetap masuk.
+func (r *leaveRequestRepository) countApprovedOnDate(ctx context.Context, filter bson.M, date string) (int64, error) {
+	filter["status"] = "approved"
+	filter["start_date"] = bson.M{"$lte": date}
+	filter["end_date"] = bson.M{"$gte": date}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$user_id"},
+			{Key: "full_day", Value: bson.D{{Key: "$max", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$in", Value: bson.A{"$duration", bson.A{models.LeaveDurationHalfDay, models.LeaveDurationHourly}}}}, 0, 1,
+			}}}}}},
+			{Key: "half_day_periods", Value: bson.D{{Key: "$addToSet", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{"$duration", models.LeaveDurationHalfDay}}}, "$half_day_period", "",
+			}}}}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"$or": []bson.M{
+			{"full_day": 1},
+			{"half_day_periods": bson.M{"$all": []string{models.HalfDayPeriodAM, models.HalfDayPeriodPM}}},
+		}}}},
+		{{Key: "$count", Value: "total"}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menghitung karyawan cuti: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Total int64 `bson:"total"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return 0, fmt.Errorf("gagal decode jumlah karyawan cuti: %w", err)
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return results[0].Total, nil
+}
+
+// FindInDateRangeForUsers mengambil pengajuan sekumpulan user dengan status tertentu
+// yang beririsan dengan rentang tanggal [startDate, endDate].
+func (r *leaveRequestRepository) FindInDateRangeForUsers(ctx context.Context, userIDs []primitive.ObjectID, startDate, endDate string, statuses []string) ([]models.LeaveRequestWithUser, error) {
+	match := bson.M{
+		"user_id":    bson.M{"$in": userIDs},
+		"status":     bson.M{"$in": statuses},
+		"start_date": bson.M{"$lte": endDate},
+		"end_date":   bson.M{"$gte": startDate},
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "start_date", Value: 1}}}},
+	}
+	pipeline = append(pipeline, userDetailStages()...)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil pengajuan pada rentang tanggal: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var requests []models.LeaveRequestWithUser
+	if err = cursor.All(ctx, &requests); err != nil {
+		return nil, fmt.Errorf("gagal decode pengajuan pada rentang tanggal: %w", err)
+	}
+	return requests, nil
+}
+
+// UpdatePendingRequest mengubah isi pengajuan yang masih pending dan menambahkan revisi ke riwayat.
+func (r *leaveRequestRepository) UpdatePendingRequest(ctx context.Context, id primitive.ObjectID, updateData bson.M, revision models.LeaveRequestRevision) (*mongo.UpdateResult, error) {
+	updateData["updated_at"] = time.Now()
+	filter := bson.M{
+		"_id":                  id,
+		"status":               "pending",
+		"withdrawal_requested": bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set":  updateData,
+		"$push": bson.M{"revisions": revision},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengubah pengajuan: %w", err)
+	}
+	return result, nil
+}
+
+// MarkResubmitted menautkan pengajuan yang ditolak ke pengajuan baru hasil pengajuan ulang.
+// Hanya berhasil sekali untuk setiap pengajuan yang ditolak.
+func (r *leaveRequestRepository) MarkResubmitted(ctx context.Context, id primitive.ObjectID, newRequestID primitive.ObjectID) (*mongo.UpdateResult, error) {
+	filter := bson.M{
+		"_id":            id,
+		"status":         "rejected",
+		"resubmitted_as": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"resubmitted_as": newRequestID, "updated_at": time.Now()}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menandai pengajuan sebagai diajukan ulang: %w", err)
+	}
+	return result, nil
+}
+
+// FindPendingCreatedBefore mengambil pengajuan pending (tanpa permintaan penarikan) yang dibuat
+// sebelum waktu tertentu. Dipakai cron job pengingat/eskalasi.
+func (r *leaveRequestRepository) FindPendingCreatedBefore(ctx context.Context, before time.Time) ([]models.LeaveRequest, error) {
+	filter := bson.M{
+		"status":               "pending",
+		"withdrawal_requested": bson.M{"$ne": true},
+		"created_at":           bson.M{"$lte": before},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil pengajuan pending: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var requests []models.LeaveRequest
+	if err = cursor.All(ctx, &requests); err != nil {
+		return nil, fmt.Errorf("gagal decode pengajuan pending: %w", err)
+	}
+	return requests, nil
+}
+
+// AppendAutoAction menambahkan satu entri ke log tindakan otomatis pengajuan.
+func (r *leaveRequestRepository) AppendAutoAction(ctx context.Context, id primitive.ObjectID, action models.LeaveAutoAction) (*mongo.UpdateResult, error) {
+	update := bson.M{"$push": bson.M{"auto_actions": action}}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mencatat tindakan otomatis: %w", err)
+	}
+	return result, nil
+}
+
+// EscalateApprovalStep mengganti approver langkah aktif dan mencatat tindakan eskalasinya.
+// Filter current_step + status pending mencegah eskalasi langkah yang sudah diputuskan.
+func (r *leaveRequestRepository) EscalateApprovalStep(ctx context.Context, id primitive.ObjectID, stepIndex int, step models.ApprovalStep, action models.LeaveAutoAction) (*mongo.UpdateResult, error) {
+	filter := bson.M{"_id": id, "status": "pending", "current_step": stepIndex}
+	update := bson.M{
+		"$set":  bson.M{fmt.Sprintf("approvals.%d", stepIndex): step, "updated_at": time.Now()},
+		"$push": bson.M{"auto_actions": action},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengeskalasi langkah persetujuan: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+type OIDCStateRepository interface {
+	Create(ctx context.Context, state *models.OIDCLoginState) (*mongo.InsertOneResult, error)
+	Consume(ctx context.Context, stateHash string) (*models.OIDCLoginState, error)
+}
+
+type oidcStateRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOIDCStateRepository() OIDCStateRepository {
+	return &oidcStateRepository{
+		collection: config.GetCollection(config.OIDCStateCollection),
+	}
+}
+
+func (r *oidcStateRepository) Create(ctx context.Context, state *models.OIDCLoginState) (*mongo.InsertOneResult, error) {
+	state.ID = primitive.NewObjectID()
+	state.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, state)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan state login SSO: %w", err)
+	}
+	return result, nil
+}
+
+// Consume menghapus state secara atomik dan mengembalikannya sehingga satu state hanya bisa dipakai
+// sekali. Mengembalikan nil jika state tidak ada atau kedaluwarsa.
+func (r *oidcStateRepository) Consume(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	filter := bson.M{
+		"state_hash": stateHash,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	var state models.OIDCLoginState
+	err := r.collection.FindOneAndDelete(ctx, filter).Decode(&state)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal memeriksa state login SSO: %w", err)
+	}
+	return &state, nil
+}
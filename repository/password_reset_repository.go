@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) (*mongo.InsertOneResult, error)
+	CountRecentForUser(ctx context.Context, userID primitive.ObjectID, since time.Time) (int64, error)
+	InvalidateForUser(ctx context.Context, userID primitive.ObjectID) (*mongo.UpdateResult, error)
+	FindActive(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error)
+	Consume(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error)
+}
+
+type passwordResetRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPasswordResetRepository() PasswordResetRepository {
+	return &passwordResetRepository{
+		collection: config.GetCollection(config.PasswordResetCollection),
+	}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, token *models.PasswordResetToken) (*mongo.InsertOneResult, error) {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan token reset password: %w", err)
+	}
+	return result, nil
+}
+
+// CountRecentForUser menghitung permintaan reset user sejak waktu tertentu (untuk pembatasan).
+func (r *passwordResetRepository) CountRecentForUser(ctx context.Context, userID primitive.ObjectID, since time.Time) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "created_at": bson.M{"$gte": since}})
+	if err != nil {
+		return 0, fmt.Errorf("gagal menghitung permintaan reset password: %w", err)
+	}
+	return count, nil
+}
+
+// InvalidateForUser menandai semua token reset user yang belum dipakai sebagai terpakai,
+// sehingga hanya token terbaru yang berlaku.
+func (r *passwordResetRepository) InvalidateForUser(ctx context.Context, userID primitive.ObjectID) (*mongo.UpdateResult, error) {
+	filter := bson.M{"user_id": userID, "used_at": bson.M{"$exists": false}}
+
+	result, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"used_at": time.Now()}})
+	if err != nil {
+		return nil, fmt.Errorf("gagal membatalkan token reset password: %w", err)
+	}
+	return result, nil
+}
+
+// FindActive mencari token yang belum dipakai dan belum kedaluwarsa tanpa memakainya, sehingga
+// password baru bisa divalidasi lebih dulu. Mengembalikan nil jika token tidak berlaku.
+func (r *passwordResetRepository) FindActive(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	var token models.PasswordResetToken
+	err := r.collection.FindOne(ctx, filter).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal mencari token reset password: %w", err)
+	}
+	return &token, nil
+}
+
+// Consume menandai token sebagai terpakai secara atomik dan mengembalikannya. Mengembalikan nil
+// jika token tidak ada, sudah dipakai, atau kedaluwarsa.
+func (r *passwordResetRepository) Consume(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	now := time.Now()
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+
+	var token models.PasswordResetToken
+	err := r.collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"used_at": now}}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal memakai token reset password: %w", err)
+	}
+	return &token, nil
+}
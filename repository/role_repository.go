@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+// roleCacheTTL adalah lama role disimpan di memori. Perubahan izin dari instance lain berlaku
+// paling lambat setelah waktu ini; perubahan dari instance yang sama langsung berlaku.
+const roleCacheTTL = 30 * time.Second
+
+type RoleRepository interface {
+	EnsureDefaultRoles(ctx context.Context) error
+	Create(ctx context.Context, role *models.Role) (*mongo.InsertOneResult, error)
+	FindAll(ctx context.Context) ([]models.Role, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Role, error)
+	FindByName(ctx context.Context, name string) (*models.Role, error)
+	Update(ctx context.Context, id primitive.ObjectID, update bson.M) (*mongo.UpdateResult, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (*mongo.DeleteResult, error)
+	HasPermission(ctx context.Context, roleName, permission string) (bool, error)
+}
+
+type cachedRole struct {
+	role     *models.Role
+	cachedAt time.Time
+}
+
+type roleRepository struct {
+	collection *mongo.Collection
+
+	mu    sync.RWMutex
+	cache map[string]cachedRole
+}
+
+func NewRoleRepository() RoleRepository {
+	return &roleRepository{
+		collection: config.GetCollection(config.RoleCollection),
+		cache:      make(map[string]cachedRole),
+	}
+}
+
+// EnsureDefaultRoles membuat role bawaan yang belum ada tanpa mengubah role yang sudah ada.
+func (r *roleRepository) EnsureDefaultRoles(ctx context.Context) error {
+	now := time.Now()
+	for _, role := range models.DefaultRoles {
+		update := bson.M{"$setOnInsert": bson.M{
+			"name":        role.Name,
+			"description": role.Description,
+			"permissions": role.Permissions,
+			"is_system":   role.IsSystem,
+			"created_at":  now,
+			"updated_at":  now,
+		}}
+		_, err := r.collection.UpdateOne(ctx, bson.M{"name": role.Name}, update, options.Update().SetUpsert(true))
+		if err != nil {
+			return fmt.Errorf("gagal membuat role bawaan %s: %w", role.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *models.Role) (*mongo.InsertOneResult, error) {
+	role.ID = primitive.NewObjectID()
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = role.CreatedAt
+
+	result, err := r.collection.InsertOne(ctx, role)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat role: %w", err)
+	}
+	return result, nil
+}
+
+func (r *roleRepository) FindAll(ctx context.Context) ([]models.Role, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "is_system", Value: -1}, {Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil role: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	roles := []models.Role{}
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, fmt.Errorf("gagal decode role: %w", err)
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Role, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	return r.findOne(ctx, bson.M{"name": name})
+}
+
+func (r *roleRepository) findOne(ctx context.Context, filter bson.M) (*models.Role, error) {
+	var role models.Role
+	err := r.collection.FindOne(ctx, filter).Decode(&role)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal mencari role: %w", err)
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) (*mongo.UpdateResult, error) {
+	update["updated_at"] = time.Now()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengupdate role: %w", err)
+	}
+	r.clearCache()
+	return result, nil
+}
+
+func (r *roleRepository) Delete(ctx context.Context, id primitive.ObjectID) (*mongo.DeleteResult, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "is_system": bson.M{"$ne": true}})
+	if err != nil {
+		return nil, fmt.Errorf("gagal menghapus role: %w", err)
+	}
+	r.clearCache()
+	return result, nil
+}
+
+// HasPermission memeriksa izin role berdasarkan nama. Role yang tidak ditemukan tidak memiliki izin apa pun.
+func (r *roleRepository) HasPermission(ctx context.Context, roleName, permission string) (bool, error) {
+	role, err := r.cachedRole(ctx, roleName)
+	if err != nil {
+		return false, err
+	}
+	if role == nil {
+		return false, nil
+	}
+	return role.HasPermission(permission), nil
+}
+
+func (r *roleRepository) cachedRole(ctx context.Context, name string) (*models.Role, error) {
+	r.mu.RLock()
+	entry, ok := r.cache[name]
+	r.mu.RUnlock()
+	if ok && time.Since(entry.cachedAt) < roleCacheTTL {
+		return entry.role, nil
+	}
+
+	role, err := r.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cache[name] = cachedRole{role: role, cachedAt: time.Now()}
+	r.mu.Unlock()
+	return role, nil
+}
+
+func (r *roleRepository) clearCache() {
+	r.mu.Lock()
+	r.cache = make(map[string]cachedRole)
+	r.mu.Unlock()
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+type SecurityEventRepository interface {
+	Create(ctx context.Context, event *models.SecurityEvent) (*mongo.InsertOneResult, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID, eventType string, page, limit int64) ([]models.SecurityEvent, int64, error)
+	CountFailedLoginsByIP(ctx context.Context, ip string, since time.Time) (int64, error)
+}
+
+type securityEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSecurityEventRepository() SecurityEventRepository {
+	return &securityEventRepository{
+		collection: config.GetCollection(config.SecurityEventCollection),
+	}
+}
+
+func (r *securityEventRepository) Create(ctx context.Context, event *models.SecurityEvent) (*mongo.InsertOneResult, error) {
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan riwayat keamanan: %w", err)
+	}
+	return result, nil
+}
+
+// FindByUser mengambil riwayat keamanan user (terbaru lebih dulu), opsional difilter berdasarkan jenis.
+func (r *securityEventRepository) FindByUser(ctx context.Context, userID primitive.ObjectID, eventType string, page, limit int64) ([]models.SecurityEvent, int64, error) {
+	filter := bson.M{"user_id": userID}
+	if eventType != "" {
+		filter["type"] = eventType
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gagal menghitung riwayat keamanan: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gagal mengambil riwayat keamanan: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.SecurityEvent{}
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, 0, fmt.Errorf("gagal decode riwayat keamanan: %w", err)
+	}
+	return events, total, nil
+}
+
+// CountFailedLoginsByIP menghitung login gagal dari satu alamat IP sejak waktu tertentu.
+func (r *securityEventRepository) CountFailedLoginsByIP(ctx context.Context, ip string, since time.Time) (int64, error) {
+	filter := bson.M{
+		"type":       models.SecurityEventLoginFailed,
+		"ip_address": ip,
+		"created_at": bson.M{"$gte": since},
+	}
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menghitung login gagal per IP: %w", err)
+	}
+	return count, nil
+}
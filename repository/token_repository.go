@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/paseto"
+)
+
+// sessionLastSeenInterval membatasi penulisan last_seen_at agar tidak terjadi update setiap request.
+const sessionLastSeenInterval = time.Minute
+
+type TokenRepository interface {
+	CreateSession(ctx context.Context, session *models.Session) (*mongo.InsertOneResult, error)
+	FindSession(ctx context.Context, sessionID string) (*models.Session, error)
+	FindSessionByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error)
+	FindActiveSessionsForUser(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error)
+	TouchSession(ctx context.Context, session *models.Session, ip string) error
+	ExtendSession(ctx context.Context, sessionID, ip string, expiresAt time.Time) error
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) (*mongo.InsertOneResult, error)
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, id primitive.ObjectID, replacedBy primitive.ObjectID) (*mongo.UpdateResult, error)
+	RevokeSession(ctx context.Context, sessionID string) (*mongo.UpdateResult, error)
+	RevokeAllSessionsForUser(ctx context.Context, userID primitive.ObjectID) (*mongo.UpdateResult, error)
+	RevokeAccessToken(ctx context.Context, claims *models.Claims) error
+	RevokeAccessTokensForUser(ctx context.Context, userID primitive.ObjectID, before time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, claims *models.Claims) (bool, error)
+}
+
+type tokenRepository struct {
+	sessionCollection *mongo.Collection
+	refreshCollection *mongo.Collection
+	revokedCollection *mongo.Collection
+}
+
+func NewTokenRepository() TokenRepository {
+	return &tokenRepository{
+		sessionCollection: config.GetCollection(config.SessionCollection),
+		refreshCollection: config.GetCollection(config.RefreshTokenCollection),
+		revokedCollection: config.GetCollection(config.RevokedTokenCollection),
+	}
+}
+
+func (r *tokenRepository) CreateSession(ctx context.Context, session *models.Session) (*mongo.InsertOneResult, error) {
+	now := time.Now()
+	session.ID = primitive.NewObjectID()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	if session.LastSeenIP == "" {
+		session.LastSeenIP = session.IPAddress
+	}
+
+	result, err := r.sessionCollection.InsertOne(ctx, session)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan sesi: %w", err)
+	}
+	return result, nil
+}
+
+func (r *tokenRepository) findSession(ctx context.Context, filter bson.M) (*models.Session, error) {
+	var session models.Session
+	err := r.sessionCollection.FindOne(ctx, filter).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal menemukan sesi: %w", err)
+	}
+	return &session, nil
+}
+
+// FindSession mencari sesi berdasarkan session ID yang dibawa token.
+func (r *tokenRepository) FindSession(ctx context.Context, sessionID string) (*models.Session, error) {
+	return r.findSession(ctx, bson.M{"session_id": sessionID})
+}
+
+// FindSessionByID mencari sesi berdasarkan ID dokumen yang ditampilkan ke user.
+func (r *tokenRepository) FindSessionByID(ctx context.Context, id primitive.ObjectID) (*models.Session, error) {
+	return r.findSession(ctx, bson.M{"_id": id})
+}
+
+// FindActiveSessionsForUser mengambil sesi user yang belum diakhiri dan belum kedaluwarsa,
+// terbaru dipakai lebih dulu.
+func (r *tokenRepository) FindActiveSessionsForUser(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	cursor, err := r.sessionCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil sesi user: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("gagal decode sesi user: %w", err)
+	}
+	return sessions, nil
+}
+
+// TouchSession mencatat waktu dan IP pemakaian terakhir sesi, paling sering sekali per
+// sessionLastSeenInterval.
+func (r *tokenRepository) TouchSession(ctx context.Context, session *models.Session, ip string) error {
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) < sessionLastSeenInterval && session.LastSeenIP == ip {
+		return nil
+	}
+
+	update := bson.M{"$set": bson.M{"last_seen_at": now, "last_seen_ip": ip}}
+	if _, err := r.sessionCollection.UpdateOne(ctx, bson.M{"_id": session.ID}, update); err != nil {
+		return fmt.Errorf("gagal memperbarui sesi: %w", err)
+	}
+	return nil
+}
+
+// ExtendSession memperpanjang masa berlaku sesi mengikuti refresh token baru.
+func (r *tokenRepository) ExtendSession(ctx context.Context, sessionID, ip string, expiresAt time.Time) error {
+	filter := bson.M{"session_id": sessionID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"expires_at": expiresAt, "last_seen_at": time.Now(), "last_seen_ip": ip}}
+	if _, err := r.sessionCollection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("gagal memperpanjang sesi: %w", err)
+	}
+	return nil
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) (*mongo.InsertOneResult, error) {
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	token.CreatedAt = time.Now()
+
+	result, err := r.refreshCollection.InsertOne(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menyimpan refresh token: %w", err)
+	}
+	return result, nil
+}
+
+func (r *tokenRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.refreshCollection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal menemukan refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken menandai refresh token sebagai sudah dipakai dan diganti token baru.
+// Hanya berhasil sekali: MatchedCount 0 berarti token sudah pernah dipakai atau dicabut.
+func (r *tokenRepository) RotateRefreshToken(ctx context.Context, id primitive.ObjectID, replacedBy primitive.ObjectID) (*mongo.UpdateResult, error) {
+	filter := bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now(), "replaced_by": replacedBy}}
+
+	result, err := r.refreshCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal merotasi refresh token: %w", err)
+	}
+	return result, nil
+}
+
+// RevokeSession mengakhiri sesi dan mencabut semua refresh token yang masih aktif pada sesi itu.
+// Access token sesi ini ikut ditolak AuthMiddleware karena sesinya tidak lagi aktif.
+func (r *tokenRepository) RevokeSession(ctx context.Context, sessionID string) (*mongo.UpdateResult, error) {
+	filter := bson.M{"session_id": sessionID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	if _, err := r.sessionCollection.UpdateOne(ctx, filter, update); err != nil {
+		return nil, fmt.Errorf("gagal mengakhiri sesi: %w", err)
+	}
+	result, err := r.refreshCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mencabut sesi: %w", err)
+	}
+	return result, nil
+}
+
+// RevokeAllSessionsForUser mengakhiri semua sesi user dan mencabut semua refresh token yang masih aktif.
+func (r *tokenRepository) RevokeAllSessionsForUser(ctx context.Context, userID primitive.ObjectID) (*mongo.UpdateResult, error) {
+	filter := bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	if _, err := r.sessionCollection.UpdateMany(ctx, filter, update); err != nil {
+		return nil, fmt.Errorf("gagal mengakhiri semua sesi user: %w", err)
+	}
+	result, err := r.refreshCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("gagal mencabut semua sesi user: %w", err)
+	}
+	return result, nil
+}
+
+// RevokeAccessToken memasukkan satu access token ke daftar pencabutan sampai token itu kedaluwarsa.
+func (r *tokenRepository) RevokeAccessToken(ctx context.Context, claims *models.Claims) error {
+	entry := models.RevokedToken{
+		ID:        primitive.NewObjectID(),
+		TokenID:   claims.TokenID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	if _, err := r.revokedCollection.InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("gagal mencabut access token: %w", err)
+	}
+	return nil
+}
+
+// RevokeAccessTokensForUser menolak semua access token user yang terbit sebelum waktu before.
+// Waktu dibulatkan ke detik karena waktu terbit di dalam token juga berpresisi detik.
+func (r *tokenRepository) RevokeAccessTokensForUser(ctx context.Context, userID primitive.ObjectID, before time.Time) error {
+	revokedBefore := before.Truncate(time.Second)
+	entry := models.RevokedToken{
+		ID:            primitive.NewObjectID(),
+		UserID:        userID,
+		RevokedBefore: &revokedBefore,
+		ExpiresAt:     before.Add(paseto.AccessTokenDuration),
+		CreatedAt:     time.Now(),
+	}
+	if _, err := r.revokedCollection.InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("gagal mencabut access token user: %w", err)
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked memeriksa apakah access token dicabut secara individual (logout)
+// atau secara massal untuk user-nya (logout semua sesi, user dihapus).
+func (r *tokenRepository) IsAccessTokenRevoked(ctx context.Context, claims *models.Claims) (bool, error) {
+	or := bson.A{
+		bson.M{"user_id": claims.UserID, "revoked_before": bson.M{"$gt": claims.IssuedAt}},
+	}
+	if claims.TokenID != "" {
+		or = append(or, bson.M{"token_id": claims.TokenID})
+	}
+
+	count, err := r.revokedCollection.CountDocuments(ctx, bson.M{"$or": or})
+	if err != nil {
+		return false, fmt.Errorf("gagal memeriksa daftar pencabutan token: %w", err)
+	}
+	return count > 0, nil
+}
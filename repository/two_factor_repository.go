@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"Sistem-Manajemen-Karyawan/config"
+	"Sistem-Manajemen-Karyawan/models"
+)
+
+type TwoFactorRepository interface {
+	CreateChallenge(ctx context.Context, challenge *models.TwoFactorChallenge) (*mongo.InsertOneResult, error)
+	RegisterChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int) (*models.TwoFactorChallenge, error)
+	ConsumeChallenge(ctx context.Context, id primitive.ObjectID) (*mongo.UpdateResult, error)
+}
+
+type twoFactorRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTwoFactorRepository() TwoFactorRepository {
+	return &twoFactorRepository{
+		collection: config.GetCollection(config.TwoFactorChallengeCollection),
+	}
+}
+
+func (r *twoFactorRepository) CreateChallenge(ctx context.Context, challenge *models.TwoFactorChallenge) (*mongo.InsertOneResult, error) {
+	challenge.ID = primitive.NewObjectID()
+	challenge.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, challenge)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuat tantangan 2FA: %w", err)
+	}
+	return result, nil
+}
+
+// RegisterChallengeAttempt menambah hitungan percobaan pada tantangan yang masih berlaku dan
+// mengembalikannya. Mengembalikan nil jika tantangan tidak ada, sudah dipakai, kedaluwarsa,
+// atau sudah mencapai maxAttempts.
+func (r *twoFactorRepository) RegisterChallengeAttempt(ctx context.Context, tokenHash string, maxAttempts int) (*models.TwoFactorChallenge, error) {
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+		"attempts":   bson.M{"$lt": maxAttempts},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var challenge models.TwoFactorChallenge
+	err := r.collection.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"attempts": 1}}, opts).Decode(&challenge)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("gagal memeriksa tantangan 2FA: %w", err)
+	}
+	return &challenge, nil
+}
+
+// ConsumeChallenge menandai tantangan sebagai terpakai. Hanya berhasil sekali.
+func (r *twoFactorRepository) ConsumeChallenge(ctx context.Context, id primitive.ObjectID) (*mongo.UpdateResult, error) {
+	filter := bson.M{"_id": id, "used_at": bson.M{"$exists": false}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"used_at": time.Now()}})
+	if err != nil {
+		return nil, fmt.Errorf("gagal memakai tantangan 2FA: %w", err)
+	}
+	return result, nil
+}
@@ -68,10 +68,18 @@ func (r *UserRepository) FindUserByID(ctx context.Context, id primitive.ObjectID
 	return &user, nil
 }
 
-func (r *UserRepository) UpdateUser(ctx context.Context, id primitive.ObjectID, updateData bson.M) (*mongo.UpdateResult, error) {
+// UpdateUser mengisi field pada updateData dan menghapus field pada unsetFields (mis. manager_id).
+func (r *UserRepository) UpdateUser(ctx context.Context, id primitive.ObjectID, updateData bson.M, unsetFields []string) (*mongo.UpdateResult, error) {
 	updateData["updated_at"] = time.Now()
 	filter := bson.M{"_id": id}
 	update := bson.M{"$set": updateData}
+	if len(unsetFields) > 0 {
+		unset := bson.M{}
+		for _, field := range unsetFields {
+			unset[field] = ""
+		}
+		update["$unset"] = unset
+	}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -115,11 +123,17 @@ func (r *UserRepository) GetAllUsers(ctx context.Context, filter bson.M, page, l
 	return users, total, nil
 }
 
-func (r *UserRepository) UpdateUserPassword(ctx context.Context, id primitive.ObjectID, hashedPassword string) error {
+// UpdateUserPassword menyimpan password baru beserta riwayat hash password sebelumnya, mencatat
+// waktu penggantian, dan menghapus kewajiban ganti password awal.
+func (r *UserRepository) UpdateUserPassword(ctx context.Context, id primitive.ObjectID, hashedPassword string, history []string) error {
+	now := time.Now()
 	update := bson.M{
 		"$set": bson.M{
-			"password":   hashedPassword,
-			"updated_at": time.Now(),
+			"password":            hashedPassword,
+			"password_history":    history,
+			"password_changed_at": now,
+			"isFirstLogin":        false,
+			"updated_at":          now,
 		},
 	}
 	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
@@ -132,8 +146,8 @@ func (r *UserRepository) UpdateUserPassword(ctx context.Context, id primitive.Ob
 func (r *UserRepository) UpdateUserFirstLoginStatus(ctx context.Context, id primitive.ObjectID, status bool) error {
 	update := bson.M{
 		"$set": bson.M{
-			"is_first_login": status,
-			"updated_at":     time.Now(),
+			"isFirstLogin": status,
+			"updated_at":   time.Now(),
 		},
 	}
 	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
@@ -144,24 +158,27 @@ func (r *UserRepository) UpdateUserFirstLoginStatus(ctx context.Context, id prim
 }
 
 func (r *UserRepository) CountDocuments(ctx context.Context, filter bson.M) (int64, error) {
-    count, err := r.collection.CountDocuments(ctx, filter)
-    if err != nil {
-        return 0, fmt.Errorf("gagal menghitung dokumen dari koleksi user: %w", err)
-    }
-    return count, nil
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("gagal menghitung dokumen dari koleksi user: %w", err)
+	}
+	return count, nil
 }
 
 func (r *UserRepository) Aggregate(ctx context.Context, pipeline mongo.Pipeline) (*mongo.Cursor, error) {
-    cursor, err := r.collection.Aggregate(ctx, pipeline)
-    if err != nil {
-        return nil, fmt.Errorf("gagal menjalankan agregasi pada koleksi user: %w", err)
-    }
-    return cursor, nil
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menjalankan agregasi pada koleksi user: %w", err)
+	}
+	return cursor, nil
 }
 
 func (r *UserRepository) FindAllActiveUsers(ctx context.Context) ([]models.User, error) {
-	// Filter untuk mengambil semua user yang rolenya BUKAN 'admin'.
-	filter := bson.M{"role": bson.M{"$ne": "admin"}}
+	// Filter untuk mengambil semua user yang rolenya BUKAN 'admin' dan belum keluar.
+	filter := bson.M{
+		"role":              bson.M{"$ne": "admin"},
+		"employment_status": bson.M{"$ne": models.EmploymentStatusInactive},
+	}
 
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
@@ -188,4 +205,199 @@ func (r *UserRepository) IsEmailTaken(ctx context.Context, email string, exclude
 		return false, fmt.Errorf("gagal menghitung dokumen: %w", err)
 	}
 	return count > 0, nil
-}
\ No newline at end of file
+}
+
+// FindTeamMembers mengembalikan bawahan langsung (manager_id) dan anggota departemen yang dikepalai,
+// tanpa menyertakan manager itu sendiri.
+func (r *UserRepository) FindTeamMembers(ctx context.Context, managerID primitive.ObjectID, departments []string) ([]models.User, error) {
+	or := bson.A{bson.M{"manager_id": managerID}}
+	if len(departments) > 0 {
+		or = append(or, bson.M{"department": bson.M{"$in": departments}})
+	}
+	filter := bson.M{
+		"_id": bson.M{"$ne": managerID},
+		"$or": or,
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("gagal menemukan anggota tim: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("gagal decode data anggota tim: %w", err)
+	}
+	return users, nil
+}
+
+// FindUsersByDepartment mengembalikan karyawan (non-admin) pada departemen tertentu.
+func (r *UserRepository) FindUsersByDepartment(ctx context.Context, department string) ([]models.User, error) {
+	filter := bson.M{"department": department, "role": bson.M{"$ne": "admin"}}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("gagal menemukan user departemen: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("gagal decode data user departemen: %w", err)
+	}
+	return users, nil
+}
+
+// FindUserIDsByDepartment mengembalikan ID semua user (semua role) pada departemen tertentu.
+func (r *UserRepository) FindUserIDsByDepartment(ctx context.Context, department string) ([]primitive.ObjectID, error) {
+	values, err := r.collection.Distinct(ctx, "_id", bson.M{"department": department})
+	if err != nil {
+		return nil, fmt.Errorf("gagal mengambil user departemen: %w", err)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// FindUsersByRoles mengembalikan semua user yang memiliki salah satu role tersebut.
+func (r *UserRepository) FindUsersByRoles(ctx context.Context, roles []string) ([]models.User, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"role": bson.M{"$in": roles}})
+	if err != nil {
+		return nil, fmt.Errorf("gagal menemukan user dengan role %v: %w", roles, err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("gagal decode data user dengan role %v: %w", roles, err)
+	}
+	return users, nil
+}
+
+// RegisterFailedLogin menambah hitungan login gagal berturut-turut dan mengembalikan nilai barunya.
+func (r *UserRepository) RegisterFailedLogin(ctx context.Context, id primitive.ObjectID) (int, error) {
+	update := bson.M{
+		"$inc": bson.M{"failed_login_count": 1},
+		"$set": bson.M{"last_failed_login_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var user models.User
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&user); err != nil {
+		return 0, fmt.Errorf("gagal mencatat login gagal: %w", err)
+	}
+	return user.FailedLoginCount, nil
+}
+
+// LockUser mengunci login user sampai waktu tertentu.
+func (r *UserRepository) LockUser(ctx context.Context, id primitive.ObjectID, until time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"locked_until": until}})
+	if err != nil {
+		return fmt.Errorf("gagal mengunci akun: %w", err)
+	}
+	return nil
+}
+
+// ResetLoginFailures menghapus hitungan login gagal dan penguncian user.
+func (r *UserRepository) ResetLoginFailures(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{
+		"$set":   bson.M{"failed_login_count": 0},
+		"$unset": bson.M{"last_failed_login_at": "", "locked_until": ""},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return fmt.Errorf("gagal mereset status login gagal: %w", err)
+	}
+	return nil
+}
+
+// SetPendingTwoFactorSecret menyimpan secret TOTP yang sedang didaftarkan (belum aktif).
+func (r *UserRepository) SetPendingTwoFactorSecret(ctx context.Context, id primitive.ObjectID, secret string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"two_factor_pending_secret": secret}})
+	if err != nil {
+		return fmt.Errorf("gagal menyimpan secret 2FA: %w", err)
+	}
+	return nil
+}
+
+// EnableTwoFactor mengaktifkan 2FA dengan secret yang sudah dikonfirmasi dan kode pemulihan baru.
+func (r *UserRepository) EnableTwoFactor(ctx context.Context, id primitive.ObjectID, secret string, step int64, recoveryCodeHashes []string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"two_factor_enabled":    true,
+			"two_factor_enabled_at": time.Now(),
+			"two_factor_secret":     secret,
+			"two_factor_last_step":  step,
+			"recovery_code_hashes":  recoveryCodeHashes,
+			"updated_at":            time.Now(),
+		},
+		"$unset": bson.M{"two_factor_pending_secret": ""},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return fmt.Errorf("gagal mengaktifkan 2FA: %w", err)
+	}
+	return nil
+}
+
+// DisableTwoFactor menonaktifkan 2FA dan menghapus secret serta kode pemulihan.
+func (r *UserRepository) DisableTwoFactor(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{
+		"$set": bson.M{"two_factor_enabled": false, "updated_at": time.Now()},
+		"$unset": bson.M{
+			"two_factor_enabled_at":     "",
+			"two_factor_secret":         "",
+			"two_factor_pending_secret": "",
+			"two_factor_last_step":      "",
+			"recovery_code_hashes":      "",
+		},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return fmt.Errorf("gagal menonaktifkan 2FA: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes mengganti seluruh kode pemulihan 2FA user.
+func (r *UserRepository) ReplaceRecoveryCodes(ctx context.Context, id primitive.ObjectID, recoveryCodeHashes []string) error {
+	update := bson.M{"$set": bson.M{"recovery_code_hashes": recoveryCodeHashes, "updated_at": time.Now()}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "two_factor_enabled": true}, update)
+	if err != nil {
+		return fmt.Errorf("gagal mengganti kode pemulihan: %w", err)
+	}
+	return nil
+}
+
+// UseTwoFactorStep mencatat periode TOTP yang dipakai. Mengembalikan false jika periode yang sama
+// atau yang lebih baru sudah pernah dipakai (kode diputar ulang).
+func (r *UserRepository) UseTwoFactorStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"two_factor_last_step": bson.M{"$exists": false}},
+			bson.M{"two_factor_last_step": bson.M{"$lt": step}},
+		},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"two_factor_last_step": step}})
+	if err != nil {
+		return false, fmt.Errorf("gagal mencatat kode 2FA: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// UseRecoveryCode menghapus satu kode pemulihan secara atomik. Mengembalikan false jika kode tidak ada.
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	filter := bson.M{"_id": id, "recovery_code_hashes": codeHash}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"recovery_code_hashes": codeHash}})
+	if err != nil {
+		return false, fmt.Errorf("gagal memakai kode pemulihan: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
@@ -8,6 +8,11 @@ import (
 
 	"Sistem-Manajemen-Karyawan/config/middleware"
 	"Sistem-Manajemen-Karyawan/handlers"
+	"Sistem-Manajemen-Karyawan/models"
+	"Sistem-Manajemen-Karyawan/pkg/notifier"
+	"Sistem-Manajemen-Karyawan/pkg/oidc"
+	"Sistem-Manajemen-Karyawan/pkg/paseto"
+	"Sistem-Manajemen-Karyawan/pkg/password"
 	"Sistem-Manajemen-Karyawan/repository"
 
 	_ "Sistem-Manajemen-Karyawan/docs"
@@ -15,22 +20,56 @@ import (
 
 func SetupRoutes(
 	app *fiber.App,
-	userRepo *repository.UserRepository,                 // Ini adalah pointer ke struct, jadi (*) sudah benar
-	deptRepo repository.DepartmentRepository,             // Ini adalah interface, JANGAN pakai (*)
-	attendanceRepo repository.AttendanceRepository,         // Ini adalah interface, JANGAN pakai (*)
-	leaveRepo repository.LeaveRequestRepository,            // Ini adalah interface, JANGAN pakai (*)
+	userRepo *repository.UserRepository, // Ini adalah pointer ke struct, jadi (*) sudah benar
+	deptRepo repository.DepartmentRepository, // Ini adalah interface, JANGAN pakai (*)
+	attendanceRepo repository.AttendanceRepository, // Ini adalah interface, JANGAN pakai (*)
+	leaveRepo repository.LeaveRequestRepository, // Ini adalah interface, JANGAN pakai (*)
 	workScheduleRepo *repository.WorkScheduleRepository, // Ini adalah pointer ke struct, jadi (*) sudah benar
+	chainRepo repository.ApprovalChainRepository,
+	delegationRepo repository.ApprovalDelegationRepository,
+	tokenRepo repository.TokenRepository,
+	pasetoMaker *paseto.PasetoMaker,
+	passwordResetRepo repository.PasswordResetRepository,
+	securityRepo repository.SecurityEventRepository,
+	twoFactorRepo repository.TwoFactorRepository,
+	roleRepo repository.RoleRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	oidcStateRepo repository.OIDCStateRepository,
+	auditLogRepo repository.AuditLogRepository,
+	activityRepo repository.ActivityRepository,
+	leaveHandler *handlers.LeaveRequestHandler, // dipakai bersama cron job eskalasi pengajuan
+	notif notifier.Notifier,
+	passwordResetURL string,
+	twoFactorIssuer string,
+	twoFactorRequiredRoles []string,
+	oidcProvider *oidc.Provider,
+	passwordLoginDisabledDomains []string,
+	passwordPolicy *password.Policy,
 ) {
 	log.Println("Memulai pendaftaran rute aplikasi...")
 
 	// Inisialisasi Handlers (tidak ada perubahan di sini)
-	authHandler := handlers.NewAuthHandler(userRepo)
-	userHandler := handlers.NewUserHandler(userRepo, deptRepo, leaveRepo)
+	authHandler := handlers.NewAuthHandler(userRepo, tokenRepo, securityRepo, twoFactorRepo, roleRepo, pasetoMaker, twoFactorIssuer, twoFactorRequiredRoles, oidcProvider, oidcStateRepo, passwordLoginDisabledDomains, passwordPolicy, activityRepo)
+	securityHandler := handlers.NewSecurityHandler(userRepo, securityRepo)
+	passwordResetHandler := handlers.NewPasswordResetHandler(userRepo, tokenRepo, passwordResetRepo, notif, passwordResetURL, passwordPolicy)
+	userHandler := handlers.NewUserHandler(userRepo, deptRepo, leaveRepo, tokenRepo, roleRepo, activityRepo, attendanceRepo)
 	deptHandler := handlers.NewDepartmentHandler(deptRepo)
-	attendanceHandler := handlers.NewAttendanceHandler(attendanceRepo, workScheduleRepo)
-	leaveHandler := handlers.NewLeaveRequestHandler(leaveRepo, attendanceRepo)
+	attendanceHandler := handlers.NewAttendanceHandler(attendanceRepo, workScheduleRepo, leaveRepo, activityRepo, userRepo, roleRepo)
+	delegationHandler := handlers.NewApprovalDelegationHandler(delegationRepo, userRepo, roleRepo)
+	managerHandler := handlers.NewManagerHandler(userRepo, deptRepo, attendanceRepo, leaveRepo, roleRepo)
+	approvalChainHandler := handlers.NewApprovalChainHandler(chainRepo)
 	fileHandler := handlers.NewFileHandler()
-	workScheduleHandler := handlers.NewWorkScheduleHandler(workScheduleRepo)
+	workScheduleHandler := handlers.NewWorkScheduleHandler(workScheduleRepo, roleRepo)
+	roleHandler := handlers.NewRoleHandler(roleRepo, userRepo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo, roleRepo)
+	sessionHandler := handlers.NewSessionHandler(tokenRepo, userRepo, securityRepo)
+	auditLogHandler := handlers.NewAuditLogHandler(auditLogRepo)
+	activityHandler := handlers.NewActivityHandler(activityRepo)
+
+	// permission memasang RequirePermission untuk satu rute.
+	permission := func(permissions ...string) fiber.Handler {
+		return middleware.RequirePermission(roleRepo, permissions...)
+	}
 
 	// Rute Health check & Dokumentasi
 	app.Get("/", func(c *fiber.Ctx) error {
@@ -45,72 +84,138 @@ func SetupRoutes(
 
 	// Grup API v1
 	api := app.Group("/api/v1")
+	// Semua request yang mengubah data dicatat ke audit log setelah handler selesai.
+	api.Use(middleware.AuditMiddleware(auditLogRepo))
 
 	// Rute untuk mengakses file (membutuhkan login)
-	api.Get("/files/:id", middleware.AuthMiddleware(), fileHandler.GetFileFromGridFS)
-	api.Get("/attachments/:filename", middleware.AuthMiddleware(), fileHandler.GetFileByFilename)
+	api.Get("/files/:id", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), fileHandler.GetFileFromGridFS)
+	api.Get("/attachments/:filename", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), fileHandler.GetFileByFilename)
 
 	// Rute Autentikasi
 	authGroup := api.Group("/auth")
-	authGroup.Post("/register", authHandler.Register)
+	authGroup.Post("/register", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), permission(models.PermissionUsersWrite), authHandler.Register)
 	authGroup.Post("/login", authHandler.Login)
-	authGroup.Post("/logout", middleware.AuthMiddleware(), authHandler.Logout)
-
+	authGroup.Post("/refresh", authHandler.RefreshToken)
+	authGroup.Get("/password-policy", authHandler.GetPasswordPolicy)
+	authGroup.Post("/password-reset/request", passwordResetHandler.RequestPasswordReset)
+	authGroup.Post("/password-reset/confirm", passwordResetHandler.ConfirmPasswordReset)
+	authGroup.Post("/logout", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), authHandler.Logout)
+	authGroup.Post("/logout-all", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), authHandler.LogoutAll)
+	authGroup.Post("/2fa/verify", authHandler.VerifyTwoFactorLogin)
+	authGroup.Get("/oidc/login", authHandler.StartOIDCLogin)
+	authGroup.Post("/oidc/callback", authHandler.OIDCCallback)
+	authGroup.Post("/2fa/setup", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), authHandler.SetupTwoFactor)
+	authGroup.Post("/2fa/enable", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), authHandler.EnableTwoFactor)
+	authGroup.Post("/2fa/disable", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), authHandler.DisableTwoFactor)
+	authGroup.Post("/2fa/recovery-codes", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), authHandler.RegenerateRecoveryCodes)
 
 	// Rute Pengguna (dilindungi otentikasi)
-	protectedUserGroup := api.Group("/users", middleware.AuthMiddleware())
+	protectedUserGroup := api.Group("/users", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware())
 	protectedUserGroup.Post("/change-password", authHandler.ChangePassword)
+	protectedUserGroup.Get("/security-history", securityHandler.GetMySecurityHistory)
+	protectedUserGroup.Get("/sessions", sessionHandler.GetMySessions)
+	protectedUserGroup.Delete("/sessions/:sessionId", sessionHandler.RevokeMySession)
 	protectedUserGroup.Get("/:id", userHandler.GetUserByID)
 	protectedUserGroup.Put("/:id", userHandler.UpdateUser)
 	protectedUserGroup.Post("/:id/upload-photo", userHandler.UploadProfilePhoto)
 	protectedUserGroup.Get("/:id/photo", userHandler.GetProfilePhoto)
 
-	// Rute Admin (dilindungi otentikasi & middleware admin)
-	adminGroup := api.Group("/admin", middleware.AuthMiddleware(), middleware.AdminMiddleware())
-	adminGroup.Get("/users", userHandler.GetAllUsers)
-	adminGroup.Delete("/users/:id", userHandler.DeleteUser)
-	adminGroup.Get("/dashboard-stats", userHandler.GetDashboardStats)
+	// Rute Admin (dilindungi otentikasi; hak akses per rute diperiksa berdasarkan izin role).
+	// Grup yang memakai FirstLoginAPIKeyMiddleware juga menerima API key, tetapi hanya pada rute
+	// yang memasang permission(...); rute lain di grup tersebut membaca user dari c.Locals("user").
+	adminGroup := api.Group("/admin", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginAPIKeyMiddleware())
+	adminGroup.Get("/users", permission(models.PermissionUsersReadAll), userHandler.GetAllUsers)
+	adminGroup.Delete("/users/:id", permission(models.PermissionUsersDelete), userHandler.DeleteUser)
+	adminGroup.Post("/users/:id/revoke-sessions", permission(models.PermissionUsersSecurity), userHandler.RevokeUserSessions)
+	adminGroup.Get("/users/:id/sessions", permission(models.PermissionUsersReadAll), sessionHandler.GetUserSessions)
+	adminGroup.Delete("/users/:id/sessions/:sessionId", permission(models.PermissionUsersSecurity), sessionHandler.RevokeUserSession)
+	adminGroup.Post("/users/:id/unlock", permission(models.PermissionUsersSecurity), securityHandler.UnlockUser)
+	adminGroup.Post("/users/:id/2fa/reset", permission(models.PermissionUsersSecurity), authHandler.ResetUserTwoFactor)
+	adminGroup.Get("/users/:id/security-history", permission(models.PermissionUsersReadAll), securityHandler.GetUserSecurityHistory)
+	adminGroup.Get("/dashboard-stats", permission(models.PermissionDashboardRead), userHandler.GetDashboardStats)
+	adminGroup.Get("/activities", permission(models.PermissionDashboardRead), activityHandler.GetActivities)
+	adminGroup.Get("/audit-logs", permission(models.PermissionAuditLogsRead), auditLogHandler.GetAuditLogs)
+
+	// Rute Role & Izin
+	adminGroup.Get("/permissions", permission(models.PermissionRolesRead), roleHandler.GetAllPermissions)
+	adminGroup.Get("/roles", permission(models.PermissionRolesRead), roleHandler.GetAllRoles)
+	adminGroup.Post("/roles", permission(models.PermissionRolesWrite), roleHandler.CreateRole)
+	adminGroup.Put("/roles/:id", permission(models.PermissionRolesWrite), roleHandler.UpdateRole)
+	adminGroup.Delete("/roles/:id", permission(models.PermissionRolesWrite), roleHandler.DeleteRole)
+
+	// Rute API Key integrasi
+	adminGroup.Get("/api-keys", permission(models.PermissionAPIKeysRead), apiKeyHandler.GetAllAPIKeys)
+	adminGroup.Post("/api-keys", permission(models.PermissionAPIKeysWrite), apiKeyHandler.CreateAPIKey)
+	adminGroup.Delete("/api-keys/:id", permission(models.PermissionAPIKeysWrite), apiKeyHandler.RevokeAPIKey)
 
 	// Rute Departemen
-	api.Get("/departments", middleware.AuthMiddleware(), deptHandler.GetAllDepartments) // Dapat diakses semua role terautentikasi
-	api.Get("/departments/:id", middleware.AuthMiddleware(), deptHandler.GetDepartmentByID) // Dapat diakses semua role terautentikasi
-	adminGroup.Post("/departments", deptHandler.CreateDepartment)
-	adminGroup.Put("/departments/:id", deptHandler.UpdateDepartment)
-	adminGroup.Delete("/departments/:id", deptHandler.DeleteDepartment)
+	api.Get("/departments", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), deptHandler.GetAllDepartments)     // Dapat diakses semua role terautentikasi
+	api.Get("/departments/:id", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), deptHandler.GetDepartmentByID) // Dapat diakses semua role terautentikasi
+	adminGroup.Post("/departments", permission(models.PermissionDepartmentsWrite), deptHandler.CreateDepartment)
+	adminGroup.Put("/departments/:id", permission(models.PermissionDepartmentsWrite), deptHandler.UpdateDepartment)
+	adminGroup.Delete("/departments/:id", permission(models.PermissionDepartmentsWrite), deptHandler.DeleteDepartment)
+
+	// Rute Rantai Persetujuan
+	adminGroup.Get("/approval-chains", permission(models.PermissionApprovalChainsRead), approvalChainHandler.GetAllApprovalChains)
+	adminGroup.Post("/approval-chains", permission(models.PermissionApprovalChainsWrite), approvalChainHandler.CreateApprovalChain)
+	adminGroup.Put("/approval-chains/:id", permission(models.PermissionApprovalChainsWrite), approvalChainHandler.UpdateApprovalChain)
+	adminGroup.Delete("/approval-chains/:id", permission(models.PermissionApprovalChainsWrite), approvalChainHandler.DeleteApprovalChain)
 
 	// Rute Kehadiran
-	attendanceGroup := api.Group("/attendance", middleware.AuthMiddleware())
-	attendanceGroup.Post("/scan", attendanceHandler.ScanQRCode)
+	attendanceGroup := api.Group("/attendance", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginAPIKeyMiddleware())
+	// Karyawan absen untuk dirinya sendiri; API key (badge reader) wajib memiliki izin attendance.scan.
+	attendanceGroup.Post("/scan", middleware.RequireAPIKeyPermission(roleRepo, models.PermissionAttendanceScan), attendanceHandler.ScanQRCode)
 	attendanceGroup.Get("/my-history", attendanceHandler.GetMyAttendanceHistory)
 	attendanceGroup.Get("/my-today", attendanceHandler.GetMyTodayAttendance) // Endpoint untuk karyawan melihat status absensi hari ini
 
-	adminAttendanceGroup := attendanceGroup.Group("/", middleware.AdminMiddleware()) // Grup khusus admin untuk absensi
-	adminAttendanceGroup.Get("/generate-qr", attendanceHandler.GenerateQRCode)
-	adminAttendanceGroup.Get("/today", attendanceHandler.GetTodayAttendance) // Laporan absensi hari ini untuk admin
-	adminAttendanceGroup.Get("/history", attendanceHandler.GetAttendanceHistoryForAdmin) // Riwayat absensi semua karyawan untuk admin
+	attendanceGroup.Get("/generate-qr", permission(models.PermissionAttendanceQRGenerate), attendanceHandler.GenerateQRCode)
+	attendanceGroup.Get("/today", permission(models.PermissionAttendanceReadAll), attendanceHandler.GetTodayAttendance)             // Laporan absensi hari ini semua karyawan
+	attendanceGroup.Get("/history", permission(models.PermissionAttendanceReadAll), attendanceHandler.GetAttendanceHistoryForAdmin) // Riwayat absensi semua karyawan
+	attendanceGroup.Get("/analytics/employees", permission(models.PermissionAttendanceReadAll), attendanceHandler.GetEmployeeAttendanceSummary)
+	attendanceGroup.Get("/analytics/departments", permission(models.PermissionAttendanceReadAll), attendanceHandler.GetDepartmentAttendanceSummary)
+	attendanceGroup.Get("/analytics/daily", permission(models.PermissionAttendanceReadAll), attendanceHandler.GetDailyAttendanceTrend)
+	attendanceGroup.Get("/analytics/lateness", permission(models.PermissionAttendanceReadAll), attendanceHandler.GetLatenessRanking)
 
 	// Rute Pengajuan Cuti & Izin
-	leaveGroup := api.Group("/leave-requests", middleware.AuthMiddleware())
+	leaveGroup := api.Group("/leave-requests", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginAPIKeyMiddleware())
 	leaveGroup.Post("/", leaveHandler.CreateLeaveRequest)
 	leaveGroup.Post("/:id/attachment", leaveHandler.UploadAttachment)
 	leaveGroup.Get("/my-requests", leaveHandler.GetMyLeaveRequests)
 	leaveGroup.Get("/summary", leaveHandler.GetLeaveSummary)
+	leaveGroup.Post("/:id/cancel", leaveHandler.CancelLeaveRequest)
+	leaveGroup.Put("/:id", leaveHandler.UpdateLeaveRequest)
+	leaveGroup.Post("/:id/resubmit", leaveHandler.ResubmitLeaveRequest)
+	leaveGroup.Get("/approvals/pending", leaveHandler.GetMyPendingApprovals)
+	leaveGroup.Put("/:id/decision", leaveHandler.DecideLeaveRequest)
 
-	adminLeaveGroup := leaveGroup.Group("/", middleware.AdminMiddleware()) // Grup khusus admin untuk cuti/izin
-	adminLeaveGroup.Get("/", leaveHandler.GetAllLeaveRequests)
-	adminLeaveGroup.Put("/:id/status", leaveHandler.UpdateLeaveRequestStatus)
+	leaveGroup.Get("/", permission(models.PermissionLeaveReadAll), leaveHandler.GetAllLeaveRequests)
+	leaveGroup.Get("/calendar", permission(models.PermissionLeaveReadAll), leaveHandler.GetLeaveCalendar)
+	leaveGroup.Put("/:id/status", permission(models.PermissionLeaveApprove), leaveHandler.UpdateLeaveRequestStatus)
+	leaveGroup.Put("/:id/withdrawal", permission(models.PermissionLeaveApprove), leaveHandler.ResolveLeaveWithdrawal)
+
+	// Rute Delegasi Persetujuan
+	delegationGroup := api.Group("/delegations", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware())
+	delegationGroup.Get("/", delegationHandler.GetMyDelegations)
+	delegationGroup.Post("/", delegationHandler.CreateDelegation)
+	delegationGroup.Delete("/:id", delegationHandler.RevokeDelegation)
+
+	// Rute Manager (manager & admin, data dibatasi pada tim masing-masing)
+	managerGroup := api.Group("/manager", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginAPIKeyMiddleware(), permission(models.PermissionTeamRead))
+	managerGroup.Get("/team", managerHandler.GetMyTeam)
+	managerGroup.Get("/dashboard", managerHandler.GetTeamDashboard)
+	managerGroup.Get("/attendance/history", managerHandler.GetTeamAttendanceHistory)
+	managerGroup.Get("/leave-requests", managerHandler.GetTeamLeaveRequests)
 
 	// ======================================================
 	// Rute Jadwal Kerja (Work Schedules) - Diperbarui
 	// ======================================================
-	workScheduleGroup := api.Group("/work-schedules", middleware.AuthMiddleware())
+	workScheduleGroup := api.Group("/work-schedules", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginAPIKeyMiddleware())
 	workScheduleGroup.Get("/", workScheduleHandler.GetAllWorkSchedules)
-	workScheduleGroup.Post("/", middleware.AdminMiddleware(), workScheduleHandler.CreateWorkSchedule)
-	workScheduleGroup.Put("/:id", middleware.AdminMiddleware(), workScheduleHandler.UpdateWorkSchedule)
-	workScheduleGroup.Delete("/:id", middleware.AdminMiddleware(), workScheduleHandler.DeleteWorkSchedule)
-	workScheduleGroup.Get("/:id", middleware.AdminMiddleware(), workScheduleHandler.GetWorkScheduleById) 
-    api.Get("/holidays", middleware.AuthMiddleware(), workScheduleHandler.GetHolidays) 
-
+	workScheduleGroup.Post("/", permission(models.PermissionWorkSchedulesWrite), workScheduleHandler.CreateWorkSchedule)
+	workScheduleGroup.Put("/:id", permission(models.PermissionWorkSchedulesWrite), workScheduleHandler.UpdateWorkSchedule)
+	workScheduleGroup.Delete("/:id", permission(models.PermissionWorkSchedulesWrite), workScheduleHandler.DeleteWorkSchedule)
+	workScheduleGroup.Get("/:id", permission(models.PermissionWorkSchedulesReadAll), workScheduleHandler.GetWorkScheduleById)
+	api.Get("/holidays", middleware.AuthMiddleware(tokenRepo, pasetoMaker, apiKeyRepo), middleware.FirstLoginMiddleware(), workScheduleHandler.GetHolidays)
 
 	log.Println("Semua rute aplikasi berhasil didaftarkan.")
 
@@ -124,45 +229,101 @@ func SetupRoutes(
 	log.Println("- GET /api/v1/files/:id (protected)")
 	log.Println("- GET /api/v1/attachments/:filename (protected)")
 
-	log.Println("- POST /api/v1/auth/register")
+	log.Println("- POST /api/v1/auth/register (izin users.write)")
 	log.Println("- POST /api/v1/auth/login")
+	log.Println("- POST /api/v1/auth/refresh")
+	log.Println("- GET /api/v1/auth/password-policy")
+	log.Println("- POST /api/v1/auth/password-reset/request")
+	log.Println("- POST /api/v1/auth/password-reset/confirm")
+	log.Println("- POST /api/v1/auth/logout (protected)")
+	log.Println("- POST /api/v1/auth/logout-all (protected)")
+	log.Println("- POST /api/v1/auth/2fa/verify")
+	log.Println("- GET /api/v1/auth/oidc/login (SSO)")
+	log.Println("- POST /api/v1/auth/oidc/callback (SSO)")
+	log.Println("- POST /api/v1/auth/2fa/setup (protected)")
+	log.Println("- POST /api/v1/auth/2fa/enable (protected)")
+	log.Println("- POST /api/v1/auth/2fa/disable (protected)")
+	log.Println("- POST /api/v1/auth/2fa/recovery-codes (protected)")
 
 	log.Println("- POST /api/v1/users/change-password (protected)")
+	log.Println("- GET /api/v1/users/security-history (protected)")
+	log.Println("- GET /api/v1/users/sessions (protected)")
+	log.Println("- DELETE /api/v1/users/sessions/:sessionId (protected)")
 	log.Println("- GET /api/v1/users/:id (protected)")
 	log.Println("- PUT /api/v1/users/:id (protected)")
 	log.Println("- POST /api/v1/users/:id/upload-photo (protected)")
 	log.Println("- GET /api/v1/users/:id/photo (protected)")
 
-	log.Println("- GET /api/v1/admin/users (admin only)")
-	log.Println("- DELETE /api/v1/admin/users/:id (admin only)")
-	log.Println("- GET /api/v1/admin/dashboard-stats (admin only)")
+	log.Println("- GET /api/v1/admin/users (izin users.read.all)")
+	log.Println("- DELETE /api/v1/admin/users/:id (izin users.delete)")
+	log.Println("- POST /api/v1/admin/users/:id/revoke-sessions (izin users.security)")
+	log.Println("- GET /api/v1/admin/users/:id/sessions (izin users.read.all)")
+	log.Println("- DELETE /api/v1/admin/users/:id/sessions/:sessionId (izin users.security)")
+	log.Println("- POST /api/v1/admin/users/:id/unlock (izin users.security)")
+	log.Println("- POST /api/v1/admin/users/:id/2fa/reset (izin users.security)")
+	log.Println("- GET /api/v1/admin/users/:id/security-history (izin users.read.all)")
+	log.Println("- GET /api/v1/admin/dashboard-stats (izin dashboard.read)")
+	log.Println("- GET /api/v1/admin/activities (izin dashboard.read)")
+	log.Println("- GET /api/v1/admin/audit-logs (izin audit_logs.read)")
+	log.Println("- GET /api/v1/admin/permissions (izin roles.read)")
+	log.Println("- GET /api/v1/admin/roles (izin roles.read)")
+	log.Println("- POST /api/v1/admin/roles (izin roles.write)")
+	log.Println("- PUT /api/v1/admin/roles/:id (izin roles.write)")
+	log.Println("- DELETE /api/v1/admin/roles/:id (izin roles.write)")
+	log.Println("- GET /api/v1/admin/api-keys (izin api_keys.read)")
+	log.Println("- POST /api/v1/admin/api-keys (izin api_keys.write)")
+	log.Println("- DELETE /api/v1/admin/api-keys/:id (izin api_keys.write)")
 
 	log.Println("- GET /api/v1/departments (protected)")
 	log.Println("- GET /api/v1/departments/:id (protected)")
-	log.Println("- POST /api/v1/admin/departments (admin only)")
-	log.Println("- PUT /api/v1/admin/departments/:id (admin only)")
-	log.Println("- DELETE /api/v1/admin/departments/:id (admin only)")
+	log.Println("- POST /api/v1/admin/departments (izin departments.write)")
+	log.Println("- PUT /api/v1/admin/departments/:id (izin departments.write)")
+	log.Println("- DELETE /api/v1/admin/departments/:id (izin departments.write)")
+
+	log.Println("- GET /api/v1/admin/approval-chains (izin approval_chains.read)")
+	log.Println("- POST /api/v1/admin/approval-chains (izin approval_chains.write)")
+	log.Println("- PUT /api/v1/admin/approval-chains/:id (izin approval_chains.write)")
+	log.Println("- DELETE /api/v1/admin/approval-chains/:id (izin approval_chains.write)")
 
 	log.Println("- POST /api/v1/attendance/scan (protected)")
 	log.Println("- GET /api/v1/attendance/my-history (protected)")
 	log.Println("- GET /api/v1/attendance/my-today (protected)")
-	log.Println("- GET /api/v1/admin/attendance/generate-qr (admin only)")
-	log.Println("- GET /api/v1/admin/attendance/today (admin only)")
-	log.Println("- GET /api/v1/admin/attendance/history (admin only)") 
+	log.Println("- GET /api/v1/attendance/generate-qr (izin attendance.qr.generate)")
+	log.Println("- GET /api/v1/attendance/today (izin attendance.read.all)")
+	log.Println("- GET /api/v1/attendance/history (izin attendance.read.all)")
+	log.Println("- GET /api/v1/attendance/analytics/employees (izin attendance.read.all)")
+	log.Println("- GET /api/v1/attendance/analytics/departments (izin attendance.read.all)")
+	log.Println("- GET /api/v1/attendance/analytics/daily (izin attendance.read.all)")
+	log.Println("- GET /api/v1/attendance/analytics/lateness (izin attendance.read.all)")
 
 	log.Println("- POST /api/v1/leave-requests (protected)")
 	log.Println("- POST /api/v1/leave-requests/:id/attachment (protected)")
 	log.Println("- GET /api/v1/leave-requests/my-requests (protected)")
-	log.Println("- GET /api/v1/admin/leave-requests (admin only)")
-	log.Println("- PUT /api/v1/admin/leave-requests/:id/status (admin only)")
+	log.Println("- GET /api/v1/leave-requests (izin leave.read.all)")
+	log.Println("- PUT /api/v1/leave-requests/:id/status (izin leave.approve)")
+	log.Println("- GET /api/v1/leave-requests/calendar (izin leave.read.all)")
+	log.Println("- POST /api/v1/leave-requests/:id/cancel (protected)")
+	log.Println("- PUT /api/v1/leave-requests/:id (protected, pemilik, hanya pending)")
+	log.Println("- POST /api/v1/leave-requests/:id/resubmit (protected, pemilik, hanya rejected)")
+	log.Println("- PUT /api/v1/leave-requests/:id/withdrawal (izin leave.approve)")
+	log.Println("- GET /api/v1/leave-requests/approvals/pending (protected)")
+	log.Println("- PUT /api/v1/leave-requests/:id/decision (protected, approver langkah aktif)")
+
+	log.Println("- GET /api/v1/delegations (protected)")
+	log.Println("- POST /api/v1/delegations (protected)")
+	log.Println("- DELETE /api/v1/delegations/:id (protected, delegator atau admin)")
 
-	log.Println("- GET /api/v1/work-schedules (protected)")           
-	log.Println("- GET /api/v1/work-schedules/:id (admin only)")      
-	log.Println("- POST /api/v1/work-schedules (admin only)")         
-	log.Println("- PUT /api/v1/work-schedules/:id (admin only)")      
-	log.Println("- DELETE /api/v1/work-schedules/:id (admin only)")   
-    log.Println("- GET /api/v1/holidays (protected)")                 
+	log.Println("- GET /api/v1/manager/team (izin team.read)")
+	log.Println("- GET /api/v1/manager/dashboard (izin team.read)")
+	log.Println("- GET /api/v1/manager/attendance/history (izin team.read)")
+	log.Println("- GET /api/v1/manager/leave-requests (izin team.read)")
 
+	log.Println("- GET /api/v1/work-schedules (protected)")
+	log.Println("- GET /api/v1/work-schedules/:id (izin work_schedules.read.all)")
+	log.Println("- POST /api/v1/work-schedules (izin work_schedules.write)")
+	log.Println("- PUT /api/v1/work-schedules/:id (izin work_schedules.write)")
+	log.Println("- DELETE /api/v1/work-schedules/:id (izin work_schedules.write)")
+	log.Println("- GET /api/v1/holidays (protected)")
 
 	log.Println("Swagger documentation tersedia di: /docs/index.html")
-}
\ No newline at end of file
+}